@@ -0,0 +1,27 @@
+/**!
+ * @name          Ascii85 Decode
+ * @description   Decodes Adobe Ascii85, with or without <~ ~>, or ZeroMQ's Z85.
+ * @icon          metamorphose
+ * @tags          ascii85,base85,z85,decode
+ * @param         z85:boolean "Z85 instead of Ascii85" false
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	const format = state.params.z85 ? 'z85' : 'ascii85'
+	let decoded
+	try {
+		decoded = encoding.decode(format, state.text, { output: 'auto' })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	// Anything that is not UTF-8 text replaces the document as bytes.
+	if (typeof decoded === 'string') {
+		state.text = decoded
+	} else {
+		state.setBytes(decoded)
+	}
+}
@@ -0,0 +1,21 @@
+/**!
+ * @name          Ascii85 Encode
+ * @description   Encodes your text as Adobe Ascii85, or ZeroMQ's Z85.
+ * @icon          metamorphose
+ * @tags          ascii85,base85,z85,encode
+ * @param         z85:boolean "Z85 instead of Ascii85" false
+ * @param         delimiters:boolean "Wrap in <~ ~>" false
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	const format = state.params.z85 ? 'z85' : 'ascii85'
+	const data = state.isBinary ? state.bytes : state.text
+	try {
+		state.text = encoding.encode(format, data, { delimiters: state.params.delimiters })
+	}
+	catch (error) {
+		state.postError(error.message)
+	}
+}
@@ -0,0 +1,32 @@
+/**!
+ * @name          Base32 Decode
+ * @description   Decodes Base32 (standard, hex or Crockford alphabet), with or without padding.
+ * @icon          metamorphose
+ * @tags          base32,decode,crockford,rfc4648
+ * @param         variant:string "Variant (std, hex, crockford)" "std"
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	const variant = state.params.variant
+	if (['std', 'hex', 'crockford'].indexOf(variant) < 0) {
+		state.postError("Variant must be std, hex or crockford")
+		return
+	}
+	const format = variant === 'std' ? 'base32' : 'base32' + variant
+	let decoded
+	try {
+		decoded = encoding.decode(format, state.text, { output: 'auto' })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	// Anything that is not UTF-8 text replaces the document as bytes.
+	if (typeof decoded === 'string') {
+		state.text = decoded
+	} else {
+		state.setBytes(decoded)
+	}
+}
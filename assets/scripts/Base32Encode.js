@@ -0,0 +1,26 @@
+/**!
+ * @name          Base32 Encode
+ * @description   Encodes your text as Base32 (standard, hex or Crockford alphabet).
+ * @icon          metamorphose
+ * @tags          base32,encode,crockford,rfc4648
+ * @param         variant:string "Variant (std, hex, crockford)" "std"
+ * @param         padding:boolean "Padding" true
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	const variant = state.params.variant
+	if (['std', 'hex', 'crockford'].indexOf(variant) < 0) {
+		state.postError("Variant must be std, hex or crockford")
+		return
+	}
+	const format = variant === 'std' ? 'base32' : 'base32' + variant
+	const data = state.isBinary ? state.bytes : state.text
+	try {
+		state.text = encoding.encode(format, data, { padding: state.params.padding })
+	}
+	catch (error) {
+		state.postError(error.message)
+	}
+}
@@ -0,0 +1,27 @@
+/**!
+ * @name          Base58 Decode
+ * @description   Decodes Base58 with the Bitcoin or Flickr alphabet.
+ * @icon          metamorphose
+ * @tags          base58,decode,bitcoin,flickr
+ * @param         flickr:boolean "Flickr alphabet" false
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	const format = state.params.flickr ? 'base58flickr' : 'base58'
+	let decoded
+	try {
+		decoded = encoding.decode(format, state.text, { output: 'auto' })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	// Anything that is not UTF-8 text replaces the document as bytes.
+	if (typeof decoded === 'string') {
+		state.text = decoded
+	} else {
+		state.setBytes(decoded)
+	}
+}
@@ -0,0 +1,20 @@
+/**!
+ * @name          Base58 Encode
+ * @description   Encodes your text as Base58 with the Bitcoin or Flickr alphabet.
+ * @icon          metamorphose
+ * @tags          base58,encode,bitcoin,flickr
+ * @param         flickr:boolean "Flickr alphabet" false
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	const format = state.params.flickr ? 'base58flickr' : 'base58'
+	const data = state.isBinary ? state.bytes : state.text
+	try {
+		state.text = encoding.encode(format, data)
+	}
+	catch (error) {
+		state.postError(error.message)
+	}
+}
@@ -0,0 +1,14 @@
+/**!
+ * @name          Base64 Decode to Bytes
+ * @description   Decodes Base64 into raw bytes, e.g. an image or a gzip stream
+ * @icon          metamorphose
+ * @tags          base64,atob,decode,binary,bytes
+ */
+
+const forge = require('@boop/node-forge')
+
+function main(state) {
+    // decode64 returns one character per byte; atob() would decode the
+    // bytes as UTF-8 and corrupt anything that is not text.
+    state.setBytes(forge.util.decode64(state.text.replace(/[^A-Za-z0-9+\/=]/g, '')))
+}
@@ -9,6 +9,8 @@ const { encode } = require('@boop/base64')
 
 function main(input) {
 	
-	input.text = encode(input.text)
+	// A binary document arrives as one character per byte, which btoa()
+	// encodes as is; text is encoded as UTF-8 first.
+	input.text = input.isBinary ? btoa(input.text) : encode(input.text)
 	
 }
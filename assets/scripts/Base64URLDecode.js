@@ -0,0 +1,25 @@
+/**!
+ * @name          Base64URL Decode
+ * @description   Decodes URL-safe Base64, with or without padding.
+ * @icon          metamorphose
+ * @tags          base64url,base64,url,decode,jwt
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	let decoded
+	try {
+		decoded = encoding.decode('base64url', state.text, { output: 'auto' })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	// Anything that is not UTF-8 text replaces the document as bytes.
+	if (typeof decoded === 'string') {
+		state.text = decoded
+	} else {
+		state.setBytes(decoded)
+	}
+}
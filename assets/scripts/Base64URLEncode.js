@@ -0,0 +1,19 @@
+/**!
+ * @name          Base64URL Encode
+ * @description   Encodes your text as URL-safe Base64 (- and _ instead of + and /).
+ * @icon          metamorphose
+ * @tags          base64url,base64,url,encode,jwt
+ * @param         padding:boolean "Padding" false
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	const data = state.isBinary ? state.bytes : state.text
+	try {
+		state.text = encoding.encode('base64url', data, { padding: state.params.padding })
+	}
+	catch (error) {
+		state.postError(error.message)
+	}
+}
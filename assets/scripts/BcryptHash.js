@@ -0,0 +1,18 @@
+/**!
+ * @name          Bcrypt Hash
+ * @description   Hashes your text as a password with bcrypt.
+ * @icon          fingerprint
+ * @tags          bcrypt,password,hash,crypto
+ * @param         cost:number "Cost (4–15)" 10
+ */
+
+const crypto = require('@boop/crypto')
+
+function main(state) {
+	try {
+		state.text = crypto.hashPassword(state.text, { algorithm: 'bcrypt', cost: state.params.cost })
+	}
+	catch (error) {
+		state.postError(error.message)
+	}
+}
@@ -0,0 +1,25 @@
+/**!
+ * @name          Bcrypt Verify
+ * @description   Checks whether your text is the password a bcrypt or Argon2id hash was made from.
+ * @icon          fingerprint
+ * @tags          bcrypt,argon2,password,verify,check,crypto
+ * @param         hash:string "Hash" ""
+ */
+
+const crypto = require('@boop/crypto')
+
+function main(state) {
+	let ok
+	try {
+		ok = crypto.verifyPassword(state.text, state.params.hash)
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	if (ok) {
+		state.postInfo("Password matches the hash")
+	} else {
+		state.postError("Password does not match the hash")
+	}
+}
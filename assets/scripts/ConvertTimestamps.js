@@ -0,0 +1,29 @@
+/**!
+ * @name          Convert timestamps in selection
+ * @description   Rewrites every date and Unix timestamp in your text in one format and time zone.
+ * @icon          watch
+ * @tags          date,time,timestamp,timezone,unix,convert,log,iso8601
+ * @param         zone:string "Time zone (UTC, Local, Europe/Berlin, +05:30…)" "UTC"
+ * @param         format:string "Format (RFC3339, ISO8601, RFC1123, unix, unixms, %Y-%m-%d…)" "RFC3339"
+ * @param         unix:boolean "Convert Unix timestamps" true
+ */
+
+const time = require('@boop/time')
+
+function main(state) {
+	const zone = state.params.zone
+	const format = state.params.format
+	let count = 0
+	try {
+		// Times written without an offset are read as local time.
+		state.text = time.replaceAll(state.text, function (match) {
+			count++
+			return time.format(match.date, format, { zone: zone })
+		}, { unix: state.params.unix })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	state.postInfo("Converted " + count + (count === 1 ? " timestamp" : " timestamps"))
+}
@@ -0,0 +1,21 @@
+/**!
+ * @name          Convert UUID formats
+ * @description   Rewrites each UUID, one per line, as hyphenated, braces, urn, hex, base64 or uppercase.
+ * @icon          metamorphose
+ * @tags          uuid,guid,convert,format,base64,braces,uppercase
+ * @param         format:string "Format (hyphenated, braces, urn, hex, base64, uppercase)" "hyphenated"
+ */
+
+const ids = require('@boop/ids')
+
+function main(state) {
+	const format = state.params.format
+	try {
+		state.text = state.text.split('\n').map(function (line) {
+			return line.trim() === '' ? line : ids.formatUUID(line, format)
+		}).join('\n')
+	}
+	catch (error) {
+		state.postError(error.message)
+	}
+}
@@ -5,13 +5,12 @@
  * @tags          date,time,calendar,unix,timestamp
  */
 
-function main(input) {
-
-    let parsedDate = Date.parse(input.text)
+const time = require('@boop/time')
 
-    if (isNaN(parsedDate)) {
+function main(input) {
+    try {
+        input.text = time.format(time.parse(input.text), 'unix')
+    } catch (error) {
         input.postError("Invalid Date")
-    } else {
-        input.text = parsedDate / 1000
     }
 }
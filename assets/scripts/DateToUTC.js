@@ -5,24 +5,13 @@
  * @tags          date,time,calendar,unix,timestamp
  */
 
-function main(input) {
+const time = require('@boop/time')
 
-    let string = input.text
-    
-    let parsedDate = Date.parse(string)
-    
-    if (isNaN(parsedDate)) {
-        parsedDate = new Date(parseInt(string * 1000))
-    } else {
-        parsedDate = new Date(parsedDate)
-    }
-    
-    let out = parsedDate.toUTCString()
-    
-    if (out === "Invalid Date") {
-        input.postError(out)
-        return
+function main(input) {
+    try {
+        // The layout of Date.prototype.toUTCString().
+        input.text = time.format(time.parse(input.text), 'http')
+    } catch (error) {
+        input.postError("Invalid Date")
     }
-    
-    input.text = out
 }
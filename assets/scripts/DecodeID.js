@@ -0,0 +1,28 @@
+/**!
+ * @name          Decode UUID/ULID
+ * @description   Shows the version, variant and timestamp of a UUID, ULID, KSUID or Snowflake ID.
+ * @icon          identification
+ * @tags          uuid,guid,ulid,ksuid,snowflake,decode,id,timestamp
+ * @param         epoch:string "Snowflake epoch (twitter, discord or milliseconds)" "twitter"
+ */
+
+const ids = require('@boop/ids')
+
+function main(state) {
+	const epoch = /^\d+$/.test(state.params.epoch) ? Number(state.params.epoch) : state.params.epoch
+	let info
+	try {
+		info = ids.decode(state.text, { epoch: epoch })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	const details = Object.keys(info).filter(function (key) {
+		return key !== 'type'
+	}).map(function (key) {
+		const value = info[key]
+		return key + ": " + (value instanceof Date ? value.toISOString() : value)
+	})
+	state.postInfo(info.type.toUpperCase() + " — " + details.join(", "))
+}
@@ -4,6 +4,8 @@
  * @icon          broom
  * @tags          css,prettify,clean,indent
  * @bias          -0.1
+ * @pure
+ * @output        css
  */
 
 const { css } = require('@boop/vkBeautify')
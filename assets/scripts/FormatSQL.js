@@ -4,6 +4,8 @@
  * @icon          broom
  * @tags          mysql,sql,prettify,clean,indent
  * @bias          -0.1
+ * @pure
+ * @output        sql
  */
 
 const { sql } = require('@boop/vkBeautify')
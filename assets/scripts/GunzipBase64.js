@@ -0,0 +1,26 @@
+/**!
+ * @name          Gunzip Base64
+ * @description   Decompresses gzip data given as Base64, or an opened .gz file.
+ * @icon          metamorphose
+ * @tags          gzip,gunzip,decompress,base64,zlib,inflate
+ */
+
+const compress = require('@boop/compress')
+
+function main(state) {
+	const data = state.isBinary ? state.bytes : state.text
+	let out
+	try {
+		out = compress.decompress('gzip', data, { output: 'auto' })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	// Anything that is not UTF-8 text replaces the document as bytes.
+	if (typeof out === 'string') {
+		state.text = out
+	} else {
+		state.setBytes(out)
+	}
+}
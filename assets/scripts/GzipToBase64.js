@@ -0,0 +1,19 @@
+/**!
+ * @name          Gzip to Base64
+ * @description   Compresses your text with gzip and encodes it as Base64.
+ * @icon          metamorphose
+ * @tags          gzip,compress,base64,deflate
+ * @param         level:number "Level (1–9)" 9
+ */
+
+const compress = require('@boop/compress')
+
+function main(state) {
+	const data = state.isBinary ? state.bytes : state.text
+	try {
+		state.text = compress.compress('gzip', data, { level: state.params.level })
+	}
+	catch (error) {
+		state.postError(error.message)
+	}
+}
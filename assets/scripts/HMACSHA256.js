@@ -0,0 +1,15 @@
+/**!
+ * @name          HMAC-SHA256
+ * @description   Computes the HMAC-SHA256 of your text with a secret key (Hex encoded)
+ * @icon          fingerprint
+ * @tags          hmac,sha256,signature,hash,crypto
+ * @param         key:string "Secret key" ""
+ * @param         base64:boolean "Base64 instead of hex" false
+ */
+
+const crypto = require('@boop/crypto')
+
+function main(state) {
+	const encoding = state.params.base64 ? 'base64' : 'hex'
+	state.text = crypto.hmac('sha256', state.params.key, state.text, { encoding: encoding })
+}
@@ -0,0 +1,20 @@
+/**!
+ * @name          Hex Dump
+ * @description   Shows your text or file as an xxd-style hex dump.
+ * @icon          metamorphose
+ * @tags          hex,dump,xxd,hexdump,binary,bytes
+ * @param         columns:number "Bytes per line" 16
+ * @param         group:number "Bytes per group" 2
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	const data = state.isBinary ? state.bytes : state.text
+	try {
+		state.text = encoding.encode('hexdump', data, { columns: state.params.columns, group: state.params.group })
+	}
+	catch (error) {
+		state.postError(error.message)
+	}
+}
@@ -0,0 +1,25 @@
+/**!
+ * @name          Hex Dump to Bytes
+ * @description   Turns an xxd or hexdump -C dump, or plain hex, back into text or bytes.
+ * @icon          metamorphose
+ * @tags          hex,dump,xxd,hexdump,binary,bytes,reverse
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	let decoded
+	try {
+		decoded = encoding.decode('hexdump', state.text, { output: 'auto' })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	// Anything that is not UTF-8 text replaces the document as bytes.
+	if (typeof decoded === 'string') {
+		state.text = decoded
+	} else {
+		state.setBytes(decoded)
+	}
+}
@@ -0,0 +1,12 @@
+/**!
+ * @name          Insert UUID v4
+ * @description   Inserts a new random UUID (version 4) at the cursor.
+ * @icon          dice
+ * @tags          uuid,guid,generate,random,insert,id,v4
+ */
+
+const ids = require('@boop/ids')
+
+function main(state) {
+	state.insert(ids.uuidv4())
+}
@@ -0,0 +1,12 @@
+/**!
+ * @name          Insert UUID v7
+ * @description   Inserts a new time-ordered UUID (version 7) at the cursor, which sorts by creation time.
+ * @icon          dice
+ * @tags          uuid,guid,generate,time,sortable,insert,id,v7
+ */
+
+const ids = require('@boop/ids')
+
+function main(state) {
+	state.insert(ids.uuidv7())
+}
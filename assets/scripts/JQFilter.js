@@ -0,0 +1,29 @@
+/**!
+ * @name          jq Filter
+ * @description   Runs a jq filter over JSON and replaces it with the results.
+ * @icon          filter
+ * @tags          json,jq,filter,query,select
+ * @param         filter:string "Filter" "."
+ * @param         raw:boolean "Raw strings (like jq -r)" false
+ */
+
+const jq = require('@boop/jq')
+
+function main(state) {
+	const raw = state.params.raw
+	let results
+	try {
+		// Text output keeps large integers exact; raw mode needs the values.
+		results = jq.query(state.text, state.params.filter, { text: !raw })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	if (raw) {
+		results = results.map(function (r) {
+			return typeof r === 'string' ? r : JSON.stringify(r, null, 2)
+		})
+	}
+	state.text = results.join('\n')
+}
@@ -0,0 +1,26 @@
+/**!
+ * @name          JSON to Plist
+ * @description   Converts JSON to an XML property list.
+ * @icon          metamorphose
+ * @tags          plist,property list,apple,macos,convert,json
+ * @output        xml
+ */
+
+const plist = require('@boop/plist')
+
+function main(input) {
+	let obj
+	try {
+		obj = JSON.parse(input.text)
+	}
+	catch(error) {
+		input.postError("Invalid JSON")
+		return
+	}
+	try {
+		input.text = plist.stringify(obj)
+	}
+	catch(error) {
+		input.postError(error.message)
+	}
+}
@@ -0,0 +1,26 @@
+/**!
+ * @name          JSON to TOML
+ * @description   Converts a JSON object to TOML.
+ * @icon          metamorphose
+ * @tags          toml,cargo,pyproject,hugo,markup,convert,json
+ * @output        toml
+ */
+
+const toml = require('@boop/toml')
+
+function main(input) {
+	let obj
+	try {
+		obj = JSON.parse(input.text)
+	}
+	catch(error) {
+		input.postError("Invalid JSON")
+		return
+	}
+	try {
+		input.text = toml.stringify(obj)
+	}
+	catch(error) {
+		input.postError(error.message)
+	}
+}
@@ -0,0 +1,32 @@
+/**!
+ * @name          JSON to XML
+ * @description   Converts JSON to XML, reading "@name" keys as attributes and arrays as repeated elements.
+ * @icon          metamorphose
+ * @tags          xml,markup,convert,json
+ * @output        xml
+ */
+
+const xml = require('@boop/xml')
+
+function main(input) {
+	let obj
+	try {
+		obj = JSON.parse(input.text)
+	}
+	catch(error) {
+		input.postError("Invalid JSON")
+		return
+	}
+	// XML needs a single root element; wrap anything else in <root>.
+	if (obj === null || typeof obj !== 'object' || Array.isArray(obj)) {
+		obj = { root: Array.isArray(obj) ? { item: obj } : obj }
+	} else if (Object.keys(obj).length !== 1 || Array.isArray(obj[Object.keys(obj)[0]])) {
+		obj = { root: obj }
+	}
+	try {
+		input.text = xml.stringify(xml.fromObject(obj))
+	}
+	catch(error) {
+		input.postError(error.message)
+	}
+}
@@ -1,17 +1,21 @@
 /**!
  * @name          JSON to YAML
- * @description   Converts JSON to YAML.
+ * @description   Converts JSON to YAML, keeping key order.
  * @icon          metamorphose
  * @tags          markup,convert
+ * @output        yaml
  */
 
-const yaml = require('@boop/js-yaml')
+const yaml = require('@boop/yaml')
 
 function main(input) {
+	let obj
 	try {
-		input.text = yaml.safeDump(JSON.parse(input.text))
+		obj = JSON.parse(input.text)
 	}
 	catch(error) {
 		input.postError("Invalid JSON")
+		return
 	}
-}
\ No newline at end of file
+	input.text = yaml.stringify(obj, { indent: 2 })
+}
@@ -0,0 +1,16 @@
+/**!
+ * @name          Join Lines With Delimiter
+ * @description   Joins all lines with a delimiter of your choice.
+ * @icon          collapse
+ * @tags          join,lines,delimiter,comma
+ * @param         delimiter:string "Delimiter" ", "
+ * @param         skipEmpty:boolean "Skip empty lines" true
+ */
+
+function main(state) {
+	var lines = state.text.split('\n');
+	if (state.params.skipEmpty) {
+		lines = lines.filter(function (line) { return line.trim() !== ''; });
+	}
+	state.text = lines.join(state.params.delimiter);
+}
@@ -4,6 +4,8 @@
  * @icon          broom
  * @tags          css,minify,clean,indent
  * @bias          -0.1
+ * @pure
+ * @output        css
  */
 
 const { cssmin } = require('@boop/vkBeautify')
@@ -4,6 +4,8 @@
  * @icon          broom
  * @tags          mysql,sql,minify,clean,indent
  * @bias          -0.1
+ * @pure
+ * @output        sql
  */
 
 const { sqlmin } = require('@boop/vkBeautify')
@@ -0,0 +1,15 @@
+/**!
+ * @name          Number Lines
+ * @description   Prefixes every line with its line number.
+ * @icon          counter
+ * @tags          number,lines,count,prefix,stream
+ */
+
+let n = 0
+
+// A streaming script: goop calls mapLine once per line, so it also works on
+// files far larger than the editor could hold (goop run --stream).
+function mapLine(line) {
+    n++
+    return n + ": " + line
+}
@@ -0,0 +1,11 @@
+/**!
+ * @name          Paste as JSON String
+ * @description   Inserts the clipboard contents as an escaped JSON string literal.
+ * @icon          quote
+ * @tags          paste,clipboard,json,string,escape
+ * @permissions   clipboard
+ */
+
+function main(state) {
+	state.insert(JSON.stringify(state.clipboard.read()));
+}
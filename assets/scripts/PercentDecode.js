@@ -0,0 +1,26 @@
+/**!
+ * @name          Percent Decode
+ * @description   Decodes percent-encoded text, with + as a space in query mode.
+ * @icon          metamorphose
+ * @tags          url,percent,decode,uri,query,path
+ * @param         mode:string "Mode (component, path, query)" "component"
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	let decoded
+	try {
+		decoded = encoding.decode('percent', state.text, { output: 'auto', mode: state.params.mode })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	// Anything that is not UTF-8 text replaces the document as bytes.
+	if (typeof decoded === 'string') {
+		state.text = decoded
+	} else {
+		state.setBytes(decoded)
+	}
+}
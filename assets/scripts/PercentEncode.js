@@ -0,0 +1,19 @@
+/**!
+ * @name          Percent Encode
+ * @description   Percent-encodes your text for a URL component, path or query string, or every byte.
+ * @icon          metamorphose
+ * @tags          url,percent,encode,uri,query,path
+ * @param         mode:string "Mode (component, path, query, all)" "component"
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	const data = state.isBinary ? state.bytes : state.text
+	try {
+		state.text = encoding.encode('percent', data, { mode: state.params.mode })
+	}
+	catch (error) {
+		state.postError(error.message)
+	}
+}
@@ -0,0 +1,19 @@
+/**!
+ * @name          Plist to JSON
+ * @description   Converts an XML or binary property list to JSON.
+ * @icon          metamorphose
+ * @tags          plist,property list,apple,macos,convert,json
+ * @output        json
+ */
+
+const plist = require('@boop/plist')
+
+function main(input) {
+	try {
+		const obj = input.isBinary ? plist.parseBinary(input.bytes) : plist.parse(input.text)
+		input.text = JSON.stringify(obj, null, 2)
+	}
+	catch(error) {
+		input.postError("Invalid property list")
+	}
+}
@@ -0,0 +1,25 @@
+/**!
+ * @name          Quoted-Printable Decode
+ * @description   Decodes quoted-printable text, as in email bodies.
+ * @icon          metamorphose
+ * @tags          quoted-printable,qp,mime,email,decode
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	let decoded
+	try {
+		decoded = encoding.decode('quoted-printable', state.text, { output: 'auto' })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	// Anything that is not UTF-8 text replaces the document as bytes.
+	if (typeof decoded === 'string') {
+		state.text = decoded
+	} else {
+		state.setBytes(decoded)
+	}
+}
@@ -0,0 +1,18 @@
+/**!
+ * @name          Quoted-Printable Encode
+ * @description   Encodes your text as quoted-printable, as in email bodies.
+ * @icon          metamorphose
+ * @tags          quoted-printable,qp,mime,email,encode
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	const data = state.isBinary ? state.bytes : state.text
+	try {
+		state.text = encoding.encode('quoted-printable', data)
+	}
+	catch (error) {
+		state.postError(error.message)
+	}
+}
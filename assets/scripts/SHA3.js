@@ -0,0 +1,13 @@
+/**!
+ * @name          SHA3-256 Hash
+ * @description   Computes the SHA3-256 hash of your text (Hex encoded)
+ * @icon          fingerprint
+ * @tags          sha3,keccak,hash,crypto
+ * @pure
+ */
+
+const crypto = require('@boop/crypto')
+
+function main(state) {
+	state.text = crypto.hash('sha3-256', state.text)
+}
@@ -0,0 +1,18 @@
+/**!
+ * @name          TOML to JSON
+ * @description   Converts TOML to JSON.
+ * @icon          metamorphose
+ * @tags          toml,cargo,pyproject,hugo,markup,convert,json
+ * @output        json
+ */
+
+const toml = require('@boop/toml')
+
+function main(input) {
+	try {
+		input.text = JSON.stringify(toml.parse(input.text), null, 2)
+	}
+	catch(error) {
+		input.postError("Invalid TOML")
+	}
+}
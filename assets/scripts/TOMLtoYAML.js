@@ -0,0 +1,23 @@
+/**!
+ * @name          TOML to YAML
+ * @description   Converts TOML to YAML.
+ * @icon          metamorphose
+ * @tags          toml,cargo,pyproject,hugo,markup,convert,yaml
+ * @output        yaml
+ */
+
+const toml = require('@boop/toml')
+const yaml = require('@boop/yaml')
+
+function main(input) {
+	let obj
+	try {
+		obj = toml.parse(input.text)
+	}
+	catch(error) {
+		input.postError("Invalid TOML")
+		return
+	}
+	// Through JSON, so dates are written as the strings they read as.
+	input.text = yaml.stringify(JSON.parse(JSON.stringify(obj)))
+}
@@ -0,0 +1,25 @@
+/**!
+ * @name          UUDecode
+ * @description   Decodes a uuencoded file.
+ * @icon          metamorphose
+ * @tags          uudecode,uuencode,uue,decode,unix
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	let decoded
+	try {
+		decoded = encoding.decode('uuencode', state.text, { output: 'auto' })
+	}
+	catch (error) {
+		state.postError(error.message)
+		return
+	}
+	// Anything that is not UTF-8 text replaces the document as bytes.
+	if (typeof decoded === 'string') {
+		state.text = decoded
+	} else {
+		state.setBytes(decoded)
+	}
+}
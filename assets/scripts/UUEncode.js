@@ -0,0 +1,19 @@
+/**!
+ * @name          UUEncode
+ * @description   Encodes your text as a uuencoded file.
+ * @icon          metamorphose
+ * @tags          uuencode,uue,encode,unix
+ * @param         name:string "File name" "data"
+ */
+
+const encoding = require('@boop/encoding')
+
+function main(state) {
+	const data = state.isBinary ? state.bytes : state.text
+	try {
+		state.text = encoding.encode('uuencode', data, { name: state.params.name })
+	}
+	catch (error) {
+		state.postError(error.message)
+	}
+}
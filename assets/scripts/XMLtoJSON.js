@@ -0,0 +1,21 @@
+/**!
+ * @name          XML to JSON
+ * @description   Converts XML to JSON, with attributes as "@name" keys and repeated elements as arrays.
+ * @icon          metamorphose
+ * @tags          xml,markup,convert,json
+ * @output        json
+ */
+
+const xml = require('@boop/xml')
+
+function main(input) {
+	let doc
+	try {
+		doc = xml.parse(input.text)
+	}
+	catch(error) {
+		input.postError("Invalid XML: " + error.message.replace(/^xml\.parse: /, ""))
+		return
+	}
+	input.text = JSON.stringify(xml.toObject(doc), null, 2)
+}
@@ -1,19 +1,21 @@
 /**!
  * @name          YAML to JSON
- * @description   Converts YAML to JSON.
+ * @description   Converts YAML to JSON, keeping key order. Several documents become an array.
  * @icon          metamorphose
  * @tags          markup,convert
+ * @output        json
  */
 
-const yaml = require('@boop/js-yaml')
+const yaml = require('@boop/yaml')
 
 function main(input) {
-
+	let docs
 	try {
-        input.text = JSON.stringify(yaml.safeLoad(input.text), null, 2)
+		docs = yaml.parseAll(input.text)
 	}
 	catch(error) {
 		input.postError("Invalid YAML")
+		return
 	}
-	
-}
\ No newline at end of file
+	input.text = JSON.stringify(docs.length === 1 ? docs[0] : docs, null, 2)
+}
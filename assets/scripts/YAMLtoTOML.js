@@ -0,0 +1,27 @@
+/**!
+ * @name          YAML to TOML
+ * @description   Converts a YAML mapping to TOML.
+ * @icon          metamorphose
+ * @tags          toml,cargo,pyproject,hugo,markup,convert,yaml
+ * @output        toml
+ */
+
+const toml = require('@boop/toml')
+const yaml = require('@boop/yaml')
+
+function main(input) {
+	let obj
+	try {
+		obj = yaml.parse(input.text)
+	}
+	catch(error) {
+		input.postError("Invalid YAML")
+		return
+	}
+	try {
+		input.text = toml.stringify(obj)
+	}
+	catch(error) {
+		input.postError(error.message)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"codeberg.org/sigterm-de/goop/internal/app"
+	"codeberg.org/sigterm-de/goop/internal/cli"
 )
 
 // Injected at build time via -ldflags.
@@ -16,6 +17,12 @@ var (
 )
 
 func main() {
+	// `goop run …` is the headless mode; it must be dispatched before the
+	// GTK application sees (and rejects) the arguments.
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(cli.Run(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	showVersion := flag.Bool("version", false, "print version information and exit")
 	flag.Parse()
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"codeberg.org/sigterm-de/goop/assets"
 	"codeberg.org/sigterm-de/goop/internal/engine"
@@ -32,10 +33,11 @@ func activate(app *gtk.Application, appVersion string) {
 	}
 
 	// ── Logging ───────────────────────────────────────────────────────────────
-	logPath, err := logging.InitLogger(appName)
+	// The window links to the log file InitLogger opened, if any.
+	cfg.LogFilePath, err = logging.InitLogger(appName)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "goop: warning: cannot initialise logger: %v\n", err)
-		logPath = ""
+		cfg.LogFilePath = ""
 	}
 	logging.Log(logging.INFO, "", fmt.Sprintf("goop %s starting", appVersion))
 
@@ -54,7 +56,9 @@ func activate(app *gtk.Application, appVersion string) {
 			result.BuiltInCount, result.UserCount, len(result.SkippedFiles)))
 
 	lib := scripts.NewLibrary(result)
-	exec := engine.NewExecutor()
+	metrics := &engine.Metrics{}
+	exec := engine.Instrument(engine.NewExecutor(), metrics)
+	app.ConnectShutdown(func() { logMetrics(metrics) })
 
 	// ── Preferences ──────────────────────────────────────────────────────────
 	prefs := LoadPreferences()
@@ -70,13 +74,21 @@ func activate(app *gtk.Application, appVersion string) {
 	}
 
 	// ── Window ────────────────────────────────────────────────────────────────
-	win := NewApplicationWindow(app, lib, exec, logPath, prefs, appVersion)
+	win := NewApplicationWindow(app, lib, exec, cfg, prefs, appVersion)
 
 	app.SetAccelsForAction("win.toggle-picker", []string{prefs.ScriptPickerShortcut})
 
 	win.Win.Present()
 }
 
+// logMetrics writes the timing of every script run this session to the log.
+func logMetrics(metrics *engine.Metrics) {
+	for _, m := range metrics.Snapshot() {
+		logging.Log(logging.INFO, m.Name, fmt.Sprintf("runs=%d failures=%d cached=%d mean=%s max=%s",
+			m.Runs, m.Failures, m.Cached, m.Mean().Round(time.Microsecond), m.Max.Round(time.Microsecond)))
+	}
+}
+
 // setupAppIcon writes the embedded icon to the XDG cache and returns the icon
 // theme search-path root (e.g. ~/.cache/goop/icons). Returns "" on failure.
 func setupAppIcon() string {
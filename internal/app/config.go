@@ -15,7 +15,11 @@ const appName = "goop"
 // Base Directory specification.
 type UserConfiguration struct {
 	ScriptsDir    string        // ~/.local/share/goop/scripts/
-	LogFilePath   string        // ~/.config/goop/goop.log
+	LogFilePath   string        // ~/.local/state/goop/goop.log; empty when logging is off
+	ParamsFile    string        // ~/.local/state/goop/params.json — last-used @param values
+	TrustFile     string        // ~/.config/goop/trust.json — approved @permissions by script hash
+	StorageDir    string        // ~/.local/share/goop/storage/ — per-script state.storage files
+	LibDir        string        // ~/.local/share/goop/lib/ — modules for require('@user/...')
 	ScriptTimeout time.Duration // Hard JS execution timeout
 }
 
@@ -27,14 +31,28 @@ func NewUserConfiguration() (UserConfiguration, error) {
 		return UserConfiguration{}, fmt.Errorf("config: create scripts dir: %w", err)
 	}
 
-	logFilePath, err := xdg.ConfigFile(filepath.Join(appName, appName+".log"))
+	logFilePath, err := xdg.StateFile(filepath.Join(appName, appName+".log"))
 	if err != nil {
 		return UserConfiguration{}, fmt.Errorf("config: resolve log path: %w", err)
 	}
 
+	paramsFile, err := xdg.StateFile(filepath.Join(appName, "params.json"))
+	if err != nil {
+		return UserConfiguration{}, fmt.Errorf("config: resolve params path: %w", err)
+	}
+
+	trustFile, err := xdg.ConfigFile(filepath.Join(appName, "trust.json"))
+	if err != nil {
+		return UserConfiguration{}, fmt.Errorf("config: resolve trust store path: %w", err)
+	}
+
 	return UserConfiguration{
 		ScriptsDir:    scriptsDir,
 		LogFilePath:   logFilePath,
+		ParamsFile:    paramsFile,
+		TrustFile:     trustFile,
+		StorageDir:    filepath.Join(xdg.DataHome, appName, "storage"),
+		LibDir:        filepath.Join(xdg.DataHome, appName, "lib"),
 		ScriptTimeout: 5 * time.Second,
 	}, nil
 }
@@ -29,6 +29,10 @@ type AppPreferences struct {
 	// SyntaxAutoDetect controls whether the editor automatically detects and
 	// applies syntax highlighting after each successful script execution.
 	SyntaxAutoDetect bool `json:"syntax_auto_detect"`
+
+	// LockedMode refuses user scripts that are new or changed since they were
+	// last approved, instead of showing them for confirmation.
+	LockedMode bool `json:"locked_mode"`
 }
 
 func defaultPreferences() AppPreferences {
@@ -40,6 +44,7 @@ func defaultPreferences() AppPreferences {
 		EditorSchemeDark:         "oblivion",
 		ScriptPickerShortcut:     "<Primary>slash",
 		SyntaxAutoDetect:         true,
+		LockedMode:               false,
 	}
 }
 
@@ -105,9 +105,11 @@ func monospaceFontFilter() *gtk.Filter {
 }
 
 // ShowSettingsDialog opens a modal preferences window transient to parent.
+// storageDir is the state.storage directory offered for inspection.
 func ShowSettingsDialog(
 	parent *gtk.ApplicationWindow,
 	prefs AppPreferences,
+	storageDir string,
 	onApply func(AppPreferences),
 ) {
 	win := gtk.NewWindow()
@@ -145,6 +147,10 @@ func ShowSettingsDialog(
 	syntaxDetectCheck.SetActive(prefs.SyntaxAutoDetect)
 	syntaxDetectCheck.SetTooltipText("Automatically apply syntax highlighting after running a script")
 
+	lockedCheck := gtk.NewCheckButtonWithLabel("Only run approved user scripts")
+	lockedCheck.SetActive(prefs.LockedMode)
+	lockedCheck.SetTooltipText("Refuse new or changed user scripts instead of asking whether to trust them")
+
 	schemeFollowCheck := gtk.NewCheckButtonWithLabel("Follow system dark/light")
 	schemeFollowCheck.SetActive(prefs.EditorSchemeFollowSystem)
 
@@ -176,6 +182,7 @@ func ShowSettingsDialog(
 		}
 		p.ScriptPickerShortcut = currentAccel
 		p.SyntaxAutoDetect = syntaxDetectCheck.Active()
+		p.LockedMode = lockedCheck.Active()
 		prefs = p
 		onApply(p)
 	}
@@ -184,6 +191,7 @@ func ShowSettingsDialog(
 	monoCheck.ConnectToggled(func() { applyChanges() })
 	syntaxDetectCheck.ConnectToggled(func() { applyChanges() })
 	schemeFollowCheck.ConnectToggled(func() { applyChanges() })
+	lockedCheck.ConnectToggled(func() { applyChanges() })
 	lightDrop.NotifyProperty("selected", func() { applyChanges() })
 	darkDrop.NotifyProperty("selected", func() { applyChanges() })
 
@@ -292,6 +300,16 @@ func ShowSettingsDialog(
 	attachLabel("Keyboard")
 	attachRow("Script picker:", shortcutBtn)
 
+	storageBtn := gtk.NewButtonWithLabel("Manage…")
+	storageBtn.SetHExpand(true)
+	storageBtn.SetTooltipText("Inspect or clear data that scripts keep between runs")
+	storageBtn.ConnectClicked(func() { ShowStorageDialog(win, storageDir) })
+
+	attachSep()
+	attachLabel("Scripts")
+	attachSpan(lockedCheck)
+	attachRow("Stored data:", storageBtn)
+
 	closeBtn := gtk.NewButtonWithLabel("Close")
 	closeBtn.SetHAlign(gtk.AlignEnd)
 	closeBtn.SetMarginTop(8)
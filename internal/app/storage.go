@@ -0,0 +1,182 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+	"codeberg.org/sigterm-de/goop/internal/logging"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+// ShowStorageDialog opens a window listing every script that keeps data in
+// state.storage. Selecting a script shows its stored JSON; Clear deletes it.
+func ShowStorageDialog(parent *gtk.Window, dir string) {
+	win := gtk.NewWindow()
+	win.SetTitle("Script storage")
+	win.SetTransientFor(parent)
+	win.SetModal(true)
+	win.SetDefaultSize(640, 400)
+	win.SetDestroyWithParent(true)
+
+	list := gtk.NewListBox()
+	list.SetSelectionMode(gtk.SelectionSingle)
+
+	view := gtk.NewTextView()
+	view.SetEditable(false)
+	view.SetMonospace(true)
+	view.SetWrapMode(gtk.WrapWordChar)
+	view.SetLeftMargin(8)
+	view.SetTopMargin(8)
+
+	clearBtn := gtk.NewButtonWithLabel("Clear")
+	clearBtn.AddCSSClass("destructive-action")
+	clearBtn.SetSensitive(false)
+
+	var infos []engine.StorageInfo
+	reload := func() {
+		for row := list.RowAtIndex(0); row != nil; row = list.RowAtIndex(0) {
+			list.Remove(row)
+		}
+		view.Buffer().SetText("")
+		clearBtn.SetSensitive(false)
+
+		var err error
+		infos, err = engine.ListStorage(dir)
+		if err != nil {
+			logging.Log(logging.WARN, "", err.Error())
+		}
+		if len(infos) == 0 {
+			empty := gtk.NewLabel("No script has stored any data.")
+			empty.AddCSSClass("no-results-label")
+			row := gtk.NewListBoxRow()
+			row.SetActivatable(false)
+			row.SetSelectable(false)
+			row.SetChild(empty)
+			list.Append(row)
+			return
+		}
+		for _, info := range infos {
+			list.Append(buildStorageRow(info))
+		}
+	}
+
+	list.ConnectRowSelected(func(row *gtk.ListBoxRow) {
+		if row == nil || row.Index() >= len(infos) {
+			view.Buffer().SetText("")
+			clearBtn.SetSensitive(false)
+			return
+		}
+		view.Buffer().SetText(readStorageJSON(infos[row.Index()].Path))
+		clearBtn.SetSensitive(true)
+	})
+
+	clearBtn.ConnectClicked(func() {
+		row := list.SelectedRow()
+		if row == nil || row.Index() >= len(infos) {
+			return
+		}
+		info := infos[row.Index()]
+		if err := engine.OpenStorageFile(info).Clear(); err != nil {
+			logging.Log(logging.WARN, info.ScriptName, err.Error())
+		}
+		reload()
+	})
+
+	listScroll := gtk.NewScrolledWindow()
+	listScroll.SetChild(list)
+	listScroll.SetSizeRequest(220, -1)
+	listScroll.SetVExpand(true)
+
+	viewScroll := gtk.NewScrolledWindow()
+	viewScroll.SetChild(view)
+	viewScroll.SetHExpand(true)
+	viewScroll.SetVExpand(true)
+
+	paned := gtk.NewPaned(gtk.OrientationHorizontal)
+	paned.SetStartChild(listScroll)
+	paned.SetEndChild(viewScroll)
+	paned.SetShrinkStartChild(false)
+
+	closeBtn := gtk.NewButtonWithLabel("Close")
+	closeBtn.ConnectClicked(func() { win.Close() })
+
+	buttons := gtk.NewBox(gtk.OrientationHorizontal, 8)
+	buttons.SetHAlign(gtk.AlignEnd)
+	buttons.SetMarginTop(8)
+	buttons.SetMarginEnd(12)
+	buttons.SetMarginBottom(12)
+	buttons.Append(clearBtn)
+	buttons.Append(closeBtn)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 0)
+	box.Append(paned)
+	box.Append(buttons)
+
+	keyCtrl := gtk.NewEventControllerKey()
+	keyCtrl.ConnectKeyPressed(func(keyval, _ uint, _ gdk.ModifierType) bool {
+		if keyval == gdk.KEY_Escape {
+			win.Close()
+			return true
+		}
+		return false
+	})
+	win.AddController(keyCtrl)
+
+	reload()
+	win.SetChild(box)
+	win.Present()
+}
+
+// buildStorageRow creates a list row showing a script name and a summary of
+// its stored data.
+func buildStorageRow(info engine.StorageInfo) *gtk.Box {
+	name := gtk.NewLabel(info.ScriptName)
+	name.SetXAlign(0)
+	name.AddCSSClass("script-name")
+	name.SetEllipsize(pango.EllipsizeEnd)
+
+	summary := gtk.NewLabel(fmt.Sprintf("%d keys · %s", len(info.Keys), formatBytes(info.Size)))
+	summary.SetXAlign(0)
+	summary.AddCSSClass("script-desc")
+	summary.SetTooltipText(strings.Join(info.Keys, ", "))
+
+	box := gtk.NewBox(gtk.OrientationVertical, 2)
+	box.SetMarginTop(6)
+	box.SetMarginBottom(6)
+	box.SetMarginStart(8)
+	box.SetMarginEnd(8)
+	box.Append(name)
+	box.Append(summary)
+	return box
+}
+
+// readStorageJSON returns the pretty-printed contents of a storage file.
+func readStorageJSON(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err.Error()
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return out.String()
+}
+
+// formatBytes renders a byte count for display, e.g. "512 B" or "3.2 KB".
+func formatBytes(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
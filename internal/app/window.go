@@ -1,6 +1,8 @@
 package app
 
 import (
+	"fmt"
+
 	"codeberg.org/sigterm-de/goop/internal/engine"
 	"codeberg.org/sigterm-de/goop/internal/logging"
 	"codeberg.org/sigterm-de/goop/internal/scripts"
@@ -23,6 +25,7 @@ type ApplicationWindow struct {
 	app        *gtk.Application
 	scriptsBtn *gtk.Button
 	version    string
+	storageDir string
 }
 
 // NewApplicationWindow builds the complete UI hierarchy and wires keyboard shortcuts.
@@ -30,11 +33,11 @@ func NewApplicationWindow(
 	app *gtk.Application,
 	lib scripts.Library,
 	exec engine.Executor,
-	logPath string,
+	cfg UserConfiguration,
 	prefs AppPreferences,
 	version string,
 ) *ApplicationWindow {
-	w := &ApplicationWindow{LogPath: logPath, prefs: prefs, app: app, version: version}
+	w := &ApplicationWindow{LogPath: cfg.LogFilePath, prefs: prefs, app: app, version: version, storageDir: cfg.StorageDir}
 
 	// ── Core widgets ─────────────────────────────────────────────────────────
 	w.editor = ui.NewEditor()
@@ -49,9 +52,21 @@ func NewApplicationWindow(
 	w.revealer.SetVExpand(true)
 
 	// postScript runs on the GTK main thread after every successful script
-	// execution. It re-runs syntax detection and updates the editor and the
-	// status bar syntax zone accordingly.
-	postScript := func() {
+	// execution. A language the script declared for its output wins;
+	// otherwise it re-runs syntax detection. Either way the editor and the
+	// status bar syntax zone are updated accordingly.
+	binaryShown := false
+	postScript := func(lang string) {
+		if data := w.editor.Bytes(); data != nil {
+			w.status.SetSyntaxLanguage(fmt.Sprintf("Binary, %s", formatBytes(int64(len(data)))))
+			binaryShown = true
+			return
+		}
+		if name := ui.LanguageName(lang); name != "" {
+			w.editor.SetLanguage(lang)
+			w.status.SetSyntaxLanguage(name)
+			return
+		}
 		if !w.prefs.SyntaxAutoDetect {
 			return
 		}
@@ -66,15 +81,20 @@ func NewApplicationWindow(
 		}
 	}
 
-	w.picker = ui.NewScriptPicker(lib, exec, w.editor, w.status, logPath, w.HideScriptPicker, postScript)
+	w.picker = ui.NewScriptPicker(lib, exec, w.editor, w.status, cfg.LogFilePath,
+		scripts.LoadParamMemory(cfg.ParamsFile), scripts.LoadTrustStore(cfg.TrustFile),
+		cfg.StorageDir, cfg.LibDir, w.HideScriptPicker, postScript)
+	w.picker.SetLocked(prefs.LockedMode)
 
 	// Clear syntax highlighting and the status bar syntax zone whenever the
 	// editor buffer is fully emptied — prevents stale highlighting from
-	// persisting after the user deletes all content.
+	// persisting after the user deletes all content. Editing a hex dump turns
+	// it into plain text, so the binary indicator goes as well.
 	w.editor.View.Buffer().ConnectChanged(func() {
-		if w.editor.View.Buffer().CharCount() == 0 {
+		if w.editor.View.Buffer().CharCount() == 0 || (binaryShown && w.editor.Bytes() == nil) {
 			w.editor.ClearLanguage()
 			w.status.ClearSyntaxLanguage()
+			binaryShown = false
 		}
 	})
 
@@ -128,11 +148,12 @@ func NewApplicationWindow(
 		if w.prefs.EditorSchemeFollowSystem {
 			w.editor.ApplyScheme(resolveActiveScheme(w.prefs))
 		}
-		ShowSettingsDialog(w.Win, w.prefs, func(newPrefs AppPreferences) {
+		ShowSettingsDialog(w.Win, w.prefs, w.storageDir, func(newPrefs AppPreferences) {
 			if newPrefs.ScriptPickerShortcut != w.prefs.ScriptPickerShortcut {
 				w.app.SetAccelsForAction("win.toggle-picker", []string{newPrefs.ScriptPickerShortcut})
 			}
 			w.prefs = newPrefs
+			w.picker.SetLocked(newPrefs.LockedMode)
 			applyPreferences(newPrefs)
 			w.editor.ApplyScheme(resolveActiveScheme(newPrefs))
 			w.updateShortcutHints(newPrefs)
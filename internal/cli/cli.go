@@ -0,0 +1,303 @@
+// Package cli implements goop's headless mode: `goop run` executes a single
+// script against standard input and writes the transformed text to standard
+// output, so scripts can be used in shell pipelines without a display.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"codeberg.org/sigterm-de/goop/assets"
+	"codeberg.org/sigterm-de/goop/internal/engine"
+	"codeberg.org/sigterm-de/goop/internal/scripts"
+	"github.com/adrg/xdg"
+)
+
+// Exit codes follow the project-wide convention.
+const (
+	exitOK       = 0 // Success
+	exitUsage    = 1 // Bad flags, unknown script or a script error
+	exitInternal = 2 // I/O or loader failure
+)
+
+// paramFlag collects repeated --param key=value flags.
+type paramFlag map[string]string
+
+func (p paramFlag) String() string {
+	pairs := make([]string, 0, len(p))
+	for k, v := range p {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (p paramFlag) Set(s string) error {
+	key, val, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	p[key] = val
+	return nil
+}
+
+// Run executes `goop run` with the arguments following the subcommand and
+// returns the process exit code. The script's output goes to stdout; info
+// messages, errors and usage go to stderr.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("goop run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		printf(stderr, "Usage: goop run [flags] <script name>\n")
+		printf(stderr, "\nReads text from stdin, runs the named script on it and writes the result to stdout.\n")
+		printf(stderr, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	params := paramFlag{}
+	fs.Var(params, "param", "script parameter as `key=value` (repeatable)")
+	scriptsDir := fs.String("scripts-dir", filepath.Join(xdg.DataHome, "goop", "scripts"), "directory containing user scripts")
+	allow := fs.String("allow", "", "comma-separated @permissions to grant without prior approval, e.g. `storage,read-file`")
+	trustFile := fs.String("trust-file", filepath.Join(xdg.ConfigHome, "goop", "trust.json"), "trust store holding permissions approved in the GUI")
+	locked := fs.Bool("locked", false, "refuse user scripts that have not been approved in the GUI")
+	libDir := fs.String("lib-dir", filepath.Join(xdg.DataHome, "goop", "lib"), "directory of modules scripts load with require('@user/name')")
+	storageDir := fs.String("storage-dir", filepath.Join(xdg.DataHome, "goop", "storage"), "directory holding state.storage data")
+	clipIn := fs.String("clipboard", "", "clipboard `text` seen by scripts that declare @permissions clipboard")
+	clipOut := fs.String("clipboard-out", "", "`file` receiving clipboard writes of such scripts")
+	timeout := fs.Duration("timeout", 5*time.Second, "hard execution timeout")
+	seed := fs.Int64("seed", 0, "seed Math.random for a repeatable run (0 = unpredictable)")
+	nowFlag := fs.String("now", "", "freeze the script's clock at this RFC 3339 `time`, e.g. 2024-01-02T15:04:05Z")
+	list := fs.Bool("list", false, "list available scripts and exit")
+	each := fs.String("each", "", "run the script once per `line` or block (blank-line separated) of the input; lines it fails on are kept and reported")
+	timing := fs.Bool("timing", false, "report how long the script took on stderr")
+	stream := fs.Bool("stream", false, "stream stdin to stdout line by line through the script's mapLine or mapRecord; --timeout applies per line")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	var now time.Time
+	if *nowFlag != "" {
+		t, err := time.Parse(time.RFC3339, *nowFlag)
+		if err != nil {
+			printf(stderr, "goop: --now: %v\n", err)
+			return exitUsage
+		}
+		now = t
+	}
+
+	eachMode, err := engine.ParseEachMode(*each)
+	if err != nil {
+		printf(stderr, "goop: --each: %v\n", err)
+		return exitUsage
+	}
+
+	result, err := scripts.NewLoader(assets.Scripts()).Load(*scriptsDir)
+	if err != nil {
+		printf(stderr, "goop: load scripts: %v\n", err)
+		return exitInternal
+	}
+	lib := scripts.NewLibrary(result)
+
+	if *list {
+		for _, s := range lib.All() {
+			printf(stdout, "%s\t%s\n", s.Name, s.Description)
+		}
+		return exitOK
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	script, ok := lib.Find(fs.Arg(0))
+	if !ok {
+		printf(stderr, "goop: no script named %q (see goop run --list)\n", fs.Arg(0))
+		return exitUsage
+	}
+
+	values, err := scripts.ResolveParams(script.Params, params)
+	if err != nil {
+		printf(stderr, "goop: %s: %v\n", script.Name, err)
+		return exitUsage
+	}
+
+	trust := scripts.LoadTrustStore(*trustFile)
+	if *locked && !trust.Approved(script) {
+		printf(stderr, "goop: %s: script is new or changed and has not been approved (locked mode)\n", script.Name)
+		return exitUsage
+	}
+	perms, err := grantedPermissions(script, trust, *allow)
+	if err != nil {
+		printf(stderr, "goop: %s: %v\n", script.Name, err)
+		return exitUsage
+	}
+	// Passing either clipboard flag is the headless equivalent of approving
+	// the clipboard permission in the GUI.
+	var clip engine.Clipboard
+	if isSet(fs, "clipboard") || *clipOut != "" {
+		clip = fileClipboard{text: *clipIn, out: *clipOut}
+		if script.HasPermission(scripts.PermClipboard) && !slices.Contains(perms, engine.PermClipboard) {
+			perms = append(perms, engine.PermClipboard)
+		}
+	}
+
+	input := engine.ExecutionInput{
+		ScriptSource: script.Content,
+		ScriptName:   script.Name,
+		Timeout:      *timeout,
+		Pure:         script.Pure,
+		Seed:         *seed,
+		Now:          now,
+		Each:         eachMode,
+		LibDir:       *libDir,
+		Params:       values,
+		Permissions:  perms,
+		Storage:      engine.NewStorage(*storageDir, script.FilePath, script.Name),
+		Clipboard:    clip,
+		Scripts:      resolver(lib, trust, *locked),
+		Progress: func(fraction float64, message string) {
+			line := fmt.Sprintf("%s: %3.0f%% %s", script.Name, fraction*100, message)
+			printf(stderr, "%s\n", strings.TrimRight(line, " "))
+		},
+	}
+
+	var text string
+	if *stream {
+		input.Stream = &engine.StreamIO{In: stdin, Out: stdout}
+	} else {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			printf(stderr, "goop: read stdin: %v\n", err)
+			return exitInternal
+		}
+		text = string(data)
+		// Input that is not UTF-8 is handed over as bytes, so binary data
+		// survives the run; output bytes are written back unchanged either way.
+		if !utf8.Valid(data) {
+			input.Data = data
+		}
+		// There is no selection on the command line: the whole input is the
+		// selection text and the cursor sits at the end, so state.insert()
+		// appends.
+		end := utf8.RuneCountInString(text)
+		input.FullText = text
+		input.SelectionText = text
+		input.SelectionStart = end
+		input.SelectionEnd = end
+	}
+
+	metrics := &engine.Metrics{}
+	res := engine.Instrument(engine.NewExecutor(), metrics).Execute(context.Background(), input)
+	if *timing {
+		for _, m := range metrics.Snapshot() {
+			printf(stderr, "%s: ran in %s\n", m.Name, m.Total.Round(time.Microsecond))
+		}
+	}
+	if !res.Success {
+		printf(stderr, "goop: %s: %s\n", script.Name, res.ErrorMessage)
+		return exitUsage
+	}
+	if len(res.Failures) > 0 {
+		printf(stderr, "goop: %s: %s\n", script.Name, res.InfoMessage)
+	} else if res.InfoMessage != "" {
+		printf(stderr, "%s\n", res.InfoMessage)
+	}
+	if *stream {
+		return exitOK // the script's output has been written as it came
+	}
+
+	if _, err := io.WriteString(stdout, res.ApplyTo(text)); err != nil {
+		printf(stderr, "goop: write stdout: %v\n", err)
+		return exitInternal
+	}
+	if len(res.Failures) > 0 {
+		return exitUsage // the output holds the failed lines unchanged
+	}
+	return exitOK
+}
+
+// printf writes formatted output to w. There is nowhere left to report a
+// failed write to stdout or stderr, so the error is dropped.
+func printf(w io.Writer, format string, a ...any) {
+	_, _ = fmt.Fprintf(w, format, a...)
+}
+
+// resolver returns the ScriptResolver for goop.run() calls. In locked mode a
+// script may only call scripts that would run on their own.
+func resolver(lib scripts.Library, trust *scripts.TrustStore, locked bool) engine.ScriptResolver {
+	return func(name string, raw map[string]string) (engine.NestedScript, error) {
+		s, ok := lib.Find(name)
+		if !ok {
+			return engine.NestedScript{}, fmt.Errorf("no script named %q", name)
+		}
+		if locked && !trust.Approved(s) {
+			return engine.NestedScript{}, fmt.Errorf("%s has not been approved (locked mode)", s.Name)
+		}
+		values, err := scripts.ResolveParams(s.Params, raw)
+		if err != nil {
+			return engine.NestedScript{}, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		return engine.NestedScript{Name: s.Name, Source: s.Content, Params: values}, nil
+	}
+}
+
+// grantedPermissions returns the permissions for a headless run of s: those
+// approved in the trust store plus those named in allow. Only permissions the
+// script declares can be granted; naming any other is an error.
+func grantedPermissions(s scripts.Script, trust *scripts.TrustStore, allow string) ([]string, error) {
+	perms := scripts.PermissionNames(trust.GrantedPermissions(s))
+	for name := range strings.SplitSeq(allow, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.Contains(perms, name) {
+			continue
+		}
+		if !s.HasPermission(scripts.Permission(name)) {
+			return nil, fmt.Errorf("--allow %s: script does not declare this permission", name)
+		}
+		perms = append(perms, name)
+	}
+	return perms, nil
+}
+
+// isSet reports whether the named flag was passed explicitly.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// fileClipboard stands in for the system clipboard in headless runs: reads
+// return the --clipboard text and writes go to the --clipboard-out file.
+type fileClipboard struct {
+	text string
+	out  string
+}
+
+func (c fileClipboard) Read() (string, error) {
+	return c.text, nil
+}
+
+func (c fileClipboard) Write(text string) error {
+	if c.out == "" {
+		return errors.New("pass --clipboard-out to receive clipboard writes")
+	}
+	return os.WriteFile(c.out, []byte(text), 0o644)
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// bindBytes adds the byte-oriented document API to state: state.isBinary,
+// state.bytes, a fresh Uint8Array copy of the document on every read, and
+// state.setBytes(data), which replaces the document with data.
+func bindBytes(vm *goja.Runtime, stateObj *goja.Object, state *ScriptState) error {
+	if err := stateObj.DefineDataProperty("isBinary", vm.ToValue(state.binary),
+		goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE,
+	); err != nil {
+		return fmt.Errorf("isBinary property: %w", err)
+	}
+
+	if err := stateObj.DefineAccessorProperty("bytes",
+		vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return newUint8Array(vm, bytes.Clone(state.Bytes()))
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE,
+	); err != nil {
+		return fmt.Errorf("bytes property: %w", err)
+	}
+
+	stateObj.Set("setBytes", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("state.setBytes requires a Uint8Array, ArrayBuffer, array of bytes or binary string"))
+		}
+		state.SetBytes(bytesArg(vm, call.Argument(0), "state.setBytes"))
+		return goja.Undefined()
+	})
+	return nil
+}
+
+// bytesArg converts an argument of api, such as state.setBytes(), to a byte
+// slice. Typed arrays share memory with the script, so the result is always a
+// copy.
+func bytesArg(vm *goja.Runtime, v goja.Value, api string) []byte {
+	switch x := v.Export().(type) {
+	case goja.ArrayBuffer:
+		return bytes.Clone(x.Bytes())
+	case string:
+		// A binary string, as returned by btoa()'s counterparts in most
+		// libraries: one character per byte.
+		out := make([]byte, 0, len(x))
+		for _, r := range x {
+			if r > 0xFF {
+				panic(vm.NewTypeError(fmt.Sprintf("%s: character U+%04X in a binary string is not a byte", api, r)))
+			}
+			out = append(out, byte(r))
+		}
+		return out
+	}
+	var b []byte
+	if err := vm.ExportTo(v, &b); err != nil {
+		panic(vm.NewTypeError(fmt.Sprintf("%s: %v", api, err)))
+	}
+	return bytes.Clone(b)
+}
+
+// dataArg returns the bytes of a data argument of api: a string as UTF-8,
+// anything else as bytes.
+func dataArg(vm *goja.Runtime, v goja.Value, api string) []byte {
+	if s, ok := v.Export().(string); ok {
+		return []byte(s)
+	}
+	return bytesArg(vm, v, api)
+}
+
+// newUint8Array returns a Uint8Array over b, which the script may then
+// modify.
+func newUint8Array(vm *goja.Runtime, b []byte) *goja.Object {
+	arr, err := vm.New(vm.Get("Uint8Array"), vm.ToValue(vm.NewArrayBuffer(b)))
+	if err != nil {
+		panic(err)
+	}
+	return arr
+}
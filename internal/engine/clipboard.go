@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// clipboardSession buffers state.clipboard writes for one execution. Like
+// storage, a write only reaches the system clipboard once main() has returned
+// normally; reads after a write see the pending value.
+type clipboardSession struct {
+	clip    Clipboard
+	pending *string
+}
+
+// flush writes the pending value, if any. Safe to call on a nil session.
+func (s *clipboardSession) flush() error {
+	if s == nil || s.pending == nil {
+		return nil
+	}
+	if err := s.clip.Write(*s.pending); err != nil {
+		return fmt.Errorf("clipboard: write: %w", err)
+	}
+	return nil
+}
+
+// bindClipboard exposes state.clipboard backed by a new session for clip.
+func bindClipboard(vm *goja.Runtime, stateObj *goja.Object, clip Clipboard) *clipboardSession {
+	sess := &clipboardSession{clip: clip}
+	obj := vm.NewObject()
+
+	obj.Set("read", func(call goja.FunctionCall) goja.Value {
+		if sess.pending != nil {
+			return vm.ToValue(*sess.pending)
+		}
+		text, err := clip.Read()
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("clipboard: read: %w", err)))
+		}
+		return vm.ToValue(text)
+	})
+
+	obj.Set("write", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("state.clipboard.write requires a string"))
+		}
+		text := call.Argument(0).String()
+		sess.pending = &text
+		return goja.Undefined()
+	})
+
+	stateObj.Set("clipboard", obj)
+	return sess
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dop251/goja"
+)
+
+// maxRunDepth bounds how deeply goop.run() calls may nest, so two scripts
+// calling each other fail quickly instead of running until the timeout.
+const maxRunDepth = 8
+
+// bindGoop exposes the goop global. goop.run(name, text, opts) runs another
+// library script on text in a fresh runtime and returns its resulting text,
+// or throws the message the script failed with. The nested run gets whatever
+// is left of this run's time budget, ending at deadline, and no elevated
+// permissions.
+func (e *executor) bindGoop(ctx context.Context, vm *goja.Runtime, input ExecutionInput, deadline time.Time) {
+	goop := vm.NewObject()
+	goop.Set("run", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 2 || goja.IsUndefined(call.Argument(0)) || goja.IsUndefined(call.Argument(1)) {
+			panic(vm.NewTypeError("goop.run requires a script name and the text to run it on"))
+		}
+		name := call.Argument(0).String()
+		text := call.Argument(1).String()
+		raw := runParams(vm, call.Argument(2))
+
+		if input.Scripts == nil {
+			throwError(vm, "goop.run: no script library is available")
+		}
+		if input.depth >= maxRunDepth {
+			throwError(vm, "goop.run: scripts nested more than %d deep", maxRunDepth)
+		}
+		nested, err := input.Scripts(name, raw)
+		if err != nil {
+			throwError(vm, "goop.run: %v", err)
+		}
+		remaining := time.Until(deadline)
+		if remaining < time.Millisecond {
+			throwError(vm, "goop.run: no time left to run %s", nested.Name)
+		}
+
+		end := utf8.RuneCountInString(text)
+		res := e.Execute(ctx, ExecutionInput{
+			ScriptSource:   nested.Source,
+			ScriptName:     nested.Name,
+			FullText:       text,
+			SelectionText:  text,
+			SelectionStart: end,
+			SelectionEnd:   end,
+			Timeout:        remaining,
+			Params:         nested.Params,
+			Seed:           input.Seed,
+			Now:            input.Now,
+			LibDir:         input.LibDir,
+			Scripts:        input.Scripts,
+			depth:          input.depth + 1,
+		})
+		if !res.Success {
+			throwError(vm, "%s", res.ErrorMessage)
+		}
+		return vm.ToValue(res.ApplyTo(text))
+	})
+	vm.Set("goop", goop)
+}
+
+// runParams converts the params property of goop.run()'s options argument to
+// the raw strings a ScriptResolver takes, as if typed into the parameter
+// dialog.
+func runParams(vm *goja.Runtime, opts goja.Value) map[string]string {
+	if opts == nil || goja.IsUndefined(opts) || goja.IsNull(opts) {
+		return nil
+	}
+	p := opts.ToObject(vm).Get("params")
+	if p == nil || goja.IsUndefined(p) || goja.IsNull(p) {
+		return nil
+	}
+	obj := p.ToObject(vm)
+	raw := make(map[string]string, len(obj.Keys()))
+	for _, k := range obj.Keys() {
+		raw[k] = obj.Get(k).String()
+	}
+	return raw
+}
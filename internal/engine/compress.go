@@ -0,0 +1,246 @@
+package engine
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/dop251/goja"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Limits on decompression. Output is capped so that a small payload cannot
+// expand into gigabytes: a decompression bomb.
+const (
+	defaultMaxDecompressed = 16 << 20  // 16 MB
+	maxDecompressed        = 256 << 20 // 256 MB
+)
+
+// compressor is one format of @boop/compress.
+type compressor struct {
+	minLevel, maxLevel, defaultLevel int
+	writer                           func(w io.Writer, level int) (io.WriteCloser, error)
+	reader                           func(r io.Reader) (io.Reader, error)
+}
+
+// compressors are the formats compress and decompress accept.
+var compressors = map[string]compressor{
+	"gzip": {
+		gzip.HuffmanOnly, gzip.BestCompression, gzip.DefaultCompression,
+		func(w io.Writer, level int) (io.WriteCloser, error) { return gzip.NewWriterLevel(w, level) },
+		func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	},
+	"zlib": {
+		zlib.HuffmanOnly, zlib.BestCompression, zlib.DefaultCompression,
+		func(w io.Writer, level int) (io.WriteCloser, error) { return zlib.NewWriterLevel(w, level) },
+		func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+	},
+	"deflate": {
+		flate.HuffmanOnly, flate.BestCompression, flate.DefaultCompression,
+		func(w io.Writer, level int) (io.WriteCloser, error) { return flate.NewWriter(w, level) },
+		func(r io.Reader) (io.Reader, error) { return flate.NewReader(r), nil },
+	},
+	"brotli": {
+		brotli.BestSpeed, brotli.BestCompression, brotli.DefaultCompression,
+		func(w io.Writer, level int) (io.WriteCloser, error) { return brotli.NewWriterLevel(w, level), nil },
+		func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	},
+	"zstd": {
+		1, 22, 3,
+		func(w io.Writer, level int) (io.WriteCloser, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)), zstd.WithEncoderConcurrency(1))
+		},
+		func(r io.Reader) (io.Reader, error) {
+			d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxDecompressed))
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		},
+	},
+}
+
+// compressModuleLoader returns the loader for @boop/compress, which exposes
+// compress(format, data, opts), decompress(format, data, opts) and formats().
+//
+// compress takes a string, compressed as UTF-8, or bytes, and returns base64
+// unless opts.encoding is "hex" or "bytes" (a Uint8Array); opts.level sets
+// the format's compression level. decompress takes bytes, or a string in
+// opts.encoding: "base64" (the default, either alphabet), "hex" or "binary"
+// (one character per byte). Format "auto" recognises gzip, zlib and zstd by
+// their headers. The result is text, throwing when it is not UTF-8, unless
+// opts.output is "bytes" or "auto", and may be at most opts.maxSize bytes.
+// Both stop when ctx is done, which the executor ties to the script's timeout.
+func compressModuleLoader(ctx context.Context) func(*goja.Runtime, *goja.Object) {
+	return func(vm *goja.Runtime, module *goja.Object) {
+		exports := module.Get("exports").(*goja.Object)
+
+		exports.Set("formats", func(goja.FunctionCall) goja.Value {
+			names := make([]any, 0, len(compressors))
+			for _, name := range slices.Sorted(maps.Keys(compressors)) {
+				names = append(names, name)
+			}
+			return vm.NewArray(names...)
+		})
+
+		exports.Set("compress", func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) < 2 {
+				panic(vm.NewTypeError("compress.compress requires a format and data"))
+			}
+			name := strings.ToLower(call.Argument(0).String())
+			c, ok := compressors[name]
+			if !ok {
+				panic(vm.NewTypeError(fmt.Sprintf("compress.compress: unknown format %q", call.Argument(0).String())))
+			}
+			data := dataArg(vm, call.Argument(1), "compress.compress")
+			opts := optionsObject(vm, call.Argument(2))
+			level := optInt(vm, opts, "level", c.defaultLevel, c.minLevel, c.maxLevel, "compress.compress")
+			var buf bytes.Buffer
+			w, err := c.writer(&buf, level)
+			if err == nil {
+				_, err = io.Copy(w, &ctxReader{ctx, bytes.NewReader(data)})
+				if cerr := w.Close(); err == nil {
+					err = cerr
+				}
+			}
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("compress.compress: %s: %w", name, err)))
+			}
+			switch enc := optString(opts, "encoding", "base64"); enc {
+			case "base64":
+				return vm.ToValue(base64.StdEncoding.EncodeToString(buf.Bytes()))
+			case "hex":
+				return vm.ToValue(hex.EncodeToString(buf.Bytes()))
+			case "bytes":
+				return newUint8Array(vm, buf.Bytes())
+			default:
+				panic(vm.NewTypeError(fmt.Sprintf("compress.compress: unknown encoding %q; use base64, hex or bytes", enc)))
+			}
+		})
+
+		exports.Set("decompress", func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) < 2 {
+				panic(vm.NewTypeError("compress.decompress requires a format and data"))
+			}
+			opts := optionsObject(vm, call.Argument(2))
+			data := compressedArg(vm, call.Argument(1), optString(opts, "encoding", "base64"))
+			limit := optInt(vm, opts, "maxSize", defaultMaxDecompressed, 1, maxDecompressed, "compress.decompress")
+			name := strings.ToLower(call.Argument(0).String())
+			if name == "auto" {
+				name = sniffCompression(data)
+				if name == "" {
+					panic(vm.NewGoError(errors.New("compress.decompress: not gzip, zlib or zstd data")))
+				}
+			}
+			c, ok := compressors[name]
+			if !ok {
+				panic(vm.NewTypeError(fmt.Sprintf("compress.decompress: unknown format %q", call.Argument(0).String())))
+			}
+			out, err := decompress(ctx, c, data, limit)
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("compress.decompress: %s: %w", name, err)))
+			}
+			switch output := optString(opts, "output", "text"); output {
+			case "text":
+				if !utf8.Valid(out) {
+					panic(vm.NewGoError(errors.New(`compress.decompress: the result is not UTF-8 text; use { output: "bytes" }`)))
+				}
+				return vm.ToValue(string(out))
+			case "bytes":
+				return newUint8Array(vm, out)
+			case "auto":
+				if utf8.Valid(out) {
+					return vm.ToValue(string(out))
+				}
+				return newUint8Array(vm, out)
+			default:
+				panic(vm.NewTypeError(fmt.Sprintf("compress.decompress: unknown output %q; use text, bytes or auto", output)))
+			}
+		})
+	}
+}
+
+// compressedArg returns the bytes of decompress's data argument: bytes as
+// they are, or a string in the given encoding.
+func compressedArg(vm *goja.Runtime, v goja.Value, encoding string) []byte {
+	s, ok := v.Export().(string)
+	if !ok || encoding == "binary" {
+		return bytesArg(vm, v, "compress.decompress")
+	}
+	var b []byte
+	var err error
+	switch encoding {
+	case "base64":
+		s = strings.TrimRight(stripSpace(s), "=")
+		if b, err = base64.RawStdEncoding.DecodeString(s); err != nil {
+			b, err = base64.RawURLEncoding.DecodeString(s)
+		}
+	case "hex":
+		b, err = hex.DecodeString(stripSpace(s))
+	default:
+		panic(vm.NewTypeError(fmt.Sprintf("compress.decompress: unknown encoding %q; use base64, hex or binary", encoding)))
+	}
+	if err != nil {
+		panic(vm.NewGoError(fmt.Errorf("compress.decompress: invalid %s: %w", encoding, err)))
+	}
+	return b
+}
+
+// sniffCompression names the format of data from its header, or returns ""
+// when there is none to go by. Raw deflate and brotli have no header.
+func sniffCompression(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return "gzip"
+	case bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "zstd"
+	case len(data) >= 2 && data[0]&0x0f == 8 && (uint16(data[0])<<8|uint16(data[1]))%31 == 0:
+		return "zlib"
+	}
+	return ""
+}
+
+// decompress decompresses data, failing once the output passes limit bytes.
+func decompress(ctx context.Context, c compressor, data []byte, limit int) ([]byte, error) {
+	r, err := c.reader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := r.(io.Closer); ok {
+		defer closer.Close()
+	}
+	// Read one byte past the limit to tell a full-sized result from a cut one.
+	out, err := io.ReadAll(io.LimitReader(&ctxReader{ctx, r}, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > limit {
+		return nil, fmt.Errorf("the result is larger than the %d B limit", limit)
+	}
+	return out, nil
+}
+
+// ctxReader reads from r until ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
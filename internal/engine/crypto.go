@@ -0,0 +1,262 @@
+package engine
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/dop251/goja"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/pbkdf2"
+	"lukechampine.com/blake3"
+)
+
+// cryptoHashes are the algorithms crypto.hash and crypto.hmac accept.
+var cryptoHashes = map[string]func() hash.Hash{
+	"md5":         md5.New,
+	"sha1":        sha1.New,
+	"sha224":      sha256.New224,
+	"sha256":      sha256.New,
+	"sha384":      sha512.New384,
+	"sha512":      sha512.New,
+	"sha512-256":  sha512.New512_256,
+	"sha3-224":    func() hash.Hash { return sha3.New224() },
+	"sha3-256":    func() hash.Hash { return sha3.New256() },
+	"sha3-384":    func() hash.Hash { return sha3.New384() },
+	"sha3-512":    func() hash.Hash { return sha3.New512() },
+	"blake2b-256": func() hash.Hash { h, _ := blake2b.New256(nil); return h },
+	"blake2b-384": func() hash.Hash { h, _ := blake2b.New384(nil); return h },
+	"blake2b-512": func() hash.Hash { h, _ := blake2b.New512(nil); return h },
+	"blake2s-256": func() hash.Hash { h, _ := blake2s.New256(nil); return h },
+	"blake3":      func() hash.Hash { return blake3.New(32, nil) },
+}
+
+// Limits on password hashing work. The hashes run in Go, where the script
+// timeout cannot interrupt them, so their cost is bounded instead.
+const (
+	maxBcryptCost     = 15
+	maxArgon2Time     = 10
+	maxArgon2MemoryKB = 256 * 1024
+	maxPBKDF2Iter     = 5_000_000
+	maxPBKDF2Length   = 1024
+	maxRandomBytes    = 1 << 20
+)
+
+// Argon2id defaults, the second recommended option of RFC 9106.
+const (
+	argon2Time      = 3
+	argon2MemoryKB  = 64 * 1024
+	argon2Threads   = 4
+	argon2KeyLength = 32
+	argon2SaltLen   = 16
+)
+
+// PBKDF2 defaults: OWASP's recommendation for PBKDF2-HMAC-SHA256.
+const (
+	pbkdf2Iter   = 600_000
+	pbkdf2Length = 32
+)
+
+// cryptoModuleLoader exposes hashing, HMAC, password hashing, PBKDF2 key
+// derivation and secure random bytes to scripts.
+//
+// Data may be a string, hashed as UTF-8, or bytes: a Uint8Array, an
+// ArrayBuffer or an array of numbers. Results are hex unless opts.encoding
+// asks for "base64", "base64url" or "bytes" (a Uint8Array).
+func cryptoModuleLoader(vm *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+
+	exports.Set("algorithms", func(goja.FunctionCall) goja.Value {
+		names := make([]any, 0, len(cryptoHashes))
+		for _, name := range slices.Sorted(maps.Keys(cryptoHashes)) {
+			names = append(names, name)
+		}
+		return vm.NewArray(names...)
+	})
+
+	exports.Set("hash", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 2 {
+			panic(vm.NewTypeError("crypto.hash requires an algorithm and data"))
+		}
+		h := cryptoHash(vm, "crypto.hash", call.Argument(0))()
+		h.Write(dataArg(vm, call.Argument(1), "crypto.hash"))
+		return cryptoEncode(vm, "crypto.hash", h.Sum(nil), call.Argument(2))
+	})
+
+	exports.Set("hmac", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 3 {
+			panic(vm.NewTypeError("crypto.hmac requires an algorithm, a key and data"))
+		}
+		newHash := cryptoHash(vm, "crypto.hmac", call.Argument(0))
+		mac := hmac.New(newHash, dataArg(vm, call.Argument(1), "crypto.hmac"))
+		mac.Write(dataArg(vm, call.Argument(2), "crypto.hmac"))
+		return cryptoEncode(vm, "crypto.hmac", mac.Sum(nil), call.Argument(3))
+	})
+
+	exports.Set("hashPassword", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("crypto.hashPassword requires a password"))
+		}
+		password := dataArg(vm, call.Argument(0), "crypto.hashPassword")
+		opts := optionsObject(vm, call.Argument(1))
+		switch algo := optString(opts, "algorithm", "bcrypt"); algo {
+		case "bcrypt":
+			cost := optInt(vm, opts, "cost", bcrypt.DefaultCost, bcrypt.MinCost, maxBcryptCost, "crypto.hashPassword")
+			out, err := bcrypt.GenerateFromPassword(password, cost)
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("crypto.hashPassword: %w", err)))
+			}
+			return vm.ToValue(string(out))
+		case "argon2id":
+			p := argon2Params{
+				time:    uint32(optInt(vm, opts, "time", argon2Time, 1, maxArgon2Time, "crypto.hashPassword")),
+				memory:  uint32(optInt(vm, opts, "memory", argon2MemoryKB, 8, maxArgon2MemoryKB, "crypto.hashPassword")),
+				threads: uint8(optInt(vm, opts, "threads", argon2Threads, 1, 255, "crypto.hashPassword")),
+				salt:    make([]byte, argon2SaltLen),
+			}
+			if _, err := rand.Read(p.salt); err != nil {
+				panic(vm.NewGoError(fmt.Errorf("crypto.hashPassword: %w", err)))
+			}
+			p.key = argon2.IDKey(password, p.salt, p.time, p.memory, p.threads, argon2KeyLength)
+			return vm.ToValue(p.String())
+		default:
+			panic(vm.NewTypeError(fmt.Sprintf("crypto.hashPassword: unknown algorithm %q; use bcrypt or argon2id", algo)))
+		}
+	})
+
+	exports.Set("verifyPassword", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 2 {
+			panic(vm.NewTypeError("crypto.verifyPassword requires a password and a hash"))
+		}
+		password := dataArg(vm, call.Argument(0), "crypto.verifyPassword")
+		encoded := strings.TrimSpace(call.Argument(1).String())
+		if strings.HasPrefix(encoded, "$argon2id$") {
+			p, err := parseArgon2(encoded)
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("crypto.verifyPassword: %w", err)))
+			}
+			key := argon2.IDKey(password, p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+			return vm.ToValue(subtle.ConstantTimeCompare(key, p.key) == 1)
+		}
+		if cost, err := bcrypt.Cost([]byte(encoded)); err == nil && cost > maxBcryptCost {
+			panic(vm.NewGoError(fmt.Errorf("crypto.verifyPassword: bcrypt cost %d is above the limit of %d", cost, maxBcryptCost)))
+		}
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), password)
+		switch {
+		case err == nil:
+			return vm.ToValue(true)
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return vm.ToValue(false)
+		}
+		panic(vm.NewGoError(fmt.Errorf("crypto.verifyPassword: not a bcrypt or argon2id hash: %w", err)))
+	})
+
+	exports.Set("pbkdf2", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 2 {
+			panic(vm.NewTypeError("crypto.pbkdf2 requires a password and a salt"))
+		}
+		password := dataArg(vm, call.Argument(0), "crypto.pbkdf2")
+		salt := dataArg(vm, call.Argument(1), "crypto.pbkdf2")
+		opts := optionsObject(vm, call.Argument(2))
+		iter := optInt(vm, opts, "iterations", pbkdf2Iter, 1, maxPBKDF2Iter, "crypto.pbkdf2")
+		length := optInt(vm, opts, "length", pbkdf2Length, 1, maxPBKDF2Length, "crypto.pbkdf2")
+		newHash := cryptoHash(vm, "crypto.pbkdf2", vm.ToValue(optString(opts, "hash", "sha256")))
+		key := pbkdf2.Key(password, salt, iter, length, newHash)
+		return cryptoEncode(vm, "crypto.pbkdf2", key, call.Argument(2))
+	})
+
+	exports.Set("randomBytes", func(call goja.FunctionCall) goja.Value {
+		n := call.Argument(0).ToInteger()
+		if n < 0 || n > maxRandomBytes {
+			panic(vm.NewTypeError(fmt.Sprintf("crypto.randomBytes: length must be between 0 and %d", maxRandomBytes)))
+		}
+		b := make([]byte, n)
+		if _, err := rand.Read(b); err != nil {
+			panic(vm.NewGoError(fmt.Errorf("crypto.randomBytes: %w", err)))
+		}
+		return cryptoEncode(vm, "crypto.randomBytes", b, call.Argument(1))
+	})
+}
+
+// cryptoHash returns the constructor of the named hash.
+func cryptoHash(vm *goja.Runtime, api string, name goja.Value) func() hash.Hash {
+	newHash, ok := cryptoHashes[strings.ToLower(name.String())]
+	if !ok {
+		panic(vm.NewTypeError(fmt.Sprintf("%s: unknown algorithm %q", api, name.String())))
+	}
+	return newHash
+}
+
+// cryptoEncode returns b in the encoding opts asks for.
+func cryptoEncode(vm *goja.Runtime, api string, b []byte, opts goja.Value) goja.Value {
+	switch enc := optString(optionsObject(vm, opts), "encoding", "hex"); enc {
+	case "hex":
+		return vm.ToValue(hex.EncodeToString(b))
+	case "base64":
+		return vm.ToValue(base64.StdEncoding.EncodeToString(b))
+	case "base64url":
+		return vm.ToValue(base64.RawURLEncoding.EncodeToString(b))
+	case "bytes":
+		return newUint8Array(vm, b)
+	default:
+		panic(vm.NewTypeError(fmt.Sprintf("%s: unknown encoding %q; use hex, base64, base64url or bytes", api, enc)))
+	}
+}
+
+// argon2Params is an Argon2id hash with the parameters it was made with.
+type argon2Params struct {
+	time, memory uint32
+	threads      uint8
+	salt, key    []byte
+}
+
+// String returns the hash in the PHC string format other Argon2
+// implementations read.
+func (p argon2Params) String() string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(p.salt), base64.RawStdEncoding.EncodeToString(p.key))
+}
+
+// parseArgon2 parses an Argon2id hash in the PHC string format, refusing
+// parameters above the limits hashPassword enforces.
+func parseArgon2(s string) (argon2Params, error) {
+	var p argon2Params
+	parts := strings.Split(s, "$")
+	if len(parts) != 6 {
+		return p, errors.New("malformed argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, fmt.Errorf("malformed argon2id parameters %q", parts[3])
+	}
+	if p.time < 1 || p.time > maxArgon2Time || p.memory < 8 || p.memory > maxArgon2MemoryKB || p.threads < 1 {
+		return p, fmt.Errorf("argon2id parameters %q are outside the supported limits", parts[3])
+	}
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(p.key) == 0 {
+		return p, errors.New("malformed argon2id key")
+	}
+	return p, nil
+}
@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// EachMode selects how ExecutionInput.Each splits the selection.
+type EachMode int
+
+const (
+	EachNone  EachMode = iota // Run once on the whole selection
+	EachLine                  // Run once per non-blank line
+	EachBlock                 // Run once per block of lines between blank lines
+)
+
+// ParseEachMode parses the name of an EachMode as given on the command line:
+// "line", "block", or "" for EachNone.
+func ParseEachMode(s string) (EachMode, error) {
+	switch s {
+	case "":
+		return EachNone, nil
+	case "line":
+		return EachLine, nil
+	case "block":
+		return EachBlock, nil
+	}
+	return EachNone, fmt.Errorf("unknown mode %q: use line or block", s)
+}
+
+// String returns the unit name used in messages: "line" or "block".
+func (m EachMode) String() string {
+	switch m {
+	case EachLine:
+		return "line"
+	case EachBlock:
+		return "block"
+	}
+	return "selection"
+}
+
+// UnitFailure is a line or block an ExecutionInput.Each run failed on.
+type UnitFailure struct {
+	Line    int    // 1-based line of the selection the unit starts on
+	Message string // The script's error message
+}
+
+// maxReportedFailures bounds how many failures the summary message spells out.
+const maxReportedFailures = 3
+
+// unit is a line or block of the selection, as byte offsets into it.
+type unit struct {
+	start, end int
+	line       int
+}
+
+// splitUnits returns the non-blank lines of text, or its blocks of
+// consecutive non-blank lines, without their line endings.
+func splitUnits(text string, mode EachMode) []unit {
+	var units []unit
+	line, pos := 0, 0
+	for pos <= len(text) {
+		line++
+		end := strings.IndexByte(text[pos:], '\n')
+		next := len(text) + 1
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += pos
+			next = end + 1
+		}
+		content := strings.TrimSuffix(text[pos:end], "\r")
+		switch {
+		case strings.TrimSpace(content) == "":
+		case mode == EachBlock && len(units) > 0 && units[len(units)-1].end == blockJoin(text, pos):
+			units[len(units)-1].end = pos + len(content)
+		default:
+			units = append(units, unit{start: pos, end: pos + len(content), line: line})
+		}
+		pos = next
+	}
+	return units
+}
+
+// blockJoin returns where the previous line ends, line ending excluded, given
+// the start of the line after it, so a block can grow by adjacent lines only.
+func blockJoin(text string, pos int) int {
+	end := pos - 1 // the '\n'
+	if end > 0 && text[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+// runEach runs input once per unit of its selection through run, which
+// returns the unit's result and whether the whole run must stop with it (a
+// timeout or cancellation), and reassembles the results.
+func runEach(input ExecutionInput, run func(ExecutionInput) (res ExecutionResult, stop bool)) ExecutionResult {
+	if input.Data != nil {
+		return ExecutionResult{
+			Success:      false,
+			ScriptName:   input.ScriptName,
+			ErrorMessage: fmt.Sprintf("a binary document has no lines to run %s on one %s at a time", input.ScriptName, input.Each),
+		}
+	}
+
+	text := input.SelectionText
+	units := splitUnits(text, input.Each)
+	var (
+		out      strings.Builder
+		failures []UnitFailure
+		info     string
+		language string
+	)
+	last := 0
+	for _, u := range units {
+		part := text[u.start:u.end]
+		end := utf8.RuneCountInString(part)
+		unitInput := input
+		unitInput.FullText = part
+		unitInput.SelectionText = part
+		unitInput.SelectionStart = 0
+		unitInput.SelectionEnd = end
+		unitInput.Each = EachNone
+
+		res, stop := run(unitInput)
+		if stop {
+			return res
+		}
+		out.WriteString(text[last:u.start])
+		last = u.end
+		if !res.Success {
+			failures = append(failures, UnitFailure{Line: u.line, Message: res.ErrorMessage})
+			out.WriteString(part)
+			continue
+		}
+		out.WriteString(res.ApplyTo(part))
+		if res.InfoMessage != "" {
+			info = res.InfoMessage
+		}
+		if language == "" {
+			language = res.Language
+		}
+	}
+	out.WriteString(text[last:])
+
+	if len(failures) > 0 {
+		info = failureSummary(input.Each, failures, len(units))
+	}
+	if len(units) > 0 && len(failures) == len(units) {
+		return ExecutionResult{
+			Success:      false,
+			ScriptName:   input.ScriptName,
+			ErrorMessage: info,
+			Failures:     failures,
+		}
+	}
+	if len(units) == 0 {
+		return ExecutionResult{Success: true, ScriptName: input.ScriptName}
+	}
+	return ExecutionResult{
+		Success:      true,
+		MutationKind: MutationReplaceSelect,
+		NewText:      out.String(),
+		InfoMessage:  info,
+		ScriptName:   input.ScriptName,
+		Language:     language,
+		Failures:     failures,
+	}
+}
+
+// failureSummary describes the failures of a run over total units, spelling
+// out the first few: "failed on 2 of 9 lines; line 3: …; line 7: …".
+func failureSummary(mode EachMode, failures []UnitFailure, total int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed on %d of %d %ss", len(failures), total, mode)
+	for i, f := range failures {
+		if i == maxReportedFailures {
+			fmt.Fprintf(&b, "; and %d more", len(failures)-i)
+			break
+		}
+		fmt.Fprintf(&b, "; line %d: %s", f.Line, f.Message)
+	}
+	return b.String()
+}
@@ -0,0 +1,559 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/ascii85"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"mime/quotedprintable"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dop251/goja"
+)
+
+// codec is one format of @boop/encoding. Both directions read their options
+// from opts, which may be nil.
+type codec struct {
+	encode func(vm *goja.Runtime, b []byte, opts *goja.Object) (string, error)
+	decode func(vm *goja.Runtime, s string, opts *goja.Object) ([]byte, error)
+}
+
+// Alphabets of the formats encoding/base32 and encoding/base64 lack.
+const (
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	base58Bitcoin     = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	base58Flickr      = "123456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+	z85Alphabet       = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ.-:+=^!/*?&<>()[]{}@%$#"
+)
+
+// maxHexdumpColumns bounds the bytes a hex dump line may show.
+const maxHexdumpColumns = 256
+
+// maxBase58Len bounds the bytes base58 encodes and decodes to, and
+// maxBase58Text the characters of their encoding. Conversion takes time
+// quadratic in the length and runs in Go, where the script timeout cannot
+// interrupt it; base58 is meant for keys and IDs, not documents.
+const (
+	maxBase58Len  = 8 << 10
+	maxBase58Text = maxBase58Len*138/100 + 1 // log(256)/log(58) < 1.38
+)
+
+// codecs are the formats encode and decode accept.
+var codecs = map[string]codec{
+	"base32":          base32Codec(base32.StdEncoding, true, nil),
+	"base32hex":       base32Codec(base32.HexEncoding, true, nil),
+	"base32crockford": base32Codec(base32.NewEncoding(crockfordAlphabet), false, crockfordReplacer),
+	"base58":          base58Codec(base58Bitcoin),
+	"base58flickr":    base58Codec(base58Flickr),
+	"base64":          base64Codec(base64.StdEncoding, true),
+	"base64url":       base64Codec(base64.URLEncoding, false),
+	"ascii85":         {encodeASCII85, decodeASCII85},
+	"z85":             {encodeZ85, decodeZ85},
+	"quoted-printable": {
+		encodeQuotedPrintable,
+		func(_ *goja.Runtime, s string, _ *goja.Object) ([]byte, error) {
+			return io.ReadAll(quotedprintable.NewReader(strings.NewReader(s)))
+		},
+	},
+	"uuencode": {encodeUU, decodeUU},
+	"percent":  {encodePercent, decodePercent},
+	"hex":      {encodeHex, decodeHex},
+	"hexdump":  {encodeHexdump, decodeHexdump},
+}
+
+// encodingModuleLoader exposes conversions between bytes and text formats:
+// encode(format, data, opts), decode(format, text, opts) and formats().
+//
+// Data is a string, encoded as UTF-8, or bytes: a Uint8Array, an ArrayBuffer
+// or an array of numbers. decode returns text unless opts.output is "bytes"
+// (a Uint8Array) or "auto" (text when the result is UTF-8, bytes otherwise).
+func encodingModuleLoader(vm *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+
+	exports.Set("formats", func(goja.FunctionCall) goja.Value {
+		names := make([]any, 0, len(codecs))
+		for _, name := range slices.Sorted(maps.Keys(codecs)) {
+			names = append(names, name)
+		}
+		return vm.NewArray(names...)
+	})
+
+	exports.Set("encode", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 2 {
+			panic(vm.NewTypeError("encoding.encode requires a format and data"))
+		}
+		c := encodingCodec(vm, "encoding.encode", call.Argument(0))
+		out, err := c.encode(vm, dataArg(vm, call.Argument(1), "encoding.encode"), optionsObject(vm, call.Argument(2)))
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("encoding.encode: %w", err)))
+		}
+		return vm.ToValue(out)
+	})
+
+	exports.Set("decode", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 2 {
+			panic(vm.NewTypeError("encoding.decode requires a format and text"))
+		}
+		c := encodingCodec(vm, "encoding.decode", call.Argument(0))
+		opts := optionsObject(vm, call.Argument(2))
+		b, err := c.decode(vm, call.Argument(1).String(), opts)
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("encoding.decode: %w", err)))
+		}
+		switch output := optString(opts, "output", "text"); output {
+		case "text":
+			if !utf8.Valid(b) {
+				panic(vm.NewGoError(errors.New(`encoding.decode: the result is not UTF-8 text; use { output: "bytes" }`)))
+			}
+			return vm.ToValue(string(b))
+		case "bytes":
+			return newUint8Array(vm, b)
+		case "auto":
+			if utf8.Valid(b) {
+				return vm.ToValue(string(b))
+			}
+			return newUint8Array(vm, b)
+		default:
+			panic(vm.NewTypeError(fmt.Sprintf("encoding.decode: unknown output %q; use text, bytes or auto", output)))
+		}
+	})
+}
+
+// encodingCodec returns the codec of the named format.
+func encodingCodec(vm *goja.Runtime, api string, name goja.Value) codec {
+	c, ok := codecs[strings.ToLower(name.String())]
+	if !ok {
+		panic(vm.NewTypeError(fmt.Sprintf("%s: unknown format %q", api, name.String())))
+	}
+	return c
+}
+
+// stripSpace removes all whitespace from s, which encoded text may be
+// wrapped or indented with.
+func stripSpace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+// crockfordReplacer normalises Crockford's base32, which ignores hyphens and
+// reads the letters that look like digits as those digits.
+var crockfordReplacer = strings.NewReplacer("-", "", "O", "0", "I", "1", "L", "1")
+
+// base32Codec writes enc with padding unless opts.padding is false, or never
+// when the format has none. Decoding takes either, in either case, after
+// applying normalise when it is set.
+func base32Codec(enc *base32.Encoding, padded bool, normalise *strings.Replacer) codec {
+	raw := enc.WithPadding(base32.NoPadding)
+	return codec{
+		encode: func(_ *goja.Runtime, b []byte, opts *goja.Object) (string, error) {
+			if padded && optBool(opts, "padding", true) {
+				return enc.EncodeToString(b), nil
+			}
+			return raw.EncodeToString(b), nil
+		},
+		decode: func(_ *goja.Runtime, s string, _ *goja.Object) ([]byte, error) {
+			s = strings.TrimRight(strings.ToUpper(stripSpace(s)), "=")
+			if normalise != nil {
+				s = normalise.Replace(s)
+			}
+			return raw.DecodeString(s)
+		},
+	}
+}
+
+// base64Codec writes enc with padding unless opts.padding says otherwise;
+// padded is the default. Decoding takes either.
+func base64Codec(enc *base64.Encoding, padded bool) codec {
+	raw := enc.WithPadding(base64.NoPadding)
+	return codec{
+		encode: func(_ *goja.Runtime, b []byte, opts *goja.Object) (string, error) {
+			if optBool(opts, "padding", padded) {
+				return enc.EncodeToString(b), nil
+			}
+			return raw.EncodeToString(b), nil
+		},
+		decode: func(_ *goja.Runtime, s string, _ *goja.Object) ([]byte, error) {
+			return raw.DecodeString(strings.TrimRight(stripSpace(s), "="))
+		},
+	}
+}
+
+// base58Codec converts with the given alphabet. Leading zero bytes are
+// written as the alphabet's first character, one each.
+func base58Codec(alphabet string) codec {
+	return codec{
+		encode: func(_ *goja.Runtime, b []byte, _ *goja.Object) (string, error) {
+			if len(b) > maxBase58Len {
+				return "", fmt.Errorf("base58 input is limited to %d bytes, got %d", maxBase58Len, len(b))
+			}
+			zeros := 0
+			for zeros < len(b) && b[zeros] == 0 {
+				zeros++
+			}
+			var out []byte
+			n := new(big.Int).SetBytes(b)
+			radix, mod := big.NewInt(58), new(big.Int)
+			for n.Sign() > 0 {
+				n.DivMod(n, radix, mod)
+				out = append(out, alphabet[mod.Int64()])
+			}
+			out = append(out, bytes.Repeat([]byte{alphabet[0]}, zeros)...)
+			slices.Reverse(out)
+			return string(out), nil
+		},
+		decode: func(_ *goja.Runtime, s string, _ *goja.Object) ([]byte, error) {
+			s = stripSpace(s)
+			if len(s) > maxBase58Text {
+				return nil, fmt.Errorf("base58 text is limited to %d characters, got %d", maxBase58Text, len(s))
+			}
+			zeros := 0
+			for zeros < len(s) && s[zeros] == alphabet[0] {
+				zeros++
+			}
+			n, radix := new(big.Int), big.NewInt(58)
+			for i, c := range s {
+				digit := strings.IndexRune(alphabet, c)
+				if digit < 0 {
+					return nil, fmt.Errorf("illegal base58 data at input byte %d", i)
+				}
+				n.Mul(n, radix).Add(n, big.NewInt(int64(digit)))
+			}
+			return append(make([]byte, zeros), n.Bytes()...), nil
+		},
+	}
+}
+
+// encodeASCII85 writes Adobe's ascii85, inside <~ ~> when opts.delimiters
+// is set.
+func encodeASCII85(_ *goja.Runtime, b []byte, opts *goja.Object) (string, error) {
+	out := make([]byte, ascii85.MaxEncodedLen(len(b)))
+	out = out[:ascii85.Encode(out, b)]
+	if optBool(opts, "delimiters", false) {
+		return "<~" + string(out) + "~>", nil
+	}
+	return string(out), nil
+}
+
+// decodeASCII85 reads ascii85, with or without the <~ ~> delimiters.
+func decodeASCII85(_ *goja.Runtime, s string, _ *goja.Object) ([]byte, error) {
+	s = stripSpace(s)
+	if inner, ok := strings.CutPrefix(s, "<~"); ok {
+		s = strings.TrimSuffix(inner, "~>")
+	}
+	out := make([]byte, 4*len(s))
+	n, _, err := ascii85.Decode(out, []byte(s), true)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
+
+// encodeZ85 writes ZeroMQ's Z85, which takes data in whole 4-byte frames.
+func encodeZ85(_ *goja.Runtime, b []byte, _ *goja.Object) (string, error) {
+	if len(b)%4 != 0 {
+		return "", fmt.Errorf("z85 data must be a multiple of 4 bytes long, not %d", len(b))
+	}
+	out := make([]byte, 0, len(b)/4*5)
+	for i := 0; i < len(b); i += 4 {
+		v := uint32(b[i])<<24 | uint32(b[i+1])<<16 | uint32(b[i+2])<<8 | uint32(b[i+3])
+		var frame [5]byte
+		for j := 4; j >= 0; j-- {
+			frame[j] = z85Alphabet[v%85]
+			v /= 85
+		}
+		out = append(out, frame[:]...)
+	}
+	return string(out), nil
+}
+
+// decodeZ85 reads Z85 text, a multiple of 5 characters long.
+func decodeZ85(_ *goja.Runtime, s string, _ *goja.Object) ([]byte, error) {
+	s = stripSpace(s)
+	if len(s)%5 != 0 {
+		return nil, fmt.Errorf("z85 text must be a multiple of 5 characters long, not %d", len(s))
+	}
+	out := make([]byte, 0, len(s)/5*4)
+	for i := 0; i < len(s); i += 5 {
+		var v uint64
+		for j, c := range s[i : i+5] {
+			digit := strings.IndexRune(z85Alphabet, c)
+			if digit < 0 {
+				return nil, fmt.Errorf("illegal z85 data at input byte %d", i+j)
+			}
+			v = v*85 + uint64(digit)
+		}
+		if v > 1<<32-1 {
+			return nil, fmt.Errorf("z85 frame %q is out of range", s[i:i+5])
+		}
+		out = append(out, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	return out, nil
+}
+
+// encodeQuotedPrintable writes quoted-printable text with \n line endings,
+// or \r\n when opts.crlf is set. opts.binary encodes line breaks too.
+func encodeQuotedPrintable(_ *goja.Runtime, b []byte, opts *goja.Object) (string, error) {
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	w.Binary = optBool(opts, "binary", false)
+	if _, err := w.Write(b); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	if optBool(opts, "crlf", false) {
+		return buf.String(), nil
+	}
+	return strings.ReplaceAll(buf.String(), "\r\n", "\n"), nil
+}
+
+// encodeUU writes a uuencoded file: a begin line with opts.mode (default
+// 644) and opts.name (default "data"), lines of up to 45 bytes and an end
+// line.
+func encodeUU(vm *goja.Runtime, b []byte, opts *goja.Object) (string, error) {
+	mode := optString(opts, "mode", "644")
+	if _, err := strconv.ParseUint(mode, 8, 32); err != nil {
+		panic(vm.NewTypeError(fmt.Sprintf("encoding.encode: uuencode mode %q is not octal", mode)))
+	}
+	name := optString(opts, "name", "data")
+	if name == "" || strings.ContainsAny(name, "\r\n") {
+		panic(vm.NewTypeError("encoding.encode: uuencode name must be a single line"))
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "begin %s %s\n", mode, name)
+	for line := range slices.Chunk(b, 45) {
+		sb.WriteByte(uuChar(byte(len(line))))
+		for i := 0; i < len(line); i += 3 {
+			var g [3]byte
+			copy(g[:], line[i:])
+			sb.WriteByte(uuChar(g[0] >> 2))
+			sb.WriteByte(uuChar(g[0]<<4&0x30 | g[1]>>4))
+			sb.WriteByte(uuChar(g[1]<<2&0x3c | g[2]>>6))
+			sb.WriteByte(uuChar(g[2] & 0x3f))
+		}
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("`\nend\n")
+	return sb.String(), nil
+}
+
+// uuChar returns the character of a 6-bit value, writing 0 as a backtick
+// rather than a space so trailing spaces can be trimmed safely.
+func uuChar(v byte) byte {
+	if v == 0 {
+		return '`'
+	}
+	return v + ' '
+}
+
+// decodeUU reads a uuencoded file, or just its lines when the begin line is
+// missing.
+func decodeUU(_ *goja.Runtime, s string, _ *goja.Object) ([]byte, error) {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "begin ") {
+			lines = lines[i+1:]
+			break
+		}
+	}
+	var out []byte
+	for i, line := range lines {
+		if line == "end" {
+			break
+		}
+		if strings.ContainsFunc(line, func(r rune) bool { return r < ' ' || r > '`' }) {
+			return nil, fmt.Errorf("line %d is not uuencoded", i+1)
+		}
+		if line == "" {
+			continue
+		}
+		n := int((line[0] - ' ') & 0x3f)
+		// Some encoders trim the trailing spaces that encode zeros.
+		body := line[1:]
+		if need := (n + 2) / 3 * 4; len(body) < need {
+			body += strings.Repeat(" ", need-len(body))
+		}
+		decoded := make([]byte, 0, n+2)
+		for j := 0; len(decoded) < n; j += 4 {
+			var g [4]byte
+			for k := range g {
+				g[k] = (body[j+k] - ' ') & 0x3f
+			}
+			decoded = append(decoded, g[0]<<2|g[1]>>4, g[1]<<4|g[2]>>2, g[2]<<6|g[3])
+		}
+		out = append(out, decoded[:n]...)
+	}
+	return out, nil
+}
+
+// percentUnreserved reports whether c is left alone in the given mode:
+// "component" keeps the unreserved characters of RFC 3986, "path" also keeps
+// / and the sub-delimiters allowed in paths, "query" is form encoding with
+// spaces as +, and "all" encodes every byte.
+func percentUnreserved(c byte, mode string) bool {
+	if mode == "all" {
+		return false
+	}
+	if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) >= 0 {
+		return true
+	}
+	return mode == "path" && strings.IndexByte("/!$&'()*+,;=:@", c) >= 0
+}
+
+// encodePercent percent-encodes b in opts.mode, "component" by default.
+func encodePercent(vm *goja.Runtime, b []byte, opts *goja.Object) (string, error) {
+	mode := percentMode(vm, opts, "encoding.encode")
+	var sb strings.Builder
+	for _, c := range b {
+		switch {
+		case mode == "query" && c == ' ':
+			sb.WriteByte('+')
+		case percentUnreserved(c, mode):
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String(), nil
+}
+
+// decodePercent decodes %XX escapes, and + as a space in "query" mode.
+func decodePercent(vm *goja.Runtime, s string, opts *goja.Object) ([]byte, error) {
+	var out string
+	var err error
+	if percentMode(vm, opts, "encoding.decode") == "query" {
+		out, err = url.QueryUnescape(s)
+	} else {
+		out, err = url.PathUnescape(s)
+	}
+	return []byte(out), err
+}
+
+// percentMode returns opts.mode for the percent format.
+func percentMode(vm *goja.Runtime, opts *goja.Object, api string) string {
+	switch mode := optString(opts, "mode", "component"); mode {
+	case "component", "path", "query", "all":
+		return mode
+	default:
+		panic(vm.NewTypeError(fmt.Sprintf("%s: unknown percent mode %q; use component, path, query or all", api, mode)))
+	}
+}
+
+// encodeHex writes lowercase hex, or uppercase when opts.uppercase is set,
+// with opts.separator between bytes.
+func encodeHex(_ *goja.Runtime, b []byte, opts *goja.Object) (string, error) {
+	digits := hex.EncodeToString(b)
+	if optBool(opts, "uppercase", false) {
+		digits = strings.ToUpper(digits)
+	}
+	sep := optString(opts, "separator", "")
+	if sep == "" || len(b) == 0 {
+		return digits, nil
+	}
+	pairs := make([]string, len(b))
+	for i := range pairs {
+		pairs[i] = digits[2*i : 2*i+2]
+	}
+	return strings.Join(pairs, sep), nil
+}
+
+// hexNoiseRE matches what decodeHex skips between digits: whitespace, the
+// usual byte separators, and 0x or \x prefixes.
+var hexNoiseRE = regexp.MustCompile(`(?i)\s+|[:,-]|0x|\\x`)
+
+// decodeHex reads hex digits in either case.
+func decodeHex(_ *goja.Runtime, s string, _ *goja.Object) ([]byte, error) {
+	return hex.DecodeString(hexNoiseRE.ReplaceAllString(s, ""))
+}
+
+// encodeHexdump writes a dump in the layout of xxd: an offset, opts.columns
+// bytes a line (default 16) in groups of opts.group (default 2, 0 for one
+// run), and the bytes as ASCII with . for anything unprintable.
+func encodeHexdump(vm *goja.Runtime, b []byte, opts *goja.Object) (string, error) {
+	cols := optInt(vm, opts, "columns", 16, 1, maxHexdumpColumns, "encoding.encode")
+	group := optInt(vm, opts, "group", 2, 0, maxHexdumpColumns, "encoding.encode")
+	if group == 0 {
+		group = cols
+	}
+	upper := optBool(opts, "uppercase", false)
+	width := 2*cols + (cols+group-1)/group - 1
+	var sb strings.Builder
+	for off := 0; off < len(b); off += cols {
+		line := b[off:min(off+cols, len(b))]
+		var hexPart strings.Builder
+		for i, c := range line {
+			if i > 0 && i%group == 0 {
+				hexPart.WriteByte(' ')
+			}
+			if upper {
+				fmt.Fprintf(&hexPart, "%02X", c)
+			} else {
+				fmt.Fprintf(&hexPart, "%02x", c)
+			}
+		}
+		fmt.Fprintf(&sb, "%08x: %-*s  ", off, width, hexPart.String())
+		for _, c := range line {
+			if c < ' ' || c > '~' {
+				c = '.'
+			}
+			sb.WriteByte(c)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+// Hex dump lines decodeHexdump reads: xxd's "offset: hex  ascii" and
+// hexdump -C's "offset  hex  |ascii|". hexdump -C pads the offset to eight
+// digits, which tells its lines from plain hex spaced out in groups.
+var (
+	xxdLineRE     = regexp.MustCompile(`^\s*[0-9a-fA-F]+:(.*)$`)
+	hexdumpLineRE = regexp.MustCompile(`^[0-9a-fA-F]{8,}  ((?:[0-9a-fA-F]{2} {1,2})*[0-9a-fA-F]{2})\s*(?:\|.*\|)?$`)
+	offsetLineRE  = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+)
+
+// decodeHexdump reads the bytes back from a dump by xxd, hexdump -C or
+// encode, or from plain hex as xxd -p writes it. Offsets are not checked:
+// the lines are read in order.
+func decodeHexdump(_ *goja.Runtime, s string, _ *goja.Object) ([]byte, error) {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	dump := slices.ContainsFunc(lines, func(l string) bool {
+		return xxdLineRE.MatchString(l) || hexdumpLineRE.MatchString(l)
+	})
+	if !dump {
+		return hex.DecodeString(stripSpace(s))
+	}
+	var out []byte
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		var digits string
+		switch {
+		case line == "", offsetLineRE.MatchString(line):
+			continue // hexdump -C ends with the length as an offset
+		case xxdLineRE.MatchString(line):
+			// The ASCII column starts after the first double space.
+			digits, _, _ = strings.Cut(strings.TrimPrefix(xxdLineRE.FindStringSubmatch(line)[1], " "), "  ")
+		case hexdumpLineRE.MatchString(line):
+			digits = hexdumpLineRE.FindStringSubmatch(line)[1]
+		default:
+			return nil, fmt.Errorf("line %d is not part of a hex dump", i+1)
+		}
+		b, err := hex.DecodeString(stripSpace(digits))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		out = append(out, b...)
+	}
+	return out, nil
+}
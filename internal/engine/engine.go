@@ -2,6 +2,8 @@ package engine
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"time"
 )
 
@@ -13,8 +15,27 @@ const (
 	MutationReplaceDoc                         // state.fullText was written
 	MutationReplaceSelect                      // state.text was written
 	MutationInsertAtCursor                     // state.insert() was called
+	MutationReplaceBytes                       // state.setBytes() was called
 )
 
+// String returns the kind's name as used in log entries, e.g.
+// "replace-selection".
+func (k MutationKind) String() string {
+	switch k {
+	case MutationNone:
+		return "none"
+	case MutationReplaceDoc:
+		return "replace-document"
+	case MutationReplaceSelect:
+		return "replace-selection"
+	case MutationInsertAtCursor:
+		return "insert"
+	case MutationReplaceBytes:
+		return "replace-bytes"
+	}
+	return fmt.Sprintf("MutationKind(%d)", int(k))
+}
+
 // ExecutionInput carries everything the engine needs to run a single script.
 type ExecutionInput struct {
 	ScriptSource   string        // Full JS source text of the script
@@ -24,6 +45,126 @@ type ExecutionInput struct {
 	SelectionStart int           // 0-based character offset of selection start
 	SelectionEnd   int           // 0-based character offset of selection end
 	Timeout        time.Duration // Hard execution timeout (typically 5 s)
+
+	// Data holds the document when it is a binary payload rather than text;
+	// nil for text. A binary document is seen by the script as state.bytes
+	// and, one character per byte, as state.text and state.fullText, with
+	// the whole document selected: FullText, SelectionText and the selection
+	// offsets are ignored.
+	Data []byte
+
+	// Language is the GtkSourceView language ID of the content (e.g. "json"),
+	// as last detected or declared; empty when unknown. Exposed read-only as
+	// state.language.
+	Language string
+
+	// OutputLanguage is the language ID the script declares for its output
+	// with @output. It is reported as ExecutionResult.Language unless the
+	// script calls state.setLanguage().
+	OutputLanguage string
+
+	// Params holds the values of the parameters the script declares with
+	// @param, keyed by name (string, float64 or bool). Exposed read-only as
+	// state.params; nil is treated as an empty set.
+	Params map[string]any
+
+	// Seed, when non-zero, seeds Math.random so a run can be repeated
+	// exactly. Zero uses unpredictable randomness.
+	Seed int64
+
+	// Now, when non-zero, freezes the clock: Date.now() and new Date() return
+	// this instant throughout the run.
+	Now time.Time
+
+	// LibDir is the directory whose .js files scripts can load with
+	// require('@user/name'). Empty disables the @user/ namespace.
+	LibDir string
+
+	// Permissions lists the capabilities granted to this run (see the Perm*
+	// constants): the script's @permissions that the user has approved.
+	// Capabilities that are not listed stay unbound, whatever else is set.
+	Permissions []string
+
+	// Storage backs state.storage, the script's persistent key/value store.
+	// It is bound only when PermStorage is granted; nil leaves it undefined.
+	Storage *Storage
+
+	// Clipboard backs state.clipboard. It is bound only when PermClipboard is
+	// granted; nil leaves state.clipboard undefined.
+	Clipboard Clipboard
+
+	// Progress receives the reports of state.progress(), at most one every
+	// 100 ms. It is called on the executing goroutine; nil discards them.
+	Progress ProgressFunc
+
+	// Stream, when set, runs the script in streaming mode: its mapLine or
+	// mapRecord function is fed Stream.In one line at a time and what it
+	// returns is written to Stream.Out as it comes, so memory stays bounded
+	// however large the input. The document fields are ignored, Timeout
+	// applies to each line rather than the whole run, and the result carries
+	// no mutation.
+	Stream *StreamIO
+
+	// Each, when not EachNone, runs main once per line or block of
+	// SelectionText instead of once on the whole of it, and replaces the
+	// selection with the results joined back in order. A unit the script
+	// fails on is left as it was and reported in ExecutionResult.Failures.
+	// Timeout applies to each unit. A binary document cannot be split and
+	// fails the run; streams ignore Each.
+	Each EachMode
+
+	// Pure marks a script that declared @pure: its result depends on nothing
+	// but this input, so Memoize may replay an earlier one.
+	Pure bool
+
+	// Scripts looks up the library scripts a script may call with goop.run().
+	// nil makes every goop.run() call throw.
+	Scripts ScriptResolver
+
+	depth    int    // goop.run() nesting level; 0 for a script run by the user
+	userRead func() // if set, called when a @user/ module is read from LibDir
+}
+
+// StreamIO is the input and output of a streaming run.
+type StreamIO struct {
+	In  io.Reader
+	Out io.Writer
+}
+
+// ProgressFunc receives a script's progress: fraction runs from 0 to 1 and
+// message is whatever the script passed along, possibly empty.
+type ProgressFunc func(fraction float64, message string)
+
+// NestedScript is a library script resolved for goop.run(), ready to execute.
+type NestedScript struct {
+	Name   string
+	Source string
+	Params map[string]any // Resolved @param values, as for ExecutionInput.Params
+}
+
+// ScriptResolver returns the script called name together with its parameter
+// values resolved from the raw strings a caller passed to goop.run(). The
+// error is thrown to the calling script, so it should read well there.
+type ScriptResolver func(name string, params map[string]string) (NestedScript, error)
+
+// Capabilities beyond the default sandbox, as listed in
+// ExecutionInput.Permissions. The names match the @permissions header values.
+const (
+	PermClipboard   = "clipboard"    // Binds state.clipboard
+	PermStorage     = "storage"      // Binds state.storage
+	PermReadFile    = "read-file"    // Binds state.readFile()
+	PermLongTimeout = "long-timeout" // Raises the timeout to LongTimeout
+)
+
+// LongTimeout is the execution timeout for scripts granted PermLongTimeout.
+const LongTimeout = 60 * time.Second
+
+// Clipboard is the system clipboard as seen by a script. Implementations are
+// called from the executing goroutine and must marshal to the UI thread
+// themselves where the toolkit requires it.
+type Clipboard interface {
+	Read() (string, error)
+	Write(text string) error
 }
 
 // ExecutionResult is the structured outcome returned by Execute.
@@ -34,15 +175,55 @@ type ExecutionResult struct {
 	NewFullText  string // Valid when MutationKind == MutationReplaceDoc
 	NewText      string // Valid when MutationKind == MutationReplaceSelect
 	InsertText   string // Valid when MutationKind == MutationInsertAtCursor
+	NewBytes     []byte // Valid when MutationKind == MutationReplaceBytes; replaces the document
 	ErrorMessage string // Human-readable; valid when Success == false
 	InfoMessage  string // Set when the script called postInfo(); shown in status bar
 	ScriptName   string
 	TimedOut     bool
+	Cached       bool // Replayed by Memoize rather than run
+
+	// SelectionSet is true when the script called state.select() or assigned
+	// state.cursor. The offsets are 0-based character offsets into the
+	// document as it is after the mutation has been applied; a cursor without
+	// a selection has NewSelectionStart == NewSelectionEnd.
+	SelectionSet      bool
+	NewSelectionStart int
+	NewSelectionEnd   int
+
+	// Failures lists the lines or blocks an ExecutionInput.Each run failed
+	// on, in order. The run still succeeds, with those units unchanged,
+	// unless it failed on all of them.
+	Failures []UnitFailure
+
+	// Language is the language ID the script declared for its output with
+	// @output or state.setLanguage(). Callers apply it in place of detecting
+	// the language; empty leaves detection to them.
+	Language string
+}
+
+// ApplyTo returns text as it reads after applying r to it, for callers that
+// ran a script on text alone with the cursor at its end: a replaced selection
+// or document becomes the new text and an insertion is appended.
+func (r ExecutionResult) ApplyTo(text string) string {
+	switch r.MutationKind {
+	case MutationReplaceSelect:
+		return r.NewText
+	case MutationReplaceDoc:
+		return r.NewFullText
+	case MutationInsertAtCursor:
+		return text + r.InsertText
+	case MutationReplaceBytes:
+		return string(r.NewBytes)
+	default:
+		return text
+	}
 }
 
 // Executor runs a single JavaScript script against a given input.
 // Implementations MUST be safe to call from any goroutine.
-// Each call creates a fresh JS runtime — no state persists between calls.
+// Each call creates a fresh JS runtime — no in-memory state persists between
+// calls; state.storage (see ExecutionInput.Storage) is the only way for a
+// script to keep data from one run to the next.
 type Executor interface {
 	Execute(ctx context.Context, input ExecutionInput) ExecutionResult
 }
@@ -5,6 +5,10 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -30,7 +34,23 @@ func NewExecutor() Executor {
 
 // Execute runs a single Boop script against the provided input and returns a
 // structured result. It never panics.
-func (e *executor) Execute(ctx context.Context, input ExecutionInput) (result ExecutionResult) {
+func (e *executor) Execute(ctx context.Context, input ExecutionInput) ExecutionResult {
+	return e.execute(ctx, input, nil)
+}
+
+// unitRun is what the runs of one script over each line or block share: the
+// compiled script, and a module registry, which caches the modules it
+// compiles along with the module environment. cancel stops the modules'
+// work, which ends the whole run.
+type unitRun struct {
+	prog     *goja.Program
+	registry *require.Registry
+	cancel   context.CancelFunc
+}
+
+// execute is Execute for one unit of a run when shared is set, and for a
+// whole run when it is nil.
+func (e *executor) execute(ctx context.Context, input ExecutionInput, shared *unitRun) (result ExecutionResult) {
 	// Recover from any internal panic.
 	defer func() {
 		if r := recover(); r != nil {
@@ -42,6 +62,27 @@ func (e *executor) Execute(ctx context.Context, input ExecutionInput) (result Ex
 		}
 	}()
 
+	// Each unit runs the script as-is in a runtime of its own, so nothing its
+	// top level or an earlier unit leaves behind carries over. The script and
+	// the modules it requires are compiled once for all of them.
+	if input.Each != EachNone && input.Stream == nil {
+		prog, err := goja.Compile(input.ScriptName, input.ScriptSource, false)
+		if err != nil {
+			return ExecutionResult{
+				Success:      false,
+				ScriptName:   input.ScriptName,
+				ErrorMessage: err.Error(),
+			}
+		}
+		runCtx, cancelRun := context.WithCancel(ctx)
+		defer cancelRun()
+		shared := &unitRun{prog: prog, registry: newRegistry(runCtx, input), cancel: cancelRun}
+		return runEach(input, func(unit ExecutionInput) (ExecutionResult, bool) {
+			res := e.execute(ctx, unit, shared)
+			return res, res.TimedOut
+		})
+	}
+
 	vm := goja.New()
 	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
 
@@ -63,18 +104,37 @@ func (e *executor) Execute(ctx context.Context, input ExecutionInput) (result Ex
 		vm.Set(name, goja.Undefined())
 	}
 
-	// ── Module system: only @boop/ paths ─────────────────────────────────────
-	registry := require.NewRegistry(require.WithLoader(blockingRequireLoader))
-	registerModules(registry)
+	// ── Determinism: seeded Math.random and a frozen clock ───────────────────
+	if input.Seed != 0 {
+		vm.SetRandSource(rand.New(rand.NewPCG(uint64(input.Seed), 0)).Float64)
+	}
+	if !input.Now.IsZero() {
+		now := input.Now
+		vm.SetTimeSource(func() time.Time { return now })
+	}
+
+	// ── Module system: only @boop/ and @user/ paths ─────────────────────────
+	// Native modules that compute in Go for a long time watch the context
+	// cancelRun cancels when the script times out.
+	registry, cancelRun := (*require.Registry)(nil), context.CancelFunc(nil)
+	if shared != nil {
+		registry, cancelRun = shared.registry, shared.cancel
+	} else {
+		var runCtx context.Context
+		runCtx, cancelRun = context.WithCancel(ctx)
+		defer cancelRun()
+		registry = newRegistry(runCtx, input)
+	}
 	registry.Enable(vm)
+	wrapRequire(vm, input.LibDir)
 
 	// ── Additional globals ───────────────────────────────────────────────────
 	registerBtoaAtob(vm)
 	registerConsoleLog(vm, input.ScriptName)
 
 	// ── State object ─────────────────────────────────────────────────────────
-	state := NewScriptState(input)
-	if err := bindState(vm, state); err != nil {
+	state, stateObj, err := newState(vm, input)
+	if err != nil {
 		return ExecutionResult{
 			Success:      false,
 			ScriptName:   input.ScriptName,
@@ -82,13 +142,31 @@ func (e *executor) Execute(ctx context.Context, input ExecutionInput) (result Ex
 		}
 	}
 
+	// ── Permissions: bind elevated capabilities only when granted ────────────
+	var storage *storageSession
+	if input.Storage != nil && slices.Contains(input.Permissions, PermStorage) {
+		storage = bindStorage(vm, stateObj, input.Storage)
+	}
+	var clipboard *clipboardSession
+	if input.Clipboard != nil && slices.Contains(input.Permissions, PermClipboard) {
+		clipboard = bindClipboard(vm, stateObj, input.Clipboard)
+	}
+	if slices.Contains(input.Permissions, PermReadFile) {
+		bindReadFile(vm, stateObj)
+	}
+
 	// ── Compile for syntax check (before starting timer) ─────────────────────
 	timeout := input.Timeout
 	if timeout <= 0 {
 		timeout = 5 * time.Second
 	}
-	prog, err := goja.Compile(input.ScriptName, input.ScriptSource, false)
-	if err != nil {
+	if slices.Contains(input.Permissions, PermLongTimeout) {
+		timeout = max(timeout, LongTimeout)
+	}
+	var prog *goja.Program
+	if shared != nil {
+		prog = shared.prog
+	} else if prog, err = goja.Compile(input.ScriptName, input.ScriptSource, false); err != nil {
 		return ExecutionResult{
 			Success:      false,
 			ScriptName:   input.ScriptName,
@@ -98,12 +176,17 @@ func (e *executor) Execute(ctx context.Context, input ExecutionInput) (result Ex
 
 	// ── Timeout timer ─────────────────────────────────────────────────────────
 	var timedOut atomic.Bool
+	deadline := time.Now().Add(timeout)
 	timer := time.AfterFunc(timeout, func() {
 		timedOut.Store(true)
+		cancelRun()
 		vm.Interrupt(errTimeout)
 	})
 	defer timer.Stop()
 
+	// goop.run() hands nested scripts what is left of this run's budget.
+	e.bindGoop(ctx, vm, input, deadline)
+
 	// ── Context cancellation ──────────────────────────────────────────────────
 	stop := make(chan struct{})
 	defer close(stop)
@@ -121,9 +204,43 @@ func (e *executor) Execute(ctx context.Context, input ExecutionInput) (result Ex
 		return e.runError(runErr, timedOut.Load(), timeout, input.ScriptName)
 	}
 
-	// Retrieve and call main(state)
-	mainFn, ok := goja.AssertFunction(vm.Get("main"))
-	if !ok {
+	// Call main(state), or drive a streaming script's mapLine/mapRecord: over
+	// Stream when streaming, otherwise over the selected text.
+	mainFn, hasMain := goja.AssertFunction(vm.Get("main"))
+	mapFn, records, streaming := streamFunc(vm)
+	switch {
+	case input.Stream != nil && !streaming:
+		return ExecutionResult{
+			Success:      false,
+			ScriptName:   input.ScriptName,
+			ErrorMessage: "script does not define mapLine(line) or mapRecord(record), so it cannot stream",
+		}
+	case input.Stream != nil:
+		// The timeout applies to each call, not to waiting for input.
+		timing := func(calling bool) {
+			if calling {
+				timer.Reset(timeout)
+			} else {
+				timer.Stop()
+			}
+		}
+		err := runStream(vm, state, mapFn, records, input.Stream.In, input.Stream.Out, timing)
+		if err != nil && !errors.Is(err, errStreamStopped) {
+			return e.runError(err, timedOut.Load(), timeout, input.ScriptName)
+		}
+	case hasMain:
+		if _, callErr := mainFn(goja.Undefined(), vm.Get("state")); callErr != nil {
+			return e.runError(callErr, timedOut.Load(), timeout, input.ScriptName)
+		}
+	case streaming:
+		var out strings.Builder
+		err := runStream(vm, state, mapFn, records, strings.NewReader(state.Text), &out, nil)
+		if err != nil && !errors.Is(err, errStreamStopped) {
+			return e.runError(err, timedOut.Load(), timeout, input.ScriptName)
+		}
+		state.Text = out.String()
+		state.textMutated = true
+	default:
 		return ExecutionResult{
 			Success:      false,
 			ScriptName:   input.ScriptName,
@@ -131,12 +248,58 @@ func (e *executor) Execute(ctx context.Context, input ExecutionInput) (result Ex
 		}
 	}
 
-	stateVal := vm.Get("state")
-	if _, callErr := mainFn(goja.Undefined(), stateVal); callErr != nil {
-		return e.runError(callErr, timedOut.Load(), timeout, input.ScriptName)
+	// Storage and clipboard changes only take effect when main() returned
+	// normally.
+	if err := storage.flush(); err != nil {
+		return ExecutionResult{
+			Success:      false,
+			ScriptName:   input.ScriptName,
+			ErrorMessage: err.Error(),
+		}
+	}
+	if err := clipboard.flush(); err != nil {
+		return ExecutionResult{
+			Success:      false,
+			ScriptName:   input.ScriptName,
+			ErrorMessage: err.Error(),
+		}
+	}
+
+	result = state.Result(input.ScriptName)
+	if input.Stream != nil {
+		// The output went to Stream.Out; there is no document to change.
+		result = ExecutionResult{
+			Success:      result.Success,
+			ScriptName:   result.ScriptName,
+			ErrorMessage: result.ErrorMessage,
+			InfoMessage:  result.InfoMessage,
+		}
 	}
+	return result
+}
 
-	return state.Result(input.ScriptName)
+// newRegistry returns the module registry of a run of input, whose native
+// modules stop when ctx is done.
+func newRegistry(ctx context.Context, input ExecutionInput) *require.Registry {
+	registry := require.NewRegistry(require.WithLoader(requireLoader(input.LibDir, input.userRead)))
+	registerModules(registry, newModuleEnv(ctx, input))
+	return registry
+}
+
+// newState creates the state for input and binds it to vm as the global
+// state, replacing any earlier one. Permission-gated members are bound
+// separately.
+func newState(vm *goja.Runtime, input ExecutionInput) (*ScriptState, *goja.Object, error) {
+	state := NewScriptState(input)
+	if err := bindState(vm, state); err != nil {
+		return nil, nil, err
+	}
+	stateObj := vm.Get("state").ToObject(vm)
+	if err := bindBytes(vm, stateObj, state); err != nil {
+		return nil, nil, err
+	}
+	bindProgress(vm, stateObj, input.Progress)
+	return state, stateObj, nil
 }
 
 func (e *executor) runError(err error, timedOut bool, timeout time.Duration, scriptName string) ExecutionResult {
@@ -148,15 +311,12 @@ func (e *executor) runError(err error, timedOut bool, timeout time.Duration, scr
 			ErrorMessage: fmt.Sprintf("Script execution timed out after %v", timeout),
 		}
 	}
-	msg := err.Error()
-	var jsException *goja.Exception
-	if errors.As(err, &jsException) {
-		msg = jsException.Error()
-	}
+	// A *goja.Exception's Error() is the JS error with its location; errors
+	// wrapped around one (a stream's line number) keep their prefix.
 	return ExecutionResult{
 		Success:      false,
 		ScriptName:   scriptName,
-		ErrorMessage: msg,
+		ErrorMessage: err.Error(),
 	}
 }
 
@@ -213,6 +373,70 @@ func bindState(vm *goja.Runtime, state *ScriptState) error {
 	}
 	stateObj.Set("selection", selObj)
 
+	// cursor property — reading returns the caret offset, assigning moves the
+	// caret (and drops any selection) once the result has been applied.
+	if err := stateObj.DefineAccessorProperty("cursor",
+		vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return vm.ToValue(state.Cursor())
+		}),
+		vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			pos := offsetArg(vm, call.Argument(0), "state.cursor")
+			state.Select(pos, pos)
+			return goja.Undefined()
+		}),
+		goja.FLAG_TRUE, goja.FLAG_TRUE,
+	); err != nil {
+		return fmt.Errorf("cursor property: %w", err)
+	}
+
+	// select() method — select(start, end) selects a range, select(pos)
+	// places the caret without a selection.
+	stateObj.Set("select", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("state.select requires a start offset"))
+		}
+		start := offsetArg(vm, call.Argument(0), "state.select")
+		end := start
+		if len(call.Arguments) > 1 && !goja.IsUndefined(call.Argument(1)) {
+			end = offsetArg(vm, call.Argument(1), "state.select")
+		}
+		state.Select(start, end)
+		return goja.Undefined()
+	})
+
+	// params — the values of the script's @param declarations, read-only like
+	// selection so a script cannot confuse itself by overwriting its inputs.
+	paramsObj := vm.NewObject()
+	for _, name := range slices.Sorted(maps.Keys(state.Params)) {
+		if err := paramsObj.DefineDataProperty(name, vm.ToValue(state.Params[name]),
+			goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE,
+		); err != nil {
+			return fmt.Errorf("params.%s property: %w", name, err)
+		}
+	}
+	stateObj.Set("params", paramsObj)
+
+	// language — the content's language ID as the caller knows it, read-only
+	// like selection.
+	if err := stateObj.DefineDataProperty("language", vm.ToValue(state.Language),
+		goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE,
+	); err != nil {
+		return fmt.Errorf("language property: %w", err)
+	}
+
+	// setLanguage() method — declares the output's language ID.
+	stateObj.Set("setLanguage", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 || goja.IsUndefined(call.Argument(0)) {
+			panic(vm.NewTypeError("state.setLanguage requires a language ID such as \"sql\""))
+		}
+		id := ""
+		if !goja.IsNull(call.Argument(0)) {
+			id = strings.ToLower(strings.TrimSpace(call.Argument(0).String()))
+		}
+		state.SetLanguage(id)
+		return goja.Undefined()
+	})
+
 	// insert() method
 	stateObj.Set("insert", func(call goja.FunctionCall) goja.Value {
 		if len(call.Arguments) > 0 {
@@ -245,6 +469,16 @@ func bindState(vm *goja.Runtime, state *ScriptState) error {
 	return nil
 }
 
+// offsetArg converts a JS value to a non-negative character offset, throwing a
+// TypeError naming the calling API when the value is negative or not a number.
+func offsetArg(vm *goja.Runtime, v goja.Value, api string) int {
+	f := v.ToFloat()
+	if math.IsNaN(f) || f < 0 {
+		panic(vm.NewTypeError(fmt.Sprintf("%s expects a non-negative character offset, got %s", api, v.String())))
+	}
+	return int(v.ToInteger())
+}
+
 // registerBtoaAtob registers base64 encode/decode globals matching the browser API.
 //
 // btoa() follows the spec: input must be a Latin-1 string (all code points ≤ 0xFF).
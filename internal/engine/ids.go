@@ -0,0 +1,391 @@
+package engine
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// idsModuleLoader returns the loader for @boop/ids, which generates UUIDs
+// (v4 and v7), ULIDs and KSUIDs, decodes them and Snowflake IDs, and
+// converts UUIDs between notations. IDs take their time from env.now and
+// their randomness from env.rand, so a run with a fixed clock and seed
+// generates the same IDs every time.
+func idsModuleLoader(env moduleEnv) func(*goja.Runtime, *goja.Object) {
+	return func(vm *goja.Runtime, module *goja.Object) {
+		exports := module.Get("exports").(*goja.Object)
+		random := func(n int) []byte {
+			b := make([]byte, n)
+			if _, err := io.ReadFull(env.rand, b); err != nil {
+				panic(vm.NewGoError(fmt.Errorf("ids: %w", err)))
+			}
+			return b
+		}
+
+		exports.Set("uuidv4", func(goja.FunctionCall) goja.Value {
+			var u uuid
+			copy(u[:], random(16))
+			u.setVersion(4)
+			return vm.ToValue(u.String())
+		})
+
+		exports.Set("uuidv7", func(goja.FunctionCall) goja.Value {
+			var u uuid
+			putMillis48(u[:6], env.now())
+			copy(u[6:], random(10))
+			u.setVersion(7)
+			return vm.ToValue(u.String())
+		})
+
+		exports.Set("ulid", func(goja.FunctionCall) goja.Value {
+			var b [16]byte
+			putMillis48(b[:6], env.now())
+			copy(b[6:], random(10))
+			return vm.ToValue(encodeULID(b))
+		})
+
+		exports.Set("ksuid", func(goja.FunctionCall) goja.Value {
+			var b [20]byte
+			secs := env.now().Unix() - ksuidEpoch
+			if secs < 0 || secs > 1<<32-1 {
+				panic(vm.NewGoError(errors.New("ids.ksuid: the clock is outside the KSUID range")))
+			}
+			binary.BigEndian.PutUint32(b[:4], uint32(secs))
+			copy(b[4:], random(16))
+			return vm.ToValue(encodeKSUID(b))
+		})
+
+		exports.Set("decode", func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) == 0 {
+				panic(vm.NewTypeError("ids.decode requires an ID"))
+			}
+			epoch, err := snowflakeEpoch(optValue(optionsObject(vm, call.Argument(1)), "epoch"))
+			if err != nil {
+				panic(vm.NewTypeError("ids.decode: " + err.Error()))
+			}
+			info, err := decodeID(strings.TrimSpace(call.Argument(0).String()), epoch)
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("ids.decode: %w", err)))
+			}
+			obj := vm.NewObject()
+			for _, f := range info {
+				if t, ok := f.value.(time.Time); ok {
+					obj.Set(f.key, newDate(vm, t))
+					continue
+				}
+				obj.Set(f.key, f.value)
+			}
+			return obj
+		})
+
+		exports.Set("formatUUID", func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) == 0 {
+				panic(vm.NewTypeError("ids.formatUUID requires a UUID"))
+			}
+			u, err := parseUUID(strings.TrimSpace(call.Argument(0).String()))
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("ids.formatUUID: %w", err)))
+			}
+			format := "hyphenated"
+			if f := call.Argument(1); !goja.IsUndefined(f) {
+				format = f.String()
+			}
+			out, ok := u.format(format)
+			if !ok {
+				panic(vm.NewTypeError(fmt.Sprintf("ids.formatUUID: unknown format %q; use %s",
+					format, strings.Join(uuidFormats, ", "))))
+			}
+			return vm.ToValue(out)
+		})
+	}
+}
+
+// newDate returns a JS Date for t, to the millisecond.
+func newDate(vm *goja.Runtime, t time.Time) *goja.Object {
+	d, err := vm.New(vm.Get("Date"), vm.ToValue(t.UnixMilli()))
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// putMillis48 writes t as 48-bit big-endian Unix milliseconds, as UUIDv7 and
+// ULID begin.
+func putMillis48(b []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// millis48 reads the 48-bit millisecond timestamp putMillis48 writes.
+func millis48(b []byte) time.Time {
+	var ms int64
+	for _, c := range b[:6] {
+		ms = ms<<8 | int64(c)
+	}
+	return time.UnixMilli(ms).UTC()
+}
+
+// uuid is a UUID in its 16 bytes.
+type uuid [16]byte
+
+// setVersion sets the version and the RFC 9562 variant.
+func (u *uuid) setVersion(v byte) {
+	u[6] = u[6]&0x0f | v<<4
+	u[8] = u[8]&0x3f | 0x80
+}
+
+func (u uuid) String() string {
+	h := hex.EncodeToString(u[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// uuidFormats are the notations formatUUID writes.
+var uuidFormats = []string{"hyphenated", "uppercase", "braces", "urn", "hex", "base64", "base64url"}
+
+// format writes u in the named notation.
+func (u uuid) format(name string) (string, bool) {
+	switch name {
+	case "hyphenated":
+		return u.String(), true
+	case "uppercase":
+		return strings.ToUpper(u.String()), true
+	case "braces":
+		return "{" + u.String() + "}", true
+	case "urn":
+		return "urn:uuid:" + u.String(), true
+	case "hex":
+		return hex.EncodeToString(u[:]), true
+	case "base64":
+		return base64.StdEncoding.EncodeToString(u[:]), true
+	case "base64url":
+		return base64.RawURLEncoding.EncodeToString(u[:]), true
+	}
+	return "", false
+}
+
+// uuidTextRE matches a UUID in hex, with or without hyphens, braces or a
+// urn:uuid: prefix.
+var uuidTextRE = regexp.MustCompile(`(?i)^(?:urn:uuid:)?\{?([0-9a-f]{8})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{12})\}?$`)
+
+// parseUUID reads a UUID in any notation formatUUID writes.
+func parseUUID(s string) (uuid, error) {
+	var u uuid
+	if m := uuidTextRE.FindStringSubmatch(s); m != nil {
+		b, err := hex.DecodeString(strings.Join(m[1:], ""))
+		if err != nil {
+			return u, err
+		}
+		copy(u[:], b)
+		return u, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil && len(b) == 16 {
+			copy(u[:], b)
+			return u, nil
+		}
+	}
+	return u, fmt.Errorf("%q is not a UUID", s)
+}
+
+// idField is one detail decodeID reports, in the order it reports them.
+type idField struct {
+	key   string
+	value any
+}
+
+// decodeID describes an ID, trying each kind in turn. Snowflake timestamps
+// count from epoch.
+func decodeID(s string, epoch snowflake) ([]idField, error) {
+	if u, err := parseUUID(s); err == nil {
+		return u.fields(), nil
+	}
+	if ulidRE.MatchString(s) {
+		b, err := decodeULID(s)
+		if err != nil {
+			return nil, err
+		}
+		return []idField{
+			{"type", "ulid"},
+			{"time", millis48(b[:6])},
+			{"random", hex.EncodeToString(b[6:])},
+		}, nil
+	}
+	if ksuidRE.MatchString(s) {
+		b, err := decodeKSUID(s)
+		if err != nil {
+			return nil, err
+		}
+		return []idField{
+			{"type", "ksuid"},
+			{"time", time.Unix(int64(binary.BigEndian.Uint32(b[:4]))+ksuidEpoch, 0).UTC()},
+			{"payload", hex.EncodeToString(b[4:])},
+		}, nil
+	}
+	if n, err := strconv.ParseUint(s, 10, 63); err == nil {
+		return epoch.fields(n), nil
+	}
+	return nil, fmt.Errorf("%q is not a UUID, ULID, KSUID or Snowflake ID", s)
+}
+
+// fields describes a UUID.
+func (u uuid) fields() []idField {
+	version := int(u[6] >> 4)
+	var variant string
+	switch {
+	case u[8]&0x80 == 0:
+		variant = "NCS"
+	case u[8]&0xc0 == 0x80:
+		variant = "RFC 9562"
+	case u[8]&0xe0 == 0xc0:
+		variant = "Microsoft"
+	default:
+		variant = "future"
+	}
+	out := []idField{{"type", "uuid"}, {"uuid", u.String()}, {"version", version}, {"variant", variant}}
+	if u == (uuid{}) {
+		return append(out, idField{"nil", true})
+	}
+	if variant != "RFC 9562" {
+		return out
+	}
+	// UUIDv1 and v6 count 100 ns intervals from the Gregorian calendar.
+	const gregorianOffset = 122192928000000000
+	switch version {
+	case 1:
+		ticks := uint64(binary.BigEndian.Uint16(u[6:8])&0x0fff)<<48 |
+			uint64(binary.BigEndian.Uint16(u[4:6]))<<32 | uint64(binary.BigEndian.Uint32(u[0:4]))
+		out = append(out, idField{"time", time.Unix(0, int64(ticks-gregorianOffset)*100).UTC()})
+	case 6:
+		ticks := uint64(binary.BigEndian.Uint32(u[0:4]))<<28 |
+			uint64(binary.BigEndian.Uint16(u[4:6]))<<12 | uint64(binary.BigEndian.Uint16(u[6:8])&0x0fff)
+		out = append(out, idField{"time", time.Unix(0, int64(ticks-gregorianOffset)*100).UTC()})
+	case 7:
+		out = append(out, idField{"time", millis48(u[:6])})
+	}
+	return out
+}
+
+// crockford is the base32 alphabet of ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidRE matches a ULID; the first character holds only 3 bits.
+var ulidRE = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$`)
+
+// encodeULID writes 16 bytes as 26 Crockford base32 characters.
+func encodeULID(b [16]byte) string {
+	n := new(big.Int).SetBytes(b[:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[new(big.Int).And(n, big.NewInt(31)).Int64()]
+		n.Rsh(n, 5)
+	}
+	return string(out)
+}
+
+// decodeULID reads a ULID.
+func decodeULID(s string) ([16]byte, error) {
+	var b [16]byte
+	n := new(big.Int)
+	for _, c := range strings.ToUpper(s) {
+		i := strings.IndexRune(crockford, c)
+		if i < 0 {
+			return b, fmt.Errorf("invalid ULID character %q", c)
+		}
+		n.Lsh(n, 5).Or(n, big.NewInt(int64(i)))
+	}
+	n.FillBytes(b[:])
+	return b, nil
+}
+
+// ksuidEpoch is the Unix time KSUID timestamps count from.
+const ksuidEpoch = 1400000000
+
+// base62 is the alphabet of KSUIDs.
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ksuidRE matches a KSUID.
+var ksuidRE = regexp.MustCompile(`^[0-9A-Za-z]{27}$`)
+
+// maxKSUID is the largest 20-byte value, "aWgEPTl1tmebfsQzFP4bxwgy80V".
+var maxKSUID = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1))
+
+// encodeKSUID writes 20 bytes as 27 base62 characters.
+func encodeKSUID(b [20]byte) string {
+	n := new(big.Int).SetBytes(b[:])
+	out := make([]byte, 27)
+	mod := new(big.Int)
+	for i := 26; i >= 0; i-- {
+		n.DivMod(n, big.NewInt(62), mod)
+		out[i] = base62[mod.Int64()]
+	}
+	return string(out)
+}
+
+// decodeKSUID reads a KSUID.
+func decodeKSUID(s string) ([20]byte, error) {
+	var b [20]byte
+	n := new(big.Int)
+	for _, c := range s {
+		n.Mul(n, big.NewInt(62)).Add(n, big.NewInt(int64(strings.IndexRune(base62, c))))
+	}
+	if n.Cmp(maxKSUID) > 0 {
+		return b, fmt.Errorf("%q is out of the KSUID range", s)
+	}
+	n.FillBytes(b[:])
+	return b, nil
+}
+
+// snowflake is a Snowflake ID layout: the epoch its timestamps count from
+// and the names of the two 5-bit fields between timestamp and sequence.
+type snowflake struct {
+	name       string
+	epochMilli int64
+	high, low  string
+}
+
+// Well-known Snowflake layouts.
+var snowflakes = map[string]snowflake{
+	"twitter": {"twitter", 1288834974657, "datacenter", "worker"},
+	"discord": {"discord", 1420070400000, "worker", "process"},
+}
+
+// snowflakeEpoch returns the layout the epoch option names: twitter (the
+// default), discord, or a custom epoch in Unix milliseconds.
+func snowflakeEpoch(v goja.Value) (snowflake, error) {
+	if v == nil {
+		return snowflakes["twitter"], nil
+	}
+	if s, ok := v.Export().(string); ok {
+		if sf, ok := snowflakes[strings.ToLower(s)]; ok {
+			return sf, nil
+		}
+		return snowflake{}, fmt.Errorf("unknown epoch %q; use twitter, discord or milliseconds since 1970", s)
+	}
+	return snowflake{"custom", v.ToInteger(), "datacenter", "worker"}, nil
+}
+
+// fields describes a Snowflake ID: 41 bits of milliseconds since the epoch,
+// two 5-bit fields and a 12-bit sequence.
+func (sf snowflake) fields(n uint64) []idField {
+	return []idField{
+		{"type", "snowflake"},
+		{"epoch", sf.name},
+		{"time", time.UnixMilli(int64(n>>22) + sf.epochMilli).UTC()},
+		{sf.high, int64(n >> 17 & 0x1f)},
+		{sf.low, int64(n >> 12 & 0x1f)},
+		{"sequence", int64(n & 0xfff)},
+	}
+}
@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/itchyny/gojq"
+)
+
+// jqModuleLoader returns the loader for @boop/jq, whose query(json, filter,
+// opts) runs a jq filter and returns every output as an array.
+//
+// json is JSON text, which may hold several values one after another as in
+// newline-delimited JSON, or a JS value. opts.vars binds each of its keys as
+// a $variable; opts.text returns each output as JSON text instead of a JS
+// value, indented by 2 spaces unless opts.compact is set, which keeps
+// integers beyond 2^53 exact. Objects come out with their keys sorted.
+// Filters cannot read the environment: env and $ENV are empty. Evaluation
+// stops when ctx is done, which the executor ties to the script's timeout.
+func jqModuleLoader(ctx context.Context) func(*goja.Runtime, *goja.Object) {
+	return func(vm *goja.Runtime, module *goja.Object) {
+		exports := module.Get("exports").(*goja.Object)
+
+		exports.Set("query", func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) < 2 {
+				panic(vm.NewTypeError("jq.query requires a JSON input and a filter"))
+			}
+			opts := jqOptions(vm, call.Argument(2))
+
+			query, err := gojq.Parse(call.Argument(1).String())
+			if err != nil {
+				panic(jqSyntaxError(vm, call.Argument(1).String(), err))
+			}
+			names := slices.Sorted(maps.Keys(opts.vars))
+			vars := make([]string, len(names))
+			values := make([]any, len(names))
+			for i, name := range names {
+				vars[i], values[i] = "$"+name, opts.vars[name]
+			}
+			code, err := gojq.Compile(query, gojq.WithVariables(vars))
+			if err != nil {
+				panic(jqSyntaxError(vm, call.Argument(1).String(), err))
+			}
+
+			inputs, err := jqInputs(vm, call.Argument(0))
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("jq.query: %w", err)))
+			}
+			var outs []any
+			for _, in := range inputs {
+				halted, err := jqRun(ctx, code, in, values, func(v any) error {
+					if !opts.text {
+						outs = append(outs, jqToJS(vm, v))
+						return nil
+					}
+					text, err := jqText(v, opts.compact)
+					outs = append(outs, text)
+					return err
+				})
+				if err != nil {
+					panic(vm.NewGoError(fmt.Errorf("jq.query: %w", err)))
+				}
+				if halted {
+					break
+				}
+			}
+			return vm.NewArray(outs...)
+		})
+	}
+}
+
+// jqQueryOptions are the options query accepts.
+type jqQueryOptions struct {
+	vars    map[string]any
+	text    bool
+	compact bool
+}
+
+// jqOptions reads the options object passed to query.
+func jqOptions(vm *goja.Runtime, v goja.Value) jqQueryOptions {
+	var opts jqQueryOptions
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return opts
+	}
+	obj := v.ToObject(vm)
+	if vars := obj.Get("vars"); vars != nil && !goja.IsUndefined(vars) && !goja.IsNull(vars) {
+		varsObj := vars.ToObject(vm)
+		opts.vars = map[string]any{}
+		for _, name := range varsObj.Keys() {
+			opts.vars[name] = jqFromJS(varsObj.Get(name).Export())
+		}
+	}
+	if text := obj.Get("text"); text != nil {
+		opts.text = text.ToBoolean()
+	}
+	if compact := obj.Get("compact"); compact != nil {
+		opts.compact = compact.ToBoolean()
+	}
+	return opts
+}
+
+// jqSyntaxError returns a JS SyntaxError for a filter that does not parse or
+// compile, pointing at the offending position when gojq reports one.
+func jqSyntaxError(vm *goja.Runtime, filter string, err error) *goja.Object {
+	msg := "jq: " + err.Error()
+	var perr *gojq.ParseError
+	if errors.As(err, &perr) {
+		msg = fmt.Sprintf("jq: %v at position %d of %q", err, perr.Offset, filter)
+	}
+	e, cerr := vm.New(vm.Get("SyntaxError"), vm.ToValue(msg))
+	if cerr != nil {
+		panic(cerr)
+	}
+	return e
+}
+
+// jqInputs returns the values a query runs over: every JSON value in a
+// string, or the single value of any other JS value.
+func jqInputs(vm *goja.Runtime, v goja.Value) ([]any, error) {
+	if goja.IsUndefined(v) || goja.IsNull(v) {
+		return []any{nil}, nil
+	}
+	text, ok := v.Export().(string)
+	if !ok {
+		return []any{jqFromJS(v.Export())}, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var inputs []any
+	for {
+		var in any
+		err := dec.Decode(&in)
+		if errors.Is(err, io.EOF) {
+			return inputs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON input: %w", err)
+		}
+		inputs = append(inputs, in)
+	}
+}
+
+// jqRun runs code over in, passing each output to emit. halted reports
+// whether the filter called halt, which ends the whole query.
+func jqRun(ctx context.Context, code *gojq.Code, in any, values []any, emit func(any) error) (halted bool, err error) {
+	iter := code.RunWithContext(ctx, in, values...)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			return false, nil
+		}
+		if err, ok := v.(error); ok {
+			var halt *gojq.HaltError
+			if errors.As(err, &halt) && halt.Value() == nil {
+				return true, nil
+			}
+			return false, err
+		}
+		if err := emit(v); err != nil {
+			return false, err
+		}
+	}
+}
+
+// jqText encodes an output as JSON text.
+func jqText(v any, compact bool) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if !compact {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// jqFromJS converts an exported JS value to the types gojq works with.
+func jqFromJS(v any) any {
+	switch x := v.(type) {
+	case int64:
+		return int(x)
+	case time.Time:
+		return x.UTC().Format(time.RFC3339Nano)
+	case []any:
+		out := make([]any, len(x))
+		for i, item := range x {
+			out[i] = jqFromJS(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, item := range x {
+			out[k] = jqFromJS(item)
+		}
+		return out
+	}
+	return v
+}
+
+// jqToJS converts a jq output to a JS value.
+func jqToJS(vm *goja.Runtime, v any) goja.Value {
+	switch x := v.(type) {
+	case map[string]any:
+		obj := vm.NewObject()
+		for _, k := range slices.Sorted(maps.Keys(x)) {
+			obj.Set(k, jqToJS(vm, x[k]))
+		}
+		return obj
+	case []any:
+		items := make([]any, len(x))
+		for i, item := range x {
+			items[i] = jqToJS(vm, item)
+		}
+		return vm.NewArray(items...)
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return vm.ToValue(n)
+		}
+		f, _ := x.Float64()
+		return vm.ToValue(f)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(x).Float64()
+		return vm.ToValue(f)
+	}
+	return vm.ToValue(v)
+}
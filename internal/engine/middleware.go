@@ -0,0 +1,343 @@
+package engine
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"codeberg.org/sigterm-de/goop/internal/logging"
+)
+
+// Middleware wraps an Executor to add behaviour around every run, such as
+// logging or caching. Middleware must keep the wrapped Executor's guarantees:
+// safe for concurrent use, and never panicking.
+type Middleware func(Executor) Executor
+
+// ExecutorFunc adapts an ordinary function to the Executor interface.
+type ExecutorFunc func(ctx context.Context, input ExecutionInput) ExecutionResult
+
+// Execute calls f(ctx, input).
+func (f ExecutorFunc) Execute(ctx context.Context, input ExecutionInput) ExecutionResult {
+	return f(ctx, input)
+}
+
+// Chain wraps exec in mws. The first middleware is the outermost: it sees
+// each run first and its result last.
+func Chain(exec Executor, mws ...Middleware) Executor {
+	for _, mw := range slices.Backward(mws) {
+		exec = mw(exec)
+	}
+	return exec
+}
+
+// Instrument wraps exec in the middleware every goop front end shares:
+// execution logging, timing into metrics, and memoisation of @pure scripts.
+func Instrument(exec Executor, metrics *Metrics) Executor {
+	return Chain(exec, Logging(), metrics.Middleware(), Memoize(defaultMemoBudget))
+}
+
+// ── Logging ──────────────────────────────────────────────────────────────────
+
+// Logging returns middleware that writes one log entry per run: the duration,
+// input and output sizes in bytes and mutation kind of a successful run, or
+// the error of a failed one, plus a warning per line an Each run failed on.
+func Logging() Middleware {
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, input ExecutionInput) ExecutionResult {
+			var in, out counter
+			if input.Stream != nil {
+				stream := *input.Stream
+				input.Stream = &StreamIO{
+					In:  io.TeeReader(stream.In, &in),
+					Out: io.MultiWriter(stream.Out, &out),
+				}
+			} else {
+				in = counter(inputSize(input))
+			}
+
+			start := time.Now()
+			res := next.Execute(ctx, input)
+			elapsed := time.Since(start).Round(time.Microsecond)
+
+			if input.Stream == nil {
+				out = counter(outputSize(res))
+			}
+			fields := fmt.Sprintf("duration=%s in=%d", elapsed, in)
+			if res.Cached {
+				fields += " cached=true"
+			}
+			for _, f := range res.Failures {
+				logging.Log(logging.WARN, input.ScriptName, fmt.Sprintf("line=%d %s", f.Line, f.Message))
+			}
+			if !res.Success {
+				logging.Log(logging.ERROR, input.ScriptName, fmt.Sprintf("%s error=%q", fields, res.ErrorMessage))
+				return res
+			}
+			logging.Log(logging.INFO, input.ScriptName,
+				fmt.Sprintf("%s out=%d mutation=%s", fields, out, res.MutationKind))
+			return res
+		})
+	}
+}
+
+// counter is an io.Writer that only counts the bytes written to it.
+type counter int64
+
+func (c *counter) Write(p []byte) (int, error) {
+	*c += counter(len(p))
+	return len(p), nil
+}
+
+// inputSize returns the size in bytes of the document a run works on.
+func inputSize(input ExecutionInput) int {
+	if input.Data != nil {
+		return len(input.Data)
+	}
+	return len(input.SelectionText)
+}
+
+// outputSize returns the size in bytes of what a result writes back.
+func outputSize(r ExecutionResult) int {
+	switch r.MutationKind {
+	case MutationReplaceDoc:
+		return len(r.NewFullText)
+	case MutationReplaceSelect:
+		return len(r.NewText)
+	case MutationInsertAtCursor:
+		return len(r.InsertText)
+	case MutationReplaceBytes:
+		return len(r.NewBytes)
+	}
+	return 0
+}
+
+// ── Metrics ──────────────────────────────────────────────────────────────────
+
+// Metrics collects per-script timing of the runs passing through its
+// middleware. The zero value is ready to use; a nil *Metrics records nothing.
+type Metrics struct {
+	mu      sync.Mutex
+	scripts map[string]*ScriptMetrics
+}
+
+// ScriptMetrics is the timing of one script's runs.
+type ScriptMetrics struct {
+	Name     string
+	Runs     int
+	Failures int
+	Cached   int           // Runs replayed by Memoize
+	Total    time.Duration // Time spent in all runs
+	Max      time.Duration // Longest single run
+}
+
+// Mean returns the average duration of a run, or 0 before the first.
+func (m ScriptMetrics) Mean() time.Duration {
+	if m.Runs == 0 {
+		return 0
+	}
+	return m.Total / time.Duration(m.Runs)
+}
+
+// Middleware returns middleware recording each run's duration in m.
+func (m *Metrics) Middleware() Middleware {
+	return func(next Executor) Executor {
+		if m == nil {
+			return next
+		}
+		return ExecutorFunc(func(ctx context.Context, input ExecutionInput) ExecutionResult {
+			start := time.Now()
+			res := next.Execute(ctx, input)
+			m.record(input.ScriptName, time.Since(start), res)
+			return res
+		})
+	}
+}
+
+func (m *Metrics) record(name string, d time.Duration, res ExecutionResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.scripts == nil {
+		m.scripts = make(map[string]*ScriptMetrics)
+	}
+	s := m.scripts[name]
+	if s == nil {
+		s = &ScriptMetrics{Name: name}
+		m.scripts[name] = s
+	}
+	s.Runs++
+	if !res.Success {
+		s.Failures++
+	}
+	if res.Cached {
+		s.Cached++
+	}
+	s.Total += d
+	s.Max = max(s.Max, d)
+}
+
+// Snapshot returns the metrics of every script run so far, by name.
+func (m *Metrics) Snapshot() []ScriptMetrics {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]ScriptMetrics, 0, len(m.scripts))
+	for _, s := range m.scripts {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// ── Memoisation ──────────────────────────────────────────────────────────────
+
+// defaultMemoBudget is the memory Instrument lets Memoize keep results in.
+const defaultMemoBudget = 16 << 20 // 16 MB
+
+// Memoize returns middleware that replays the result of an earlier identical
+// run of a @pure script (ExecutionInput.Pure) instead of running it again.
+// Only successful runs are kept, least recently used first out once they
+// exceed budget bytes. Streams and runs granted storage, clipboard or file
+// access are always executed, since their effects go beyond the result.
+// Results of runs that call other scripts with goop.run() or load @user/
+// modules are not kept either, since those sources may change between runs.
+func Memoize(budget int) Middleware {
+	return func(next Executor) Executor {
+		c := &memo{budget: budget, entries: make(map[[sha256.Size]byte]*list.Element), order: list.New()}
+		return ExecutorFunc(func(ctx context.Context, input ExecutionInput) ExecutionResult {
+			if !memoizable(input) {
+				return next.Execute(ctx, input)
+			}
+			key, err := memoKey(input)
+			if err != nil {
+				return next.Execute(ctx, input)
+			}
+			if res, ok := c.get(key); ok {
+				return res
+			}
+			composed := false
+			if resolve := input.Scripts; resolve != nil {
+				input.Scripts = func(name string, params map[string]string) (NestedScript, error) {
+					composed = true
+					return resolve(name, params)
+				}
+			}
+			userRead := input.userRead
+			input.userRead = func() {
+				composed = true
+				if userRead != nil {
+					userRead()
+				}
+			}
+			res := next.Execute(ctx, input)
+			if res.Success && !composed {
+				c.put(key, res)
+			}
+			return res
+		})
+	}
+}
+
+// memoizable reports whether input may be answered from the cache.
+func memoizable(input ExecutionInput) bool {
+	if !input.Pure || input.Stream != nil {
+		return false
+	}
+	for _, p := range input.Permissions {
+		if p != PermLongTimeout {
+			return false
+		}
+	}
+	return true
+}
+
+// memoKey digests everything about input that a pure script's result may
+// depend on.
+func memoKey(input ExecutionInput) ([sha256.Size]byte, error) {
+	b, err := json.Marshal(struct {
+		Source, Name, FullText, SelectionText string
+		SelectionStart, SelectionEnd          int
+		Data                                  []byte
+		Language, OutputLanguage, LibDir      string
+		Params                                map[string]any
+		Seed                                  int64
+		Now                                   time.Time
+		Each                                  EachMode
+	}{
+		input.ScriptSource, input.ScriptName, input.FullText, input.SelectionText,
+		input.SelectionStart, input.SelectionEnd,
+		input.Data,
+		input.Language, input.OutputLanguage, input.LibDir,
+		input.Params,
+		input.Seed,
+		input.Now,
+		input.Each,
+	})
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(b), nil
+}
+
+// memo is a size-bounded least recently used cache of results.
+type memo struct {
+	mu      sync.Mutex
+	budget  int
+	size    int
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List // of *memoEntry, most recently used first
+}
+
+type memoEntry struct {
+	key  [sha256.Size]byte
+	res  ExecutionResult
+	size int
+}
+
+func (c *memo) get(key [sha256.Size]byte) (ExecutionResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return ExecutionResult{}, false
+	}
+	c.order.MoveToFront(el)
+	res := el.Value.(*memoEntry).res
+	res.NewBytes = bytes.Clone(res.NewBytes)
+	res.Failures = slices.Clone(res.Failures)
+	res.Cached = true
+	return res, true
+}
+
+func (c *memo) put(key [sha256.Size]byte, res ExecutionResult) {
+	size := outputSize(res) + len(res.InfoMessage)
+	if size > c.budget {
+		return
+	}
+	res.NewBytes = bytes.Clone(res.NewBytes)
+	res.Failures = slices.Clone(res.Failures)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	c.entries[key] = c.order.PushFront(&memoEntry{key: key, res: res, size: size})
+	c.size += size
+	for c.size > c.budget {
+		oldest := c.order.Back()
+		e := oldest.Value.(*memoEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, e.key)
+		c.size -= e.size
+	}
+}
@@ -1,14 +1,18 @@
 package engine
 
 import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"io/fs"
+	"math/rand/v2"
 	"strings"
+	"time"
 
 	"codeberg.org/sigterm-de/goop/assets"
-	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/require"
-	"gopkg.in/yaml.v3"
 )
 
 // libFS holds the embedded scripts/lib directory for @boop/ JS modules.
@@ -23,66 +27,42 @@ func init() {
 	libFS = sub
 }
 
-// registerModules sets up the @boop/ module namespace on the given require
-// registry. All other require() paths will return "Cannot find module".
-func registerModules(registry *require.Registry) {
-	registry.RegisterNativeModule("@boop/yaml", yamlModuleLoader)
+// moduleEnv is what native modules may take from the run that loads them.
+type moduleEnv struct {
+	ctx  context.Context  // done when the script times out
+	now  func() time.Time // the run's clock, fixed when ExecutionInput.Now is
+	rand io.Reader        // random bytes, seeded when ExecutionInput.Seed is
 }
 
-// yamlModuleLoader exposes yaml.parse and yaml.stringify to scripts.
-func yamlModuleLoader(runtime *goja.Runtime, module *goja.Object) {
-	exports := module.Get("exports").(*goja.Object)
-
-	exports.Set("parse", func(call goja.FunctionCall) goja.Value {
-		if len(call.Arguments) == 0 {
-			panic(runtime.NewTypeError("yaml.parse requires a string argument"))
-		}
-		src := call.Arguments[0].String()
-		var out any
-		if err := yaml.Unmarshal([]byte(src), &out); err != nil {
-			panic(runtime.NewGoError(fmt.Errorf("yaml.parse: %w", err)))
-		}
-		return runtime.ToValue(normaliseYAML(out))
-	})
-
-	exports.Set("stringify", func(call goja.FunctionCall) goja.Value {
-		if len(call.Arguments) == 0 {
-			panic(runtime.NewTypeError("yaml.stringify requires an argument"))
-		}
-		val := call.Arguments[0].Export()
-		b, err := yaml.Marshal(val)
-		if err != nil {
-			panic(runtime.NewGoError(fmt.Errorf("yaml.stringify: %w", err)))
-		}
-		return runtime.ToValue(string(b))
-	})
+// newModuleEnv returns the environment of a run of input that stops when ctx
+// is done. Without a seed, random bytes come from crypto/rand.
+func newModuleEnv(ctx context.Context, input ExecutionInput) moduleEnv {
+	env := moduleEnv{ctx: ctx, now: time.Now, rand: cryptorand.Reader}
+	if !input.Now.IsZero() {
+		now := input.Now
+		env.now = func() time.Time { return now }
+	}
+	if input.Seed != 0 {
+		var seed [32]byte
+		binary.LittleEndian.PutUint64(seed[:], uint64(input.Seed))
+		env.rand = rand.NewChaCha8(seed)
+	}
+	return env
 }
 
-// normaliseYAML recursively converts map[interface{}]interface{} (produced by
-// yaml.v3 when keys are not strings) to map[string]interface{} so that goja
-// can expose it as a plain JS object.
-func normaliseYAML(v any) any {
-	switch val := v.(type) {
-	case map[string]any:
-		out := make(map[string]any, len(val))
-		for k, vv := range val {
-			out[k] = normaliseYAML(vv)
-		}
-		return out
-	case map[any]any:
-		out := make(map[string]any, len(val))
-		for k, vv := range val {
-			out[fmt.Sprintf("%v", k)] = normaliseYAML(vv)
-		}
-		return out
-	case []any:
-		for i, vv := range val {
-			val[i] = normaliseYAML(vv)
-		}
-		return val
-	default:
-		return val
-	}
+// registerModules sets up the @boop/ module namespace on the given require
+// registry. All other require() paths will return "Cannot find module".
+func registerModules(registry *require.Registry, env moduleEnv) {
+	registry.RegisterNativeModule("@boop/yaml", yamlModuleLoader)
+	registry.RegisterNativeModule("@boop/plist", plistModuleLoader)
+	registry.RegisterNativeModule("@boop/toml", tomlModuleLoader)
+	registry.RegisterNativeModule("@boop/xml", xmlModuleLoader)
+	registry.RegisterNativeModule("@boop/crypto", cryptoModuleLoader)
+	registry.RegisterNativeModule("@boop/encoding", encodingModuleLoader)
+	registry.RegisterNativeModule("@boop/compress", compressModuleLoader(env.ctx))
+	registry.RegisterNativeModule("@boop/ids", idsModuleLoader(env))
+	registry.RegisterNativeModule("@boop/time", timeModuleLoader(env))
+	registry.RegisterNativeModule("@boop/jq", jqModuleLoader(env.ctx))
 }
 
 // blockingRequireLoader serves @boop/ JS lib files from the embedded FS and
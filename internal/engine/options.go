@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// optionsObject returns the options object a module function was passed, or
+// nil when it was left out.
+func optionsObject(vm *goja.Runtime, v goja.Value) *goja.Object {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil
+	}
+	return v.ToObject(vm)
+}
+
+// optValue returns opts[name], or nil when opts is nil or the option is
+// unset.
+func optValue(opts *goja.Object, name string) goja.Value {
+	if opts == nil {
+		return nil
+	}
+	v := opts.Get(name)
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil
+	}
+	return v
+}
+
+// optString returns the string option name, or def when it is unset.
+func optString(opts *goja.Object, name, def string) string {
+	if v := optValue(opts, name); v != nil {
+		return v.String()
+	}
+	return def
+}
+
+// optInt returns the integer option name, or def when it is unset. A value
+// outside min–max throws a TypeError naming api.
+func optInt(vm *goja.Runtime, opts *goja.Object, name string, def, minimum, maximum int, api string) int {
+	v := optValue(opts, name)
+	if v == nil {
+		return def
+	}
+	n := v.ToInteger()
+	if n < int64(minimum) || n > int64(maximum) {
+		panic(vm.NewTypeError(fmt.Sprintf("%s: %s must be between %d and %d", api, name, minimum, maximum)))
+	}
+	return int(n)
+}
+
+// optBool returns the boolean option name, or def when it is unset.
+func optBool(opts *goja.Object, name string, def bool) bool {
+	if v := optValue(opts, name); v != nil {
+		return v.ToBoolean()
+	}
+	return def
+}
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"howett.net/plist"
+)
+
+// plistModuleLoader exposes plist.parse, plist.stringify, plist.parseBinary
+// and plist.stringifyBinary to scripts.
+//
+// Dictionaries become objects with their keys in sorted order, dates become
+// Date objects and data becomes a base64 string. In the other direction
+// integral numbers are written as <integer>, Date objects as <date>, typed
+// arrays and ArrayBuffers as <data>, and null or undefined values are left out,
+// as property lists cannot hold them.
+func plistModuleLoader(vm *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+
+	exports.Set("parse", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("plist.parse requires a string argument"))
+		}
+		return plistParse(vm, []byte(call.Argument(0).String()), "plist.parse")
+	})
+
+	exports.Set("parseBinary", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("plist.parseBinary requires a base64 string or bytes"))
+		}
+		var data []byte
+		if s, ok := call.Argument(0).Export().(string); ok {
+			var err error
+			data, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("plist.parseBinary: %w", err)))
+			}
+		} else {
+			data = bytesArg(vm, call.Argument(0), "plist.parseBinary")
+		}
+		return plistParse(vm, data, "plist.parseBinary")
+	})
+
+	exports.Set("stringify", func(call goja.FunctionCall) goja.Value {
+		b := plistMarshal(vm, call, plist.XMLFormat, "plist.stringify")
+		return vm.ToValue(string(b))
+	})
+
+	exports.Set("stringifyBinary", func(call goja.FunctionCall) goja.Value {
+		b := plistMarshal(vm, call, plist.BinaryFormat, "plist.stringifyBinary")
+		return vm.ToValue(base64.StdEncoding.EncodeToString(b))
+	})
+}
+
+// plistParse decodes a property list in any format and converts it to a JS
+// value.
+func plistParse(vm *goja.Runtime, data []byte, api string) goja.Value {
+	var out any
+	if _, err := plist.Unmarshal(data, &out); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("%s: %w", api, err)))
+	}
+	return plistToJS(vm, out)
+}
+
+// plistMarshal encodes the first argument of call in format.
+func plistMarshal(vm *goja.Runtime, call goja.FunctionCall, format int, api string) []byte {
+	if len(call.Arguments) == 0 {
+		panic(vm.NewTypeError(api + " requires an argument"))
+	}
+	v, ok := plistFromJS(vm, call.Argument(0), api)
+	if !ok {
+		panic(vm.NewTypeError(api + ": a property list cannot be null or undefined"))
+	}
+	var (
+		b   []byte
+		err error
+	)
+	if format == plist.XMLFormat {
+		b, err = plist.MarshalIndent(v, format, "\t")
+	} else {
+		b, err = plist.Marshal(v, format)
+	}
+	if err != nil {
+		panic(vm.NewGoError(fmt.Errorf("%s: %w", api, err)))
+	}
+	return b
+}
+
+// plistToJS converts a decoded property list value to a JS value.
+func plistToJS(vm *goja.Runtime, v any) goja.Value {
+	switch val := v.(type) {
+	case map[string]any:
+		obj := vm.NewObject()
+		for _, k := range slices.Sorted(maps.Keys(val)) {
+			obj.Set(k, plistToJS(vm, val[k]))
+		}
+		return obj
+	case []any:
+		items := make([]any, len(val))
+		for i, item := range val {
+			items[i] = plistToJS(vm, item)
+		}
+		return vm.NewArray(items...)
+	case []byte:
+		return vm.ToValue(base64.StdEncoding.EncodeToString(val))
+	case time.Time:
+		d, err := vm.New(vm.Get("Date"), vm.ToValue(val.UnixMilli()))
+		if err != nil {
+			panic(err)
+		}
+		return d
+	case uint64:
+		if val <= math.MaxInt64 {
+			return vm.ToValue(int64(val))
+		}
+		return vm.ToValue(float64(val))
+	default:
+		return vm.ToValue(val)
+	}
+}
+
+// plistFromJS converts a JS value to one the plist encoder understands. ok is
+// false for null and undefined, which have no property list form.
+func plistFromJS(vm *goja.Runtime, v goja.Value, api string) (any, bool) {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, false
+	}
+	switch x := v.Export().(type) {
+	case bool, string:
+		return x, true
+	case int64:
+		return x, true
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+			return int64(x), true
+		}
+		return x, true
+	case time.Time:
+		return x.UTC(), true
+	case goja.ArrayBuffer, []byte:
+		return bytesArg(vm, v, api), true
+	}
+
+	obj := v.ToObject(vm)
+	switch obj.ClassName() {
+	case "Array":
+		n := obj.Get("length").ToInteger()
+		items := make([]any, 0, n)
+		for i := range n {
+			if item, ok := plistFromJS(vm, obj.Get(fmt.Sprint(i)), api); ok {
+				items = append(items, item)
+			}
+		}
+		return items, true
+	case "Object":
+		dict := make(map[string]any, len(obj.Keys()))
+		for _, k := range obj.Keys() {
+			if item, ok := plistFromJS(vm, obj.Get(k), api); ok {
+				dict[k] = item
+			}
+		}
+		return dict, true
+	}
+	panic(vm.NewTypeError(fmt.Sprintf("%s: cannot store a %s in a property list", api, obj.ClassName())))
+}
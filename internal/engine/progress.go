@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// progressInterval is the minimum time between two reports passed on to
+// ExecutionInput.Progress, so a script calling state.progress() once per line
+// does not flood the UI. Completion (a fraction of 1) is always reported.
+const progressInterval = 100 * time.Millisecond
+
+// bindProgress adds state.progress(fraction, message). Without a Progress
+// callback the method is still bound and does nothing, so scripts can report
+// progress unconditionally.
+func bindProgress(vm *goja.Runtime, stateObj *goja.Object, report ProgressFunc) {
+	var last time.Time
+	stateObj.Set("progress", func(call goja.FunctionCall) goja.Value {
+		f := call.Argument(0).ToFloat()
+		if len(call.Arguments) == 0 || math.IsNaN(f) {
+			panic(vm.NewTypeError(fmt.Sprintf("state.progress expects a fraction between 0 and 1, got %s", call.Argument(0).String())))
+		}
+		f = min(max(f, 0), 1)
+		msg := ""
+		if arg := call.Argument(1); !goja.IsUndefined(arg) && !goja.IsNull(arg) {
+			msg = arg.String()
+		}
+		if report == nil {
+			return goja.Undefined()
+		}
+		if now := time.Now(); f == 1 || now.Sub(last) >= progressInterval {
+			last = now
+			report(f, msg)
+		}
+		return goja.Undefined()
+	})
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dop251/goja"
+)
+
+// maxReadFileBytes caps how much state.readFile() loads, matching the size
+// cap for user script files.
+const maxReadFileBytes = 5 * 1024 * 1024 // 5 MB
+
+// bindReadFile exposes state.readFile(path), which returns the contents of a
+// UTF-8 text file. path must be absolute or start with "~/".
+func bindReadFile(vm *goja.Runtime, stateObj *goja.Object) {
+	stateObj.Set("readFile", func(call goja.FunctionCall) goja.Value {
+		text, err := readFile(call.Argument(0).String())
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return vm.ToValue(text)
+	})
+}
+
+func readFile(path string) (string, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("readFile: %w", err)
+		}
+		path = filepath.Join(home, rest)
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("readFile: %q is not an absolute path", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("readFile: %w", err)
+	}
+	defer f.Close()
+
+	// Read one byte past the cap so an oversized file is detected without
+	// trusting a size that may change underneath us.
+	data, err := io.ReadAll(io.LimitReader(f, maxReadFileBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("readFile: %w", err)
+	}
+	if len(data) > maxReadFileBytes {
+		return "", fmt.Errorf("readFile: %s is larger than the %d B limit", path, maxReadFileBytes)
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("readFile: %s is not UTF-8 text", path)
+	}
+	return string(data), nil
+}
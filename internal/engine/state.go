@@ -5,16 +5,22 @@ package engine
 // so that the write-semantics priority table can be applied after main() returns.
 //
 // Priority (highest first):
-//  1. postError() called   → discard all mutations, show error
-//  2. state.text written   → replace selection (or full doc if no selection)
-//  3. state.fullText written → replace full document
-//  4. state.insert() called → insert at cursor
-//  5. nothing written      → no change
+//  1. postError() called   → discard all mutations (and any selection request), show error
+//  2. state.setBytes() called → replace full document with bytes
+//  3. state.text written   → replace selection (or full doc if no selection)
+//  4. state.fullText written → replace full document
+//  5. state.insert() called → insert at cursor
+//  6. nothing written      → no change
+//
+// For a binary document the whole document is the selection, so 3 and 5
+// also replace the full document.
 type ScriptState struct {
 	// Exported fields — visible to the JS VM via TagFieldNameMapper("json").
-	FullText      string    `json:"fullText"`
-	Text          string    `json:"text"`
-	SelectionInfo selection `json:"selection"`
+	FullText      string         `json:"fullText"`
+	Text          string         `json:"text"`
+	SelectionInfo selection      `json:"selection"`
+	Params        map[string]any `json:"params"`
+	Language      string         `json:"language"`
 
 	// Unexported tracking fields — not visible to JS.
 	originalFullText string
@@ -26,6 +32,14 @@ type ScriptState struct {
 	infoMessage      string
 	insertText       string
 	insertPending    bool
+	selectionSet     bool
+	newSelStart      int
+	newSelEnd        int
+	outputLanguage   string
+	data             []byte // document bytes: the binary payload, or the UTF-8 text
+	binary           bool   // the document is a binary payload
+	newBytes         []byte
+	bytesSet         bool
 }
 
 type selection struct {
@@ -35,6 +49,21 @@ type selection struct {
 
 // NewScriptState constructs a ScriptState from the execution input.
 func NewScriptState(input ExecutionInput) *ScriptState {
+	if input.Data != nil {
+		text := binaryString(input.Data)
+		end := len(input.Data)
+		return &ScriptState{
+			FullText:         text,
+			Text:             text,
+			SelectionInfo:    selection{Start: 0, End: end},
+			Params:           input.Params,
+			Language:         input.Language,
+			originalFullText: text,
+			outputLanguage:   input.OutputLanguage,
+			data:             input.Data,
+			binary:           true,
+		}
+	}
 	return &ScriptState{
 		FullText: input.FullText,
 		Text:     input.SelectionText,
@@ -42,8 +71,35 @@ func NewScriptState(input ExecutionInput) *ScriptState {
 			Start: input.SelectionStart,
 			End:   input.SelectionEnd,
 		},
+		Params:           input.Params,
+		Language:         input.Language,
 		originalFullText: input.FullText,
+		outputLanguage:   input.OutputLanguage,
+		data:             []byte(input.FullText),
+	}
+}
+
+// binaryString returns b as a string of one character per byte (U+0000 to
+// U+00FF), the form btoa() and other binary-string APIs expect.
+func binaryString(b []byte) string {
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
 	}
+	return string(r)
+}
+
+// Bytes implements state.bytes — the document as bytes: the binary payload,
+// or the UTF-8 encoding of the full text as it was before the script ran.
+func (s *ScriptState) Bytes() []byte {
+	return s.data
+}
+
+// SetBytes implements state.setBytes(data) — replaces the whole document with
+// data, which the caller shows as text if it is valid UTF-8.
+func (s *ScriptState) SetBytes(data []byte) {
+	s.newBytes = data
+	s.bytesSet = true
 }
 
 // Insert implements state.insert(text) — inserts text at the cursor position.
@@ -53,6 +109,36 @@ func (s *ScriptState) Insert(text string) {
 	s.insertPending = true
 }
 
+// Select implements state.select(start, end) and state.cursor = n — records
+// the selection the editor should show once the mutation has been applied.
+// The original state.selection is left untouched. Offsets given in reverse
+// order are swapped so the range is always start <= end.
+func (s *ScriptState) Select(start, end int) {
+	if end < start {
+		start, end = end, start
+	}
+	s.selectionSet = true
+	s.newSelStart = start
+	s.newSelEnd = end
+}
+
+// Cursor returns the cursor offset reported by state.cursor: the position set
+// by the script if any, otherwise the end of the original selection (which is
+// the caret position when nothing is selected).
+func (s *ScriptState) Cursor() int {
+	if s.selectionSet {
+		return s.newSelEnd
+	}
+	return s.SelectionInfo.End
+}
+
+// SetLanguage implements state.setLanguage(id) — declares the language of the
+// script's output, overriding its @output header. An empty id withdraws the
+// declaration so the caller detects the language itself.
+func (s *ScriptState) SetLanguage(id string) {
+	s.outputLanguage = id
+}
+
 // PostError implements state.postError(msg) — signals an error.
 // All pending mutations are discarded; only the first call's message is kept.
 func (s *ScriptState) PostError(msg string) {
@@ -84,12 +170,32 @@ func (s *ScriptState) Result(scriptName string) ExecutionResult {
 	}
 
 	base.Success = true
+	base.Language = s.outputLanguage
 
 	if s.infoPosted {
 		base.InfoMessage = s.infoMessage
 	}
 
+	if s.selectionSet {
+		base.SelectionSet = true
+		base.NewSelectionStart = s.newSelStart
+		base.NewSelectionEnd = s.newSelEnd
+	}
+
 	switch {
+	case s.bytesSet:
+		base.MutationKind = MutationReplaceBytes
+		base.NewBytes = s.newBytes
+
+	case s.binary && (s.textMutated || s.insertPending):
+		// The whole binary document was the selection.
+		base.MutationKind = MutationReplaceDoc
+		if s.textMutated {
+			base.NewFullText = s.Text
+		} else {
+			base.NewFullText = s.originalFullText + s.insertText
+		}
+
 	case s.textMutated:
 		base.MutationKind = MutationReplaceSelect
 		base.NewText = s.Text
@@ -0,0 +1,300 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// maxStorageBytes is the quota for a single script's state.storage, measured
+// as the size of its serialised values. Storage is meant for counters,
+// templates and small glossaries, not for documents; 1 MB is generous for
+// that while keeping a runaway script from filling the disk.
+const maxStorageBytes = 1024 * 1024 // 1 MB
+
+// storageMu serialises storage file access across concurrent executions.
+var storageMu sync.Mutex
+
+// Storage is the persistent key/value store behind state.storage. Each script
+// gets its own JSON file, named after a hash of the script's key (its file
+// path), which also records the script's display name for inspection.
+type Storage struct {
+	path       string
+	scriptName string
+}
+
+// storageFile is the on-disk format of a Storage file.
+type storageFile struct {
+	Script string                     `json:"script"`
+	Data   map[string]json.RawMessage `json:"data"`
+}
+
+// StorageInfo describes one script's stored data for inspection in the UI.
+type StorageInfo struct {
+	ScriptName string
+	Path       string
+	Size       int64    // File size in bytes
+	Keys       []string // Stored keys, sorted
+}
+
+// NewStorage returns the storage for the script identified by scriptKey
+// (typically Script.FilePath) inside dir. Nothing is read or created until
+// the script accesses state.storage.
+func NewStorage(dir, scriptKey, scriptName string) *Storage {
+	sum := sha256.Sum256([]byte(scriptKey))
+	return &Storage{
+		path:       filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"),
+		scriptName: scriptName,
+	}
+}
+
+// OpenStorageFile returns the storage backed by an existing file, as listed by
+// ListStorage.
+func OpenStorageFile(info StorageInfo) *Storage {
+	return &Storage{path: info.Path, scriptName: info.ScriptName}
+}
+
+// Load reads all stored values. A missing file yields an empty map.
+func (s *Storage) Load() (map[string]json.RawMessage, error) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	return s.load()
+}
+
+func (s *Storage) load() (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]json.RawMessage{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("storage: read: %w", err)
+	}
+	var f storageFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("storage: parse %s: %w", s.path, err)
+	}
+	if f.Data == nil {
+		f.Data = map[string]json.RawMessage{}
+	}
+	return f.Data, nil
+}
+
+// Save replaces the stored values. The file is written atomically (temporary
+// file + rename) so a crash never leaves a half-written store behind. An empty
+// map removes the file.
+func (s *Storage) Save(values map[string]json.RawMessage) error {
+	if n := storageSize(values); n > maxStorageBytes {
+		return fmt.Errorf("storage: %d B exceeds the quota of %d B", n, maxStorageBytes)
+	}
+
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	if len(values) == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("storage: remove: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(storageFile{Script: s.scriptName, Data: values}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("storage: marshal: %w", err)
+	}
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("storage: create dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".storage-*.tmp")
+	if err != nil {
+		return fmt.Errorf("storage: create temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }() // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("storage: write: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("storage: sync: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("storage: close: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("storage: rename: %w", err)
+	}
+	return nil
+}
+
+// Clear deletes all stored values.
+func (s *Storage) Clear() error {
+	return s.Save(nil)
+}
+
+// ListStorage describes every script store in dir, sorted by script name.
+// A missing directory yields an empty list.
+func ListStorage(dir string) ([]StorageInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("storage: read dir: %w", err)
+	}
+
+	var out []StorageInfo
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var f storageFile
+		if json.Unmarshal(data, &f) != nil {
+			continue
+		}
+		keys := make([]string, 0, len(f.Data))
+		for k := range f.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out = append(out, StorageInfo{ScriptName: f.Script, Path: path, Size: int64(len(data)), Keys: keys})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.ToLower(out[i].ScriptName) < strings.ToLower(out[j].ScriptName)
+	})
+	return out, nil
+}
+
+// storageSize returns the number of bytes the values occupy when serialised.
+func storageSize(values map[string]json.RawMessage) int {
+	n := 0
+	for k, v := range values {
+		n += len(k) + len(v)
+	}
+	return n
+}
+
+// storageSession buffers state.storage reads and writes for one execution.
+// The file is loaded on first access and written back once by flush, after
+// main() has returned normally — an uncaught exception or a timeout discards
+// every change made during the run.
+type storageSession struct {
+	store  *Storage
+	values map[string]json.RawMessage
+	dirty  bool
+}
+
+func (s *storageSession) ensureLoaded(vm *goja.Runtime) {
+	if s.values != nil {
+		return
+	}
+	values, err := s.store.Load()
+	if err != nil {
+		panic(vm.NewGoError(err))
+	}
+	s.values = values
+}
+
+// flush persists pending changes. Safe to call when nothing was touched.
+func (s *storageSession) flush() error {
+	if s == nil || !s.dirty {
+		return nil
+	}
+	return s.store.Save(s.values)
+}
+
+// bindStorage exposes state.storage backed by a new session for store.
+func bindStorage(vm *goja.Runtime, stateObj *goja.Object, store *Storage) *storageSession {
+	sess := &storageSession{store: store}
+	jsonObj := vm.Get("JSON").ToObject(vm)
+	stringify, _ := goja.AssertFunction(jsonObj.Get("stringify"))
+	parse, _ := goja.AssertFunction(jsonObj.Get("parse"))
+
+	obj := vm.NewObject()
+
+	obj.Set("get", func(call goja.FunctionCall) goja.Value {
+		sess.ensureLoaded(vm)
+		raw, ok := sess.values[call.Argument(0).String()]
+		if !ok {
+			return call.Argument(1) // caller-supplied default, or undefined
+		}
+		v, err := parse(goja.Undefined(), vm.ToValue(string(raw)))
+		if err != nil {
+			panic(err)
+		}
+		return v
+	})
+
+	obj.Set("set", func(call goja.FunctionCall) goja.Value {
+		sess.ensureLoaded(vm)
+		key := call.Argument(0).String()
+		v, err := stringify(goja.Undefined(), call.Argument(1))
+		if err != nil {
+			panic(err)
+		}
+		if goja.IsUndefined(v) {
+			panic(vm.NewTypeError(fmt.Sprintf("storage.set(%q): value is not JSON-serialisable", key)))
+		}
+		prev, had := sess.values[key]
+		sess.values[key] = json.RawMessage(v.String())
+		if n := storageSize(sess.values); n > maxStorageBytes {
+			if had {
+				sess.values[key] = prev
+			} else {
+				delete(sess.values, key)
+			}
+			panic(vm.NewGoError(fmt.Errorf("storage quota exceeded: %d B > %d B", n, maxStorageBytes)))
+		}
+		sess.dirty = true
+		return goja.Undefined()
+	})
+
+	obj.Set("delete", func(call goja.FunctionCall) goja.Value {
+		sess.ensureLoaded(vm)
+		key := call.Argument(0).String()
+		_, had := sess.values[key]
+		if had {
+			delete(sess.values, key)
+			sess.dirty = true
+		}
+		return vm.ToValue(had)
+	})
+
+	obj.Set("keys", func(call goja.FunctionCall) goja.Value {
+		sess.ensureLoaded(vm)
+		keys := make([]string, 0, len(sess.values))
+		for k := range sess.values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return vm.ToValue(keys)
+	})
+
+	obj.Set("clear", func(call goja.FunctionCall) goja.Value {
+		sess.ensureLoaded(vm)
+		if len(sess.values) > 0 {
+			sess.values = map[string]json.RawMessage{}
+			sess.dirty = true
+		}
+		return goja.Undefined()
+	})
+
+	stateObj.Set("storage", obj)
+	return sess
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dop251/goja"
+)
+
+// maxStreamLine bounds one line or record in streaming mode, and with it the
+// memory a stream needs however large the input is.
+const maxStreamLine = 16 * 1024 * 1024 // 16 MB
+
+// errStreamStopped ends a stream when the script calls state.postError().
+var errStreamStopped = errors.New("stream stopped by postError")
+
+// streamFunc returns the function a streaming script defines in place of
+// main: mapLine(line, state), or mapRecord(record, state) for newline
+// delimited JSON. ok is false when the script defines neither.
+func streamFunc(vm *goja.Runtime) (fn goja.Callable, records, ok bool) {
+	if fn, ok := goja.AssertFunction(vm.Get("mapLine")); ok {
+		return fn, false, true
+	}
+	if fn, ok := goja.AssertFunction(vm.Get("mapRecord")); ok {
+		return fn, true, true
+	}
+	return nil, false, false
+}
+
+// runStream drives fn over in one line at a time and writes what it returns
+// to out: a string (or, for records, a value serialised as JSON) becomes a
+// line, an array several lines, and null or undefined drops the line. Output
+// ends with a newline exactly when the input did. Output is flushed whenever
+// more input is needed, and on the way out, so a slow producer sees each
+// line's output before the next arrives and an error keeps what came before
+// it. timing, if non-nil, is called with true before each call and with
+// false once it is done and before input is first read, so the caller can run
+// a per-line timeout that does not count the wait for input.
+func runStream(vm *goja.Runtime, state *ScriptState, fn goja.Callable, records bool,
+	in io.Reader, out io.Writer, timing func(calling bool)) (err error) {
+	if timing == nil {
+		timing = func(bool) {}
+	}
+	timing(false)
+	var parse, stringify goja.Callable
+	if records {
+		json := vm.Get("JSON").ToObject(vm)
+		parse, _ = goja.AssertFunction(json.Get("parse"))
+		stringify, _ = goja.AssertFunction(json.Get("stringify"))
+	}
+
+	w := bufio.NewWriter(out)
+	defer func() {
+		if ferr := w.Flush(); ferr != nil && err == nil {
+			err = fmt.Errorf("write output: %w", ferr)
+		}
+	}()
+
+	sc := bufio.NewScanner(&flushingReader{in, w})
+	sc.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	endsWithNewline := false
+	sc.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if advance > 0 {
+			endsWithNewline = data[advance-1] == '\n'
+		}
+		return advance, token, err
+	})
+
+	wrote := false
+	emit := func(v goja.Value) error {
+		if records {
+			s, err := stringify(goja.Undefined(), v)
+			if err != nil {
+				return err
+			}
+			v = s
+		}
+		if wrote {
+			if err := w.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+		wrote = true
+		_, err := w.WriteString(v.String())
+		return err
+	}
+
+	stateVal := vm.Get("state")
+	line := 0
+	for sc.Scan() {
+		line++
+		var arg goja.Value = vm.ToValue(sc.Text())
+		if records {
+			if len(sc.Bytes()) == 0 {
+				continue
+			}
+			timing(true)
+			v, err := parse(goja.Undefined(), arg)
+			timing(false)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			arg = v
+		}
+		timing(true)
+		ret, err := fn(goja.Undefined(), arg, stateVal)
+		timing(false)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if state.errorPosted {
+			state.errorMessage = fmt.Sprintf("line %d: %s", line, state.errorMessage)
+			return errStreamStopped
+		}
+		if goja.IsUndefined(ret) || goja.IsNull(ret) {
+			continue
+		}
+		if arr, ok := ret.(*goja.Object); ok && arr.ClassName() == "Array" {
+			for i := range arr.Get("length").ToInteger() {
+				if err := emit(arr.Get(fmt.Sprint(i))); err != nil {
+					return fmt.Errorf("line %d: %w", line, err)
+				}
+			}
+			continue
+		}
+		if err := emit(ret); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read input after line %d: %w", line, err)
+	}
+	if wrote && endsWithNewline {
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushingReader flushes w before each read from r, which may block until
+// the producer writes more.
+type flushingReader struct {
+	r io.Reader
+	w *bufio.Writer
+}
+
+func (f *flushingReader) Read(p []byte) (int, error) {
+	if err := f.w.Flush(); err != nil {
+		return 0, fmt.Errorf("write output: %w", err)
+	}
+	return f.r.Read(p)
+}
@@ -0,0 +1,455 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // IANA zones on systems without a zoneinfo database
+
+	"github.com/dop251/goja"
+)
+
+// timeModuleLoader returns the loader for @boop/time, which parses,
+// formats and converts times between zones and does duration arithmetic in
+// Go. Dates cross into scripts as JS Date objects, which keep milliseconds:
+// finer digits are dropped. now() and the year of timestamps that lack one
+// come from env.now, so a run with a fixed clock is repeatable.
+func timeModuleLoader(env moduleEnv) func(*goja.Runtime, *goja.Object) {
+	return func(vm *goja.Runtime, module *goja.Object) {
+		exports := module.Get("exports").(*goja.Object)
+
+		// toTime reads a time argument: a Date, milliseconds since 1970 like
+		// a Date's value, or text to parse with opts.
+		toTime := func(v goja.Value, opts *goja.Object, api string) time.Time {
+			switch x := v.Export().(type) {
+			case time.Time:
+				return x
+			case int64:
+				return time.UnixMilli(x)
+			case float64:
+				return time.Unix(0, int64(math.Round(x*1e6)))
+			case string:
+				t, err := parseTimeOpts(vm, x, opts, env.now(), api)
+				if err != nil {
+					panic(vm.NewGoError(fmt.Errorf("%s: %w", api, err)))
+				}
+				return t
+			}
+			panic(vm.NewTypeError(api + " requires a Date, a number of milliseconds or text"))
+		}
+
+		exports.Set("now", func(goja.FunctionCall) goja.Value {
+			return newDate(vm, env.now())
+		})
+
+		exports.Set("parse", func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) == 0 {
+				panic(vm.NewTypeError("time.parse requires text"))
+			}
+			opts := optionsObject(vm, call.Argument(1))
+			t, err := parseTimeOpts(vm, call.Argument(0).String(), opts, env.now(), "time.parse")
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("time.parse: %w", err)))
+			}
+			return newDate(vm, t)
+		})
+
+		exports.Set("format", func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) == 0 {
+				panic(vm.NewTypeError("time.format requires a time"))
+			}
+			opts := optionsObject(vm, call.Argument(2))
+			t := toTime(call.Argument(0), opts, "time.format")
+			layout := "RFC3339"
+			if l := call.Argument(1); !goja.IsUndefined(l) && !goja.IsNull(l) {
+				layout = l.String()
+			}
+			loc := timeZoneOpt(vm, opts, "time.format")
+			return vm.ToValue(formatTime(t.In(loc), layout))
+		})
+
+		exports.Set("offset", func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) == 0 {
+				panic(vm.NewTypeError("time.offset requires a time zone"))
+			}
+			loc, err := loadZone(call.Argument(0).String())
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("time.offset: %w", err)))
+			}
+			t := env.now()
+			if len(call.Arguments) > 1 {
+				t = toTime(call.Argument(1), nil, "time.offset")
+			}
+			name, offset := t.In(loc).Zone()
+			obj := vm.NewObject()
+			obj.Set("name", name)
+			obj.Set("offset", offset/60)
+			obj.Set("dst", t.In(loc).IsDST())
+			return obj
+		})
+
+		exports.Set("add", func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) < 2 {
+				panic(vm.NewTypeError("time.add requires a time and a duration"))
+			}
+			t := toTime(call.Argument(0), nil, "time.add")
+			return newDate(vm, t.Add(durationArg(vm, call.Argument(1), "time.add")))
+		})
+
+		exports.Set("diff", func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) < 2 {
+				panic(vm.NewTypeError("time.diff requires two times"))
+			}
+			a := toTime(call.Argument(0), nil, "time.diff")
+			b := toTime(call.Argument(1), nil, "time.diff")
+			return vm.ToValue(durationMillis(a.Sub(b)))
+		})
+
+		exports.Set("parseDuration", func(call goja.FunctionCall) goja.Value {
+			d, err := parseDuration(call.Argument(0).String())
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("time.parseDuration: %w", err)))
+			}
+			return vm.ToValue(durationMillis(d))
+		})
+
+		exports.Set("formatDuration", func(call goja.FunctionCall) goja.Value {
+			d := durationArg(vm, call.Argument(0), "time.formatDuration")
+			style := optString(optionsObject(vm, call.Argument(1)), "style", "short")
+			out, ok := formatDuration(d, style)
+			if !ok {
+				panic(vm.NewTypeError(fmt.Sprintf("time.formatDuration: unknown style %q; use short, long, clock, iso or go", style)))
+			}
+			return vm.ToValue(out)
+		})
+
+		exports.Set("replaceAll", func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) < 2 {
+				panic(vm.NewTypeError("time.replaceAll requires text and a function"))
+			}
+			fn, ok := goja.AssertFunction(call.Argument(1))
+			if !ok {
+				panic(vm.NewTypeError("time.replaceAll: the second argument must be a function"))
+			}
+			opts := optionsObject(vm, call.Argument(2))
+			loc := timeZoneOpt(vm, opts, "time.replaceAll")
+			unix := optBool(opts, "unix", true)
+			now := env.now()
+			text := call.Argument(0).String()
+			out := timestampRE.ReplaceAllStringFunc(text, func(s string) string {
+				m, ok := matchTimestamp(s, loc, now, unix)
+				if !ok {
+					return s
+				}
+				match := vm.NewObject()
+				match.Set("text", s)
+				match.Set("kind", m.kind)
+				match.Set("date", newDate(vm, m.t))
+				r, err := fn(goja.Undefined(), match)
+				if err != nil {
+					panic(err)
+				}
+				if goja.IsUndefined(r) || goja.IsNull(r) {
+					return s
+				}
+				return r.String()
+			})
+			return vm.ToValue(out)
+		})
+	}
+}
+
+// parseTimeOpts parses s as parse(s, opts) does: with opts.layout when it
+// is set, otherwise in any of the forms parseTime knows. Text without an
+// offset is read in opts.zone; numbers are in opts.unit.
+func parseTimeOpts(vm *goja.Runtime, s string, opts *goja.Object, now time.Time, api string) (time.Time, error) {
+	loc := timeZoneOpt(vm, opts, api)
+	s = strings.TrimSpace(s)
+	if layout := optString(opts, "layout", ""); layout != "" {
+		goLayout, err := timeLayout(layout)
+		if err != nil {
+			return time.Time{}, err
+		}
+		t, err := time.ParseInLocation(goLayout, s, loc)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return fixZoneAbbrev(t), nil
+	}
+	if unit := optString(opts, "unit", ""); unit != "" {
+		scale, ok := unixUnits[unit]
+		if !ok {
+			panic(vm.NewTypeError(fmt.Sprintf("%s: unknown unit %q; use s, ms, us or ns", api, unit)))
+		}
+		return parseUnix(s, scale)
+	}
+	return parseTime(s, loc, now)
+}
+
+// timeZoneOpt returns the location opts.zone names: an IANA zone such as
+// "Europe/Berlin", "UTC", "Local" (the default) or a fixed offset "+05:30".
+func timeZoneOpt(vm *goja.Runtime, opts *goja.Object, api string) *time.Location {
+	loc, err := loadZone(optString(opts, "zone", "Local"))
+	if err != nil {
+		panic(vm.NewTypeError(fmt.Sprintf("%s: %v", api, err)))
+	}
+	return loc
+}
+
+// fixedOffsetRE matches a fixed UTC offset such as +05:30, -0800 or +9.
+var fixedOffsetRE = regexp.MustCompile(`^(?:UTC|GMT)?([+-])(\d{1,2}):?(\d{2})?$`)
+
+// loadZone returns the location of an IANA zone name or a fixed offset.
+func loadZone(name string) (*time.Location, error) {
+	if m := fixedOffsetRE.FindStringSubmatch(name); m != nil {
+		h, _ := strconv.Atoi(m[2])
+		mins, _ := strconv.Atoi(m[3])
+		if h > 14 || mins > 59 {
+			return nil, fmt.Errorf("offset %q is out of range", name)
+		}
+		off := h*3600 + mins*60
+		if m[1] == "-" {
+			off = -off
+		}
+		return time.FixedZone(name, off), nil
+	}
+	if strings.EqualFold(name, "utc") || strings.EqualFold(name, "z") {
+		return time.UTC, nil
+	}
+	if strings.EqualFold(name, "local") {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// unixUnits are the units of Unix timestamps, in nanoseconds.
+var unixUnits = map[string]int64{"s": 1e9, "ms": 1e6, "us": 1e3, "ns": 1}
+
+// parseUnix reads a Unix timestamp, which may have a fraction, in units of
+// scale nanoseconds.
+func parseUnix(s string, scale int64) (time.Time, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || (frac != "" && strings.Trim(frac, "0123456789") != "") {
+		return time.Time{}, fmt.Errorf("%q is not a Unix timestamp", s)
+	}
+	if n > math.MaxInt64/scale || n < math.MinInt64/scale {
+		return time.Time{}, fmt.Errorf("%q is out of range", s)
+	}
+	ns := n * scale
+	if frac != "" {
+		f, _ := strconv.ParseFloat("0."+frac, 64)
+		if strings.HasPrefix(whole, "-") {
+			f = -f
+		}
+		ns += int64(math.Round(f * float64(scale)))
+	}
+	return time.Unix(0, ns), nil
+}
+
+// unixScale guesses the unit of a Unix timestamp from its digits: seconds
+// up to 11 (the year 5138), then milliseconds, microseconds and nanoseconds.
+func unixScale(digits string) int64 {
+	switch n := len(strings.TrimPrefix(digits, "-")); {
+	case n <= 11:
+		return 1e9
+	case n <= 14:
+		return 1e6
+	case n <= 17:
+		return 1e3
+	}
+	return 1
+}
+
+// unixRE matches a Unix timestamp, with an optional fraction.
+var unixRE = regexp.MustCompile(`^-?\d+(?:\.\d+)?$`)
+
+// zonedLayouts are the layouts parseTime tries that carry their own offset
+// or zone; localLayouts have none and are read in the caller's zone.
+var (
+	zonedLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05Z0700",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05Z0700",
+		"2006-01-02 15:04:05 Z07:00",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02 15:04:05 -0700 MST",
+		"2006-01-02 15:04:05 MST",
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04Z0700",
+		"2006-01-02 15:04Z07:00",
+		"2006-01-02 15:04Z0700",
+		"2006-01-02 15:04 MST",
+		time.RFC1123,
+		time.RFC1123Z,
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 -0700 (MST)",
+		"2 Jan 2006 15:04:05 -0700",
+		time.RFC850,
+		time.RFC822,
+		time.RFC822Z,
+		time.UnixDate,
+		time.RubyDate,
+		"Mon Jan 02 2006 15:04:05 GMT-0700",
+		"02/Jan/2006:15:04:05 -0700",
+	}
+	localLayouts = []string{
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04",
+		"2006-01-02",
+		"2006/01/02 15:04:05",
+		"2006/01/02 15:04",
+		"2006/01/02",
+		"20060102T150405",
+		time.ANSIC,
+		"Mon Jan 02 2006 15:04:05",
+		"02 Jan 2006 15:04:05",
+		"2 Jan 2006",
+		"Jan 2, 2006",
+		"January 2, 2006",
+		"Jan 2, 2006 15:04:05",
+		"January 2, 2006 15:04:05",
+		"02/Jan/2006:15:04:05",
+	}
+	// yearlessLayouts are syslog's stamps, which are taken to be in the
+	// current year.
+	yearlessLayouts = []string{time.Stamp, "Jan 2 15:04:05"}
+)
+
+// parseTime reads s in any of the forms @boop/time recognises: Unix
+// timestamps, RFC 3339 and ISO 8601 dates and times, RFC 1123 and RFC 822 as
+// in HTTP and email, Apache common log format, syslog, ctime and
+// Date.prototype.toString(). Text without an offset is read in loc.
+func parseTime(s string, loc *time.Location, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if unixRE.MatchString(s) {
+		whole, _, _ := strings.Cut(s, ".")
+		return parseUnix(s, unixScale(whole))
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if i := strings.Index(s, " GMT"); i > 0 && strings.HasSuffix(s, ")") {
+		// Date.prototype.toString() ends with the zone's name in brackets.
+		if j := strings.LastIndex(s, " ("); j > i {
+			s = s[:j]
+		}
+	}
+	for _, layout := range zonedLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return fixZoneAbbrev(t), nil
+		}
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range yearlessLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return time.Date(now.In(loc).Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot read %q as a time", s)
+}
+
+// rfc822Zones are the zone abbreviations RFC 822 defines. Go reads other
+// abbreviations than UTC and GMT as a zero offset unless loc uses them.
+var rfc822Zones = map[string]int{
+	"EST": -5, "EDT": -4, "CST": -6, "CDT": -5, "MST": -7, "MDT": -6, "PST": -8, "PDT": -7,
+}
+
+// fixZoneAbbrev gives t the offset of an RFC 822 zone abbreviation that Go
+// did not know.
+func fixZoneAbbrev(t time.Time) time.Time {
+	name, offset := t.Zone()
+	if h, ok := rfc822Zones[name]; ok && offset == 0 {
+		y, mo, d := t.Date()
+		return time.Date(y, mo, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.FixedZone(name, h*3600))
+	}
+	return t
+}
+
+// timestampRE finds candidate timestamps for replaceAll; matchTimestamp
+// then checks each. The alternatives are ISO 8601, RFC 1123 and RFC 822,
+// common log format, syslog and Unix seconds or milliseconds.
+var timestampRE = regexp.MustCompile(`\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2}| UTC)?)?` +
+	`|(?:(?:Mon|Tue|Wed|Thu|Fri|Sat|Sun), )?\d{1,2} (?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) \d{4} \d{2}:\d{2}:\d{2} (?:[+-]\d{4}|[A-Z]{2,4})` +
+	`|\d{2}/(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}` +
+	`|(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) [ \d]\d \d{2}:\d{2}:\d{2}` +
+	`|\b\d{10}(?:\d{3})?\b`)
+
+// timestampMatch is a timestamp replaceAll found, with its kind: "iso",
+// "rfc1123", "clf", "syslog", "unix" or "unixms".
+type timestampMatch struct {
+	t    time.Time
+	kind string
+}
+
+// matchTimestamp reads a candidate timestampRE found, rejecting Unix
+// timestamps when unix is false and those outside 2001–2100, which are more
+// likely to be other numbers.
+func matchTimestamp(s string, loc *time.Location, now time.Time, unix bool) (timestampMatch, bool) {
+	var kind string
+	switch {
+	case unixRE.MatchString(s):
+		if !unix {
+			return timestampMatch{}, false
+		}
+		kind = "unix"
+		if len(s) == 13 {
+			kind = "unixms"
+		}
+	case s[0] >= '0' && s[0] <= '9' && s[4] == '-':
+		kind = "iso"
+	case strings.Contains(s, "/"):
+		kind = "clf"
+	case len(s) == len(time.Stamp):
+		kind = "syslog"
+	default:
+		kind = "rfc1123"
+	}
+	t, err := parseTime(strings.Replace(s, " UTC", "Z", 1), loc, now)
+	if err != nil {
+		return timestampMatch{}, false
+	}
+	if strings.HasPrefix(kind, "unix") && (t.Year() < 2001 || t.Year() > 2100) {
+		return timestampMatch{}, false
+	}
+	return timestampMatch{t, kind}, true
+}
+
+// durationArg reads a duration argument: milliseconds, or text that
+// parseDuration reads.
+func durationArg(vm *goja.Runtime, v goja.Value, api string) time.Duration {
+	switch x := v.Export().(type) {
+	case int64:
+		return time.Duration(x) * time.Millisecond
+	case float64:
+		if math.IsNaN(x) || math.Abs(x) > float64(math.MaxInt64/int64(time.Millisecond)) {
+			panic(vm.NewTypeError(api + ": the duration is out of range"))
+		}
+		return time.Duration(math.Round(x * float64(time.Millisecond)))
+	case string:
+		d, err := parseDuration(x)
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("%s: %w", api, err)))
+		}
+		return d
+	}
+	panic(vm.NewTypeError(api + " requires a duration in milliseconds or text"))
+}
+
+// durationMillis returns d in milliseconds, as scripts count time.
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,351 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the named layouts format and parse accept, by lowercase
+// name: Go's layout constants and a few common formats of their own.
+var timeLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"iso8601":     "2006-01-02T15:04:05.000Z07:00",
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc822":      time.RFC822,
+	"rfc822z":     time.RFC822Z,
+	"rfc850":      time.RFC850,
+	"ansic":       time.ANSIC,
+	"unixdate":    time.UnixDate,
+	"rubydate":    time.RubyDate,
+	"kitchen":     time.Kitchen,
+	"stamp":       time.Stamp,
+	"stampmilli":  time.StampMilli,
+	"datetime":    time.DateTime,
+	"dateonly":    time.DateOnly,
+	"timeonly":    time.TimeOnly,
+	"clf":         "02/Jan/2006:15:04:05 -0700",
+	"syslog":      time.Stamp,
+	"http":        "Mon, 02 Jan 2006 15:04:05 GMT",
+}
+
+// formatTime writes t in layout: a name from timeLayouts, "unix", "unixms",
+// "unixus" or "unixns", a strftime format when it contains %, or otherwise
+// a Go layout.
+func formatTime(t time.Time, layout string) string {
+	switch name := strings.ToLower(layout); name {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unixms":
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case "unixus":
+		return strconv.FormatInt(t.UnixMicro(), 10)
+	case "unixns":
+		return strconv.FormatInt(t.UnixNano(), 10)
+	case "http":
+		// HTTP dates are always in GMT.
+		return t.UTC().Format(timeLayouts[name])
+	default:
+		if goLayout, ok := timeLayouts[name]; ok {
+			return t.Format(goLayout)
+		}
+	}
+	if strings.Contains(layout, "%") {
+		return strftime(t, layout)
+	}
+	return t.Format(layout)
+}
+
+// timeLayout returns the Go layout parse reads a layout option with: a
+// name from timeLayouts, a strftime format or a Go layout.
+func timeLayout(layout string) (string, error) {
+	if goLayout, ok := timeLayouts[strings.ToLower(layout)]; ok {
+		return goLayout, nil
+	}
+	if !strings.Contains(layout, "%") {
+		return layout, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' || i == len(layout)-1 {
+			sb.WriteByte(layout[i])
+			continue
+		}
+		i++
+		verb := string(layout[i])
+		if verb == ":" && i+1 < len(layout) {
+			i++
+			verb += string(layout[i])
+		}
+		goLayout, ok := strftimeLayouts[verb]
+		if !ok {
+			return "", fmt.Errorf("%%%s cannot be parsed", verb)
+		}
+		sb.WriteString(goLayout)
+	}
+	return sb.String(), nil
+}
+
+// strftimeLayouts are the strftime conversions that have a Go layout
+// equivalent, which are the ones parse accepts.
+var strftimeLayouts = map[string]string{
+	"a": "Mon", "A": "Monday", "b": "Jan", "h": "Jan", "B": "January",
+	"d": "02", "e": "_2", "j": "002", "m": "01", "y": "06", "Y": "2006",
+	"H": "15", "I": "03", "M": "04", "S": "05", "p": "PM",
+	"f": ".000000", "L": ".000",
+	"z": "-0700", ":z": "-07:00", "Z": "MST",
+	"F": "2006-01-02", "T": "15:04:05", "D": "01/02/06", "R": "15:04",
+	"n": "\n", "t": "\t", "%": "%",
+}
+
+// strftime writes t in a strftime format. Besides the conversions of
+// strftimeLayouts it supports %C, %k, %l, %P, %s, %u and %w; %f writes
+// microseconds and %L milliseconds, without a leading dot.
+func strftime(t time.Time, format string) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i == len(format)-1 {
+			sb.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch c := format[i]; c {
+		case 'C':
+			fmt.Fprintf(&sb, "%02d", t.Year()/100)
+		case 'k':
+			fmt.Fprintf(&sb, "%2d", t.Hour())
+		case 'l':
+			fmt.Fprintf(&sb, "%2d", (t.Hour()+11)%12+1)
+		case 'P':
+			sb.WriteString(strings.ToLower(t.Format("PM")))
+		case 's':
+			sb.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'u':
+			sb.WriteString(strconv.Itoa((int(t.Weekday())+6)%7 + 1))
+		case 'w':
+			sb.WriteString(strconv.Itoa(int(t.Weekday())))
+		case 'f':
+			fmt.Fprintf(&sb, "%06d", t.Nanosecond()/1e3)
+		case 'L':
+			fmt.Fprintf(&sb, "%03d", t.Nanosecond()/1e6)
+		case ':':
+			if i+1 < len(format) && format[i+1] == 'z' {
+				i++
+				sb.WriteString(t.Format("-07:00"))
+			} else {
+				sb.WriteString("%:")
+			}
+		default:
+			if goLayout, ok := strftimeLayouts[string(c)]; ok {
+				sb.WriteString(t.Format(goLayout))
+			} else {
+				// Unknown conversions are written as they are, as C does.
+				sb.WriteByte('%')
+				sb.WriteByte(c)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// durationUnits are the units parseDuration reads in human durations.
+var durationUnits = map[string]time.Duration{
+	"w": 7 * 24 * time.Hour, "wk": 7 * 24 * time.Hour, "wks": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"ms": time.Millisecond, "msec": time.Millisecond, "millisecond": time.Millisecond, "milliseconds": time.Millisecond,
+	"us": time.Microsecond, "µs": time.Microsecond, "microsecond": time.Microsecond, "microseconds": time.Microsecond,
+	"ns": time.Nanosecond, "nanosecond": time.Nanosecond, "nanoseconds": time.Nanosecond,
+}
+
+var (
+	// humanDurationRE matches one amount and unit of a human duration.
+	humanDurationRE = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zµ]+)$`)
+	// clockDurationRE matches h:mm or h:mm:ss with optional fractional
+	// seconds.
+	clockDurationRE = regexp.MustCompile(`^(\d+):(\d{1,2})(?::(\d{1,2}(?:\.\d+)?))?$`)
+	// isoDurationRE matches an ISO 8601 duration.
+	isoDurationRE = regexp.MustCompile(`^P(?:(\d+(?:[.,]\d+)?)Y)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)W)?(?:(\d+(?:[.,]\d+)?)D)?(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+)
+
+// errDurationRange reports a duration beyond about 292 years, the range of
+// time.Duration.
+var errDurationRange = errors.New("the duration is out of range")
+
+// parseDuration reads a duration as Go writes it ("1h30m"), as a clock
+// ("1:30:00"), in ISO 8601 ("PT1H30M") or in words ("1 hour, 30 minutes").
+// Any of them may start with a minus sign.
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	sign := time.Duration(1)
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		sign, s = -1, strings.TrimSpace(rest)
+	}
+	var total float64
+	switch {
+	case s == "":
+		return 0, errors.New("empty duration")
+	case clockDurationRE.MatchString(s):
+		m := clockDurationRE.FindStringSubmatch(s)
+		h, _ := strconv.ParseFloat(m[1], 64)
+		mins, _ := strconv.ParseFloat(m[2], 64)
+		secs, _ := strconv.ParseFloat("0"+m[3], 64)
+		total = h*float64(time.Hour) + mins*float64(time.Minute) + secs*float64(time.Second)
+	case isoDurationRE.MatchString(s) && s != "P" && !strings.HasSuffix(s, "T"):
+		m := isoDurationRE.FindStringSubmatch(s)
+		if m[1] != "" || m[2] != "" {
+			return 0, fmt.Errorf("%q has years or months, which have no fixed length", s)
+		}
+		units := []time.Duration{0, 0, 7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+		for i, unit := range units {
+			if m[i+1] != "" {
+				n, _ := strconv.ParseFloat(strings.Replace(m[i+1], ",", ".", 1), 64)
+				total += n * float64(unit)
+			}
+		}
+	default:
+		parts := strings.FieldsFunc(strings.ReplaceAll(strings.ToLower(s), " and ", ","), func(r rune) bool {
+			return r == ','
+		})
+		for _, part := range parts {
+			for _, field := range splitDurationFields(strings.TrimSpace(part)) {
+				m := humanDurationRE.FindStringSubmatch(field)
+				if m == nil {
+					return 0, fmt.Errorf("cannot read %q as a duration", s)
+				}
+				unit, ok := durationUnits[m[2]]
+				if !ok {
+					return 0, fmt.Errorf("unknown unit %q in %q", m[2], s)
+				}
+				n, _ := strconv.ParseFloat(m[1], 64)
+				total += n * float64(unit)
+			}
+		}
+	}
+	if total > math.MaxInt64 {
+		return 0, errDurationRange
+	}
+	return sign * time.Duration(math.Round(total)), nil
+}
+
+// durationFieldRE splits "2 days 3h" into "2 days" and "3h".
+var durationFieldRE = regexp.MustCompile(`\d+(?:\.\d+)?\s*[a-zµ]*`)
+
+// splitDurationFields returns the amount-and-unit pairs of s, or s itself
+// when anything else is in it so that the caller reports it.
+func splitDurationFields(s string) []string {
+	fields := durationFieldRE.FindAllString(s, -1)
+	if strings.Join(strings.Fields(strings.Join(fields, "")), "") != strings.Join(strings.Fields(s), "") {
+		return []string{s}
+	}
+	for i, f := range fields {
+		fields[i] = strings.TrimSpace(f)
+	}
+	return fields
+}
+
+// formatDuration writes d in a style: "short" ("1d 2h 3m 4.5s"), "long"
+// ("1 day, 2 hours, 3 minutes and 4.5 seconds"), "clock" ("26:03:04.5"),
+// "iso" ("P1DT2H3M4.5S") or "go" ("26h3m4.5s").
+func formatDuration(d time.Duration, style string) (string, bool) {
+	if style == "go" {
+		return d.String(), true
+	}
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		if d == math.MinInt64 {
+			d++ // -d would overflow
+		}
+		d = -d
+	}
+	days := int64(d / (24 * time.Hour))
+	hours := int64(d / time.Hour % 24)
+	minutes := int64(d / time.Minute % 60)
+	seconds := formatSeconds(d % time.Minute)
+	switch style {
+	case "short":
+		var parts []string
+		for _, p := range []struct {
+			n    int64
+			unit string
+		}{{days, "d"}, {hours, "h"}, {minutes, "m"}} {
+			if p.n != 0 {
+				parts = append(parts, strconv.FormatInt(p.n, 10)+p.unit)
+			}
+		}
+		if seconds != "0" || len(parts) == 0 {
+			parts = append(parts, seconds+"s")
+		}
+		return sign + strings.Join(parts, " "), true
+	case "long":
+		var parts []string
+		for _, p := range []struct {
+			n    int64
+			unit string
+		}{{days, "day"}, {hours, "hour"}, {minutes, "minute"}} {
+			if p.n == 1 {
+				parts = append(parts, "1 "+p.unit)
+			} else if p.n != 0 {
+				parts = append(parts, strconv.FormatInt(p.n, 10)+" "+p.unit+"s")
+			}
+		}
+		if seconds == "1" {
+			parts = append(parts, "1 second")
+		} else if seconds != "0" || len(parts) == 0 {
+			parts = append(parts, seconds+" seconds")
+		}
+		if len(parts) > 1 {
+			parts = append(parts[:len(parts)-2], parts[len(parts)-2]+" and "+parts[len(parts)-1])
+		}
+		return sign + strings.Join(parts, ", "), true
+	case "clock":
+		secs := seconds
+		if whole, _, _ := strings.Cut(secs, "."); len(whole) < 2 {
+			secs = "0" + secs
+		}
+		return fmt.Sprintf("%s%d:%02d:%s", sign, int64(d/time.Hour), minutes, secs), true
+	case "iso":
+		var sb strings.Builder
+		sb.WriteString(sign + "P")
+		if days != 0 {
+			fmt.Fprintf(&sb, "%dD", days)
+		}
+		if hours != 0 || minutes != 0 || seconds != "0" || days == 0 {
+			sb.WriteByte('T')
+			if hours != 0 {
+				fmt.Fprintf(&sb, "%dH", hours)
+			}
+			if minutes != 0 {
+				fmt.Fprintf(&sb, "%dM", minutes)
+			}
+			if seconds != "0" || (hours == 0 && minutes == 0) {
+				sb.WriteString(seconds + "S")
+			}
+		}
+		return sb.String(), true
+	}
+	return "", false
+}
+
+// formatSeconds writes d, less than a minute, as seconds with as many
+// decimals as it needs.
+func formatSeconds(d time.Duration) string {
+	whole := int64(d / time.Second)
+	frac := int64(d % time.Second)
+	if frac == 0 {
+		return strconv.FormatInt(whole, 10)
+	}
+	return strconv.FormatInt(whole, 10) + "." + strings.TrimRight(fmt.Sprintf("%09d", frac), "0")
+}
@@ -0,0 +1,431 @@
+package engine
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dop251/goja"
+)
+
+// tomlModuleLoader exposes toml.parse and toml.stringify to scripts.
+//
+// Tables keep the order their keys are written in, and stringify writes an
+// object's keys in its own order, plain values before tables. Every table and
+// array parse returns remembers which of its entries were floats, so a float
+// such as 1.0 is written back as 1.0 rather than 1; other whole numbers are
+// written as integers. Integers beyond 2^53 lose precision as they do in any
+// JS number. Offset date-times become Date objects. Local dates, times and
+// date-times, which a Date cannot hold, become objects that read as their
+// TOML text through toString() and JSON.stringify() and are written back as
+// the same local value. TOML has no null, so null and undefined values are
+// left out by stringify.
+func tomlModuleLoader(vm *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+	floats := goja.NewSymbol("toml.floats")
+
+	exports.Set("parse", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("toml.parse requires a string argument"))
+		}
+		var out map[string]any
+		meta, err := toml.Decode(call.Argument(0).String(), &out)
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("toml.parse: %w", err)))
+		}
+		p := tomlParse{vm: vm, floats: floats, order: make(map[string]int)}
+		// A table may only be listed through the keys under it, as with
+		// dotted keys and [a.b] headers, so it takes the place of its first.
+		for i, key := range meta.Keys() {
+			for n := range key {
+				if _, ok := p.order[tomlPath(key[:n+1])]; !ok {
+					p.order[tomlPath(key[:n+1])] = i
+				}
+			}
+		}
+		return p.toJS(out, nil)
+	})
+
+	exports.Set("stringify", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("toml.stringify requires an argument"))
+		}
+		doc, _ := tomlFromJS(vm, floats, call.Argument(0), false)
+		table, ok := doc.(*tomlTable)
+		if !ok {
+			panic(vm.NewTypeError("toml.stringify: a TOML document must be an object"))
+		}
+		var buf strings.Builder
+		table.write(&buf, nil, false)
+		return vm.ToValue(buf.String())
+	})
+}
+
+// tomlPath joins the parts of a key path so it can index a map.
+func tomlPath(key []string) string {
+	return strings.Join(key, "\x00")
+}
+
+// tomlParse converts a decoded document to JS values.
+type tomlParse struct {
+	vm     *goja.Runtime
+	floats *goja.Symbol   // tags tables and arrays with the entries that are floats
+	order  map[string]int // position of each key path in the document
+}
+
+// toJS converts the decoded value at path to a JS value.
+func (p *tomlParse) toJS(v any, path []string) goja.Value {
+	vm := p.vm
+	switch val := v.(type) {
+	case map[string]any:
+		keys := slices.Collect(maps.Keys(val))
+		slices.SortFunc(keys, func(a, b string) int {
+			return cmp.Or(cmp.Compare(p.position(path, a), p.position(path, b)), strings.Compare(a, b))
+		})
+		obj := vm.NewObject()
+		marks := make(map[string]bool)
+		for _, k := range keys {
+			obj.Set(k, p.toJS(val[k], append(slices.Clip(path), k)))
+			if tomlWholeFloat(val[k]) {
+				marks[k] = true
+			}
+		}
+		p.tag(obj, marks)
+		return obj
+	case []map[string]any:
+		items := make([]any, len(val))
+		for i, item := range val {
+			items[i] = p.toJS(item, path)
+		}
+		return vm.NewArray(items...)
+	case []any:
+		items := make([]any, len(val))
+		marks := make(map[string]bool)
+		for i, item := range val {
+			items[i] = p.toJS(item, path)
+			if tomlWholeFloat(item) {
+				marks[strconv.Itoa(i)] = true
+			}
+		}
+		arr := vm.NewArray(items...)
+		p.tag(arr, marks)
+		return arr
+	case time.Time:
+		switch val.Location().String() {
+		case "datetime-local":
+			return tomlLocalValue(vm, val.Format("2006-01-02T15:04:05.999999999"))
+		case "date-local":
+			return tomlLocalValue(vm, val.Format(time.DateOnly))
+		case "time-local":
+			return tomlLocalValue(vm, val.Format("15:04:05.999999999"))
+		}
+		d, err := vm.New(vm.Get("Date"), vm.ToValue(val.UnixMilli()))
+		if err != nil {
+			panic(err)
+		}
+		return d
+	default:
+		return vm.ToValue(val)
+	}
+}
+
+// position returns where key under path first appears in the document, or
+// a position after all others for keys the metadata does not list.
+func (p *tomlParse) position(path []string, key string) int {
+	if i, ok := p.order[tomlPath(append(slices.Clip(path), key))]; ok {
+		return i
+	}
+	return len(p.order)
+}
+
+// tag records on obj which of its entries were floats with a whole value.
+func (p *tomlParse) tag(obj *goja.Object, marks map[string]bool) {
+	if len(marks) == 0 {
+		return
+	}
+	if err := obj.DefineDataPropertySymbol(p.floats, p.vm.ToValue(marks),
+		goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(err)
+	}
+}
+
+// tomlWholeFloat reports whether v is a float that a JS number cannot tell
+// from an integer.
+func tomlWholeFloat(v any) bool {
+	f, ok := v.(float64)
+	return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+}
+
+// tomlLocalKey is the hidden property marking a local date or time parsed
+// from TOML, holding its text.
+const tomlLocalKey = "__tomlLocal"
+
+// tomlLocal is a local date, time or date-time, written back verbatim.
+type tomlLocal string
+
+// tomlLocalValue returns the JS object standing for a local date or time.
+func tomlLocalValue(vm *goja.Runtime, text string) goja.Value {
+	obj := vm.NewObject()
+	str := vm.ToValue(func(goja.FunctionCall) goja.Value { return vm.ToValue(text) })
+	for _, name := range []string{"toString", "toJSON"} {
+		if err := obj.DefineDataProperty(name, str, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+			panic(err)
+		}
+	}
+	if err := obj.DefineDataProperty(tomlLocalKey, vm.ToValue(text), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(err)
+	}
+	return obj
+}
+
+// tomlFromJS converts a JS value to one tomlTable writes. ok is false for
+// null and undefined, which TOML cannot hold. float keeps a whole number a
+// float; the entries of a table or array that parse tagged as floats under
+// the floats symbol get it.
+func tomlFromJS(vm *goja.Runtime, floats *goja.Symbol, v goja.Value, float bool) (any, bool) {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, false
+	}
+	switch x := v.Export().(type) {
+	case bool, string:
+		return x, true
+	case int64:
+		if float {
+			return float64(x), true
+		}
+		return x, true
+	case float64:
+		if !float && x == math.Trunc(x) && math.Abs(x) < 1<<63 {
+			return int64(x), true
+		}
+		return x, true
+	case time.Time:
+		return x.UTC(), true
+	}
+
+	obj := v.ToObject(vm)
+	if local := obj.Get(tomlLocalKey); local != nil {
+		return tomlLocal(local.String()), true
+	}
+	var marks map[string]bool
+	if tagged := obj.GetSymbol(floats); tagged != nil {
+		marks, _ = tagged.Export().(map[string]bool)
+	}
+	switch obj.ClassName() {
+	case "Array":
+		n := obj.Get("length").ToInteger()
+		items := make([]any, 0, n)
+		for i := range n {
+			k := strconv.FormatInt(i, 10)
+			if item, ok := tomlFromJS(vm, floats, obj.Get(k), marks[k]); ok {
+				items = append(items, item)
+			}
+		}
+		return items, true
+	case "Object":
+		table := &tomlTable{}
+		for _, k := range obj.Keys() {
+			if item, ok := tomlFromJS(vm, floats, obj.Get(k), marks[k]); ok {
+				table.keys = append(table.keys, k)
+				table.values = append(table.values, item)
+			}
+		}
+		return table, true
+	}
+	panic(vm.NewTypeError(fmt.Sprintf("toml.stringify: cannot store a %s in TOML", obj.ClassName())))
+}
+
+// tomlTable is a table to write, with its keys in order. Values are bool,
+// string, int64, float64, time.Time, tomlLocal, []any or *tomlTable.
+type tomlTable struct {
+	keys   []string
+	values []any
+}
+
+// write writes t, found at path, to b: its plain values first, then its
+// tables and arrays of tables, each in key order. element marks an entry of
+// an array of tables. A table holding nothing but tables gets no header of
+// its own; theirs define it.
+func (t *tomlTable) write(b *strings.Builder, path []string, element bool) {
+	plain, sub := 0, 0
+	for _, v := range t.values {
+		if _, ok := tomlSection(v); ok {
+			sub++
+		} else {
+			plain++
+		}
+	}
+	switch {
+	case element:
+		tomlBreak(b)
+		b.WriteString("[[" + tomlKeyPath(path) + "]]\n")
+	case len(path) > 0 && (plain > 0 || sub == 0):
+		tomlBreak(b)
+		b.WriteString("[" + tomlKeyPath(path) + "]\n")
+	}
+	for i, v := range t.values {
+		if _, ok := tomlSection(v); !ok {
+			b.WriteString(tomlKey(t.keys[i]) + " = ")
+			tomlWriteValue(b, v)
+			b.WriteByte('\n')
+		}
+	}
+	for i, v := range t.values {
+		tables, ok := tomlSection(v)
+		if !ok {
+			continue
+		}
+		subPath := append(slices.Clip(path), t.keys[i])
+		if table, ok := v.(*tomlTable); ok {
+			table.write(b, subPath, false)
+			continue
+		}
+		for _, table := range tables {
+			table.write(b, subPath, true)
+		}
+	}
+}
+
+// tomlSection reports whether v is written as a section of its own: a table,
+// or a non-empty array of nothing but tables, which it returns.
+func tomlSection(v any) ([]*tomlTable, bool) {
+	switch x := v.(type) {
+	case *tomlTable:
+		return nil, true
+	case []any:
+		if len(x) == 0 {
+			return nil, false
+		}
+		tables := make([]*tomlTable, len(x))
+		for i, item := range x {
+			table, ok := item.(*tomlTable)
+			if !ok {
+				return nil, false
+			}
+			tables[i] = table
+		}
+		return tables, true
+	}
+	return nil, false
+}
+
+// tomlBreak separates a section header from what b already holds.
+func tomlBreak(b *strings.Builder) {
+	if b.Len() > 0 {
+		b.WriteByte('\n')
+	}
+}
+
+// tomlWriteValue writes v inline: tables as inline tables, arrays on one line.
+func tomlWriteValue(b *strings.Builder, v any) {
+	switch x := v.(type) {
+	case bool:
+		b.WriteString(strconv.FormatBool(x))
+	case string:
+		b.WriteString(tomlQuote(x))
+	case int64:
+		b.WriteString(strconv.FormatInt(x, 10))
+	case float64:
+		b.WriteString(tomlFloat(x))
+	case time.Time:
+		b.WriteString(x.Format(time.RFC3339Nano))
+	case tomlLocal:
+		b.WriteString(string(x))
+	case []any:
+		b.WriteByte('[')
+		for i, item := range x {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			tomlWriteValue(b, item)
+		}
+		b.WriteByte(']')
+	case *tomlTable:
+		b.WriteByte('{')
+		for i, k := range x.keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(tomlKey(k) + " = ")
+			tomlWriteValue(b, x.values[i])
+		}
+		b.WriteByte('}')
+	}
+}
+
+// tomlFloat formats f so it reads back as a float: with a fraction or an
+// exponent, or as inf or nan.
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// tomlBareKeyRE matches the keys TOML lets stand unquoted.
+var tomlBareKeyRE = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlKey returns k bare when TOML allows it, quoted otherwise.
+func tomlKey(k string) string {
+	if tomlBareKeyRE.MatchString(k) {
+		return k
+	}
+	return tomlQuote(k)
+}
+
+// tomlKeyPath returns the dotted key of a section header.
+func tomlKeyPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, k := range path {
+		keys[i] = tomlKey(k)
+	}
+	return strings.Join(keys, ".")
+}
+
+// tomlQuote returns s as a TOML basic string.
+func tomlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/require"
+)
+
+// maxUserModuleBytes is the size cap for a single @user/ module file, the
+// same limit the loader applies to user scripts.
+const maxUserModuleBytes = 5 * 1024 * 1024 // 5 MB
+
+// userPrefix is the require() namespace served from ExecutionInput.LibDir.
+const userPrefix = "@user/"
+
+// requireLoader returns the source loader for one execution: @user/ modules
+// come from libDir, everything else goes through blockingRequireLoader.
+// userRead, if non-nil, is called for each @user/ module read.
+func requireLoader(libDir string, userRead func()) require.SourceLoader {
+	return func(p string) ([]byte, error) {
+		if name, ok := strings.CutPrefix(p, "node_modules/"+userPrefix); ok {
+			if userRead != nil {
+				userRead()
+			}
+			return readUserModule(libDir, name)
+		}
+		if strings.Contains(p, "/node_modules/"+userPrefix) {
+			// goja_nodejs first looks for node_modules next to the requiring
+			// module; keep it walking up to the top-level node_modules so each
+			// module has exactly one path (and one instance).
+			return nil, require.ModuleFileDoesNotExistError
+		}
+		return blockingRequireLoader(p)
+	}
+}
+
+// readUserModule reads name (as passed by goja_nodejs, e.g. "csv.js" or
+// "ids/index.js") from libDir. Only .js files inside libDir are served.
+func readUserModule(libDir, name string) ([]byte, error) {
+	name = path.Clean(name)
+	if libDir == "" || path.Ext(name) != ".js" || name == ".." || strings.HasPrefix(name, "../") ||
+		strings.Contains(name, "node_modules") {
+		return nil, require.ModuleFileDoesNotExistError
+	}
+
+	file := filepath.Join(libDir, filepath.FromSlash(name))
+	info, err := os.Stat(file)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, require.ModuleFileDoesNotExistError
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s%s: %w", userPrefix, name, err)
+	}
+	if info.Size() > maxUserModuleBytes {
+		return nil, fmt.Errorf("%s%s: file size %d B exceeds limit of %d B", userPrefix, name, info.Size(), maxUserModuleBytes)
+	}
+	return os.ReadFile(file)
+}
+
+// userModuleCandidates lists the files a require of key ("@user/name") may
+// resolve to, for "Cannot find module" messages.
+func userModuleCandidates(libDir, key string) []string {
+	name := filepath.FromSlash(strings.TrimPrefix(key, userPrefix))
+	return []string{
+		filepath.Join(libDir, name+".js"),
+		filepath.Join(libDir, name, "index.js"),
+	}
+}
+
+// wrapRequire replaces the global require() with one that detects circular
+// @user/ requires and reports missing @user/ modules with the locations it
+// searched. Other names pass straight through to goja_nodejs.
+func wrapRequire(vm *goja.Runtime, libDir string) {
+	orig, ok := goja.AssertFunction(vm.Get("require"))
+	if !ok {
+		return
+	}
+	var loading []string // @user/ modules whose top-level code is running
+
+	vm.Set("require", func(call goja.FunctionCall) goja.Value {
+		key := userModuleKey(vm, call.Argument(0).String())
+		if key == "" {
+			v, err := orig(goja.Undefined(), call.Arguments...)
+			if err != nil {
+				panic(err)
+			}
+			return v
+		}
+
+		if i := slices.Index(loading, key); i >= 0 {
+			chain := append(slices.Clone(loading[i:]), key)
+			throwError(vm, "Circular require: %s", strings.Join(chain, " -> "))
+		}
+		loading = append(loading, key)
+		defer func() { loading = loading[:len(loading)-1] }()
+
+		// Require the canonical name so resolution does not depend on the
+		// caller's location.
+		v, err := orig(goja.Undefined(), vm.ToValue(key))
+		if err != nil {
+			if libDir == "" {
+				throwError(vm, "Cannot find module '%s': no user library directory is configured", key)
+			}
+			candidates := userModuleCandidates(libDir, key)
+			if !slices.ContainsFunc(candidates, fileExists) {
+				throwError(vm, "Cannot find module '%s'; looked in: %s", key, strings.Join(candidates, ", "))
+			}
+			panic(err)
+		}
+		return v
+	})
+}
+
+// userModuleKey returns the canonical "@user/name" for a require() argument
+// naming a user module — directly, or relative to the @user/ module making
+// the call — and "" for anything else.
+func userModuleKey(vm *goja.Runtime, name string) string {
+	var key string
+	switch {
+	case strings.HasPrefix(name, userPrefix):
+		key = path.Clean(name)
+	case strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../"):
+		frames := vm.CaptureCallStack(2, nil)
+		if len(frames) < 2 {
+			return ""
+		}
+		caller, ok := strings.CutPrefix(filepath.ToSlash(frames[1].SrcName()), "node_modules/")
+		if !ok || !strings.HasPrefix(caller, userPrefix) {
+			return ""
+		}
+		key = path.Join(path.Dir(caller), name)
+	default:
+		return ""
+	}
+	key = strings.TrimSuffix(key, ".js")
+	if !strings.HasPrefix(key, userPrefix) || strings.Contains(key, "node_modules") {
+		return "" // escapes the namespace; let the blocking loader reject it
+	}
+	return key
+}
+
+// throwError throws a JavaScript Error, as Node does for require() failures.
+func throwError(vm *goja.Runtime, format string, a ...any) {
+	obj, err := vm.New(vm.Get("Error"), vm.ToValue(fmt.Sprintf(format, a...)))
+	if err != nil {
+		panic(err)
+	}
+	panic(obj)
+}
+
+func fileExists(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && !info.IsDir()
+}
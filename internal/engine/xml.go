@@ -0,0 +1,580 @@
+package engine
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// xmlModuleLoader exposes xml.parse, xml.stringify, xml.query, xml.text,
+// xml.toObject and xml.fromObject to scripts.
+//
+// parse returns a document node. Every node is a plain object with a type:
+//
+//	{type: "document", children: [...]}
+//	{type: "element", name: "soap:Body", namespace: "http://…", attributes: {…}, children: [...]}
+//	{type: "text" | "cdata" | "comment" | "directive", text: "…"}
+//	{type: "instruction", target: "xml", text: "version=\"1.0\""}
+//
+// Names are kept as written, prefix included, and namespace holds the URI
+// the prefix is bound to. Text that is only whitespace is dropped unless
+// opts.whitespace is set. stringify writes any node back, indenting elements
+// that hold only other elements by opts.indent spaces (2 by default, 0 for
+// none); it also accepts a bare string in children as a text node.
+//
+// toObject and fromObject convert to and from a compact object form for JSON:
+// an element holding only text becomes that string, attributes are keys
+// prefixed with "@", text beside attributes or elements is "#text", and
+// repeated child elements become arrays.
+func xmlModuleLoader(vm *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+
+	exports.Set("parse", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("xml.parse requires a string argument"))
+		}
+		keepSpace := false
+		if opts := call.Argument(1); !goja.IsUndefined(opts) && !goja.IsNull(opts) {
+			if ws := opts.ToObject(vm).Get("whitespace"); ws != nil {
+				keepSpace = ws.ToBoolean()
+			}
+		}
+		doc, err := xmlParse(call.Argument(0).String(), keepSpace)
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("xml.parse: %w", err)))
+		}
+		return xmlToJS(vm, doc)
+	})
+
+	exports.Set("stringify", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("xml.stringify requires a node"))
+		}
+		indent := 2
+		if opts := call.Argument(1); !goja.IsUndefined(opts) && !goja.IsNull(opts) {
+			if v := opts.ToObject(vm).Get("indent"); v != nil && !goja.IsUndefined(v) {
+				indent = int(v.ToInteger())
+				if indent < 0 || indent > 8 {
+					panic(vm.NewTypeError("xml.stringify: indent must be between 0 and 8"))
+				}
+			}
+		}
+		n, err := xmlFromJS(vm, call.Argument(0))
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("xml.stringify: %w", err)))
+		}
+		w := &xmlWriter{indent: strings.Repeat(" ", indent)}
+		if err := w.write(n, 0, indent > 0); err != nil {
+			panic(vm.NewGoError(fmt.Errorf("xml.stringify: %w", err)))
+		}
+		return vm.ToValue(w.b.String())
+	})
+
+	exports.Set("query", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 2 {
+			panic(vm.NewTypeError("xml.query requires a node and a path"))
+		}
+		ctx, ok := call.Argument(0).(*goja.Object)
+		if !ok {
+			panic(vm.NewTypeError("xml.query: the first argument must be a node"))
+		}
+		path, err := parseXMLPath(call.Argument(1).String())
+		if err != nil {
+			panic(vm.NewTypeError(fmt.Sprintf("xml.query: %v", err)))
+		}
+		return vm.NewArray(path.eval(vm, ctx)...)
+	})
+
+	exports.Set("text", func(call goja.FunctionCall) goja.Value {
+		n, ok := call.Argument(0).(*goja.Object)
+		if !ok {
+			panic(vm.NewTypeError("xml.text requires a node"))
+		}
+		return vm.ToValue(xmlTextContent(vm, n))
+	})
+
+	exports.Set("toObject", func(call goja.FunctionCall) goja.Value {
+		n, err := xmlFromJS(vm, call.Argument(0))
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("xml.toObject: %w", err)))
+		}
+		if n.Type == "document" {
+			if n = n.root(); n == nil {
+				panic(vm.NewGoError(errors.New("xml.toObject: the document has no root element")))
+			}
+		}
+		if n.Type != "element" {
+			panic(vm.NewTypeError("xml.toObject requires a document or an element"))
+		}
+		obj := vm.NewObject()
+		obj.Set(n.Name, xmlCompact(vm, n))
+		return obj
+	})
+
+	exports.Set("fromObject", func(call goja.FunctionCall) goja.Value {
+		obj, ok := call.Argument(0).(*goja.Object)
+		if !ok || obj.ClassName() != "Object" || len(obj.Keys()) != 1 {
+			panic(vm.NewTypeError("xml.fromObject requires an object with a single key naming the root element"))
+		}
+		name := obj.Keys()[0]
+		root, err := xmlExpand(vm, name, obj.Get(name))
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("xml.fromObject: %w", err)))
+		}
+		doc := &xmlNode{Type: "document", Children: []*xmlNode{
+			{Type: "instruction", Target: "xml", Text: `version="1.0" encoding="UTF-8"`},
+			root,
+		}}
+		xmlResolve(doc, nil)
+		return xmlToJS(vm, doc)
+	})
+}
+
+// xmlNode is a node of the object model, in Go.
+type xmlNode struct {
+	Type      string // document, element, text, cdata, comment, instruction or directive
+	Name      string // element name as written, prefix included
+	Namespace string // namespace URI of the element
+	Attrs     []xmlAttr
+	Children  []*xmlNode
+	Target    string // instruction target
+	Text      string
+}
+
+// xmlAttr is an attribute, named as written.
+type xmlAttr struct {
+	Name, Value string
+}
+
+// root returns the root element of a document, or nil.
+func (n *xmlNode) root() *xmlNode {
+	for _, c := range n.Children {
+		if c.Type == "element" {
+			return c
+		}
+	}
+	return nil
+}
+
+// xmlNamespaceURI is the namespace the xml prefix is always bound to.
+const xmlNamespaceURI = "http://www.w3.org/XML/1998/namespace"
+
+// xmlParse parses src into a document. The declared encoding is ignored: src
+// is already text.
+func xmlParse(src string, keepSpace bool) (*xmlNode, error) {
+	dec := xml.NewDecoder(strings.NewReader(src))
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	doc := &xmlNode{Type: "document"}
+	stack := []*xmlNode{doc}
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &xmlNode{Type: "element", Name: xmlQName(t.Name)}
+			for _, a := range t.Attr {
+				el.Attrs = append(el.Attrs, xmlAttr{Name: xmlQName(a.Name), Value: a.Value})
+			}
+			if top == doc && doc.root() != nil {
+				line, _ := dec.InputPos()
+				return nil, fmt.Errorf("line %d: more than one root element", line)
+			}
+			top.Children = append(top.Children, el)
+			stack = append(stack, el)
+		case xml.EndElement:
+			if name := xmlQName(t.Name); top == doc || top.Name != name {
+				line, _ := dec.InputPos()
+				if top == doc {
+					return nil, fmt.Errorf("line %d: unexpected </%s>", line, name)
+				}
+				return nil, fmt.Errorf("line %d: element <%s> closed by </%s>", line, top.Name, name)
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if strings.HasPrefix(src[start:], "<![CDATA[") {
+				top.Children = append(top.Children, &xmlNode{Type: "cdata", Text: string(t)})
+				continue
+			}
+			text := string(t)
+			if strings.TrimSpace(text) == "" && (!keepSpace || top == doc) {
+				continue
+			}
+			if top == doc {
+				line, _ := dec.InputPos()
+				return nil, fmt.Errorf("line %d: text outside the root element", line)
+			}
+			if last := len(top.Children) - 1; last >= 0 && top.Children[last].Type == "text" {
+				top.Children[last].Text += text
+				continue
+			}
+			top.Children = append(top.Children, &xmlNode{Type: "text", Text: text})
+		case xml.Comment:
+			top.Children = append(top.Children, &xmlNode{Type: "comment", Text: string(t)})
+		case xml.ProcInst:
+			top.Children = append(top.Children, &xmlNode{Type: "instruction", Target: t.Target, Text: string(t.Inst)})
+		case xml.Directive:
+			top.Children = append(top.Children, &xmlNode{Type: "directive", Text: string(t)})
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("element <%s> is not closed", stack[len(stack)-1].Name)
+	}
+	if doc.root() == nil {
+		return nil, errors.New("no root element")
+	}
+	xmlResolve(doc, nil)
+	return doc, nil
+}
+
+// xmlQName returns a raw token name as written.
+func xmlQName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// xmlResolve sets the namespace of every element under n from the xmlns
+// declarations in scope, keyed by prefix with "" for the default namespace.
+func xmlResolve(n *xmlNode, scope map[string]string) {
+	if n.Type == "element" {
+		declared := false
+		for _, a := range n.Attrs {
+			prefix, ok := strings.CutPrefix(a.Name, "xmlns:")
+			if !ok && a.Name != "xmlns" {
+				continue
+			}
+			if !ok {
+				prefix = ""
+			}
+			if !declared {
+				inner := map[string]string{}
+				maps.Copy(inner, scope)
+				scope, declared = inner, true
+			}
+			scope[prefix] = a.Value
+		}
+		prefix, _, found := strings.Cut(n.Name, ":")
+		switch {
+		case !found:
+			n.Namespace = scope[""]
+		case prefix == "xml":
+			n.Namespace = xmlNamespaceURI
+		default:
+			n.Namespace = scope[prefix]
+		}
+	}
+	for _, c := range n.Children {
+		xmlResolve(c, scope)
+	}
+}
+
+// xmlToJS converts a node to its JS object.
+func xmlToJS(vm *goja.Runtime, n *xmlNode) goja.Value {
+	obj := vm.NewObject()
+	obj.Set("type", n.Type)
+	switch n.Type {
+	case "element":
+		obj.Set("name", n.Name)
+		obj.Set("namespace", n.Namespace)
+		attrs := vm.NewObject()
+		for _, a := range n.Attrs {
+			attrs.Set(a.Name, a.Value)
+		}
+		obj.Set("attributes", attrs)
+	case "instruction":
+		obj.Set("target", n.Target)
+	}
+	switch n.Type {
+	case "document", "element":
+		children := make([]any, len(n.Children))
+		for i, c := range n.Children {
+			children[i] = xmlToJS(vm, c)
+		}
+		obj.Set("children", vm.NewArray(children...))
+	default:
+		obj.Set("text", n.Text)
+	}
+	return obj
+}
+
+// xmlFromJS converts a node's JS object back to a node. A string stands for
+// a text node.
+func xmlFromJS(vm *goja.Runtime, v goja.Value) (*xmlNode, error) {
+	if s, ok := v.Export().(string); ok {
+		return &xmlNode{Type: "text", Text: s}, nil
+	}
+	obj, ok := v.(*goja.Object)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a node", v.String())
+	}
+	n := &xmlNode{Type: xmlField(obj, "type")}
+	switch n.Type {
+	case "element":
+		n.Name = xmlField(obj, "name")
+		n.Namespace = xmlField(obj, "namespace")
+		if !xmlNameRE.MatchString(n.Name) {
+			return nil, fmt.Errorf("invalid element name %q", n.Name)
+		}
+		if attrs, ok := obj.Get("attributes").(*goja.Object); ok {
+			for _, k := range attrs.Keys() {
+				if !xmlNameRE.MatchString(k) {
+					return nil, fmt.Errorf("invalid attribute name %q on <%s>", k, n.Name)
+				}
+				n.Attrs = append(n.Attrs, xmlAttr{Name: k, Value: attrs.Get(k).String()})
+			}
+		}
+	case "instruction":
+		n.Target = xmlField(obj, "target")
+		if !xmlNameRE.MatchString(n.Target) {
+			return nil, fmt.Errorf("invalid instruction target %q", n.Target)
+		}
+		n.Text = xmlField(obj, "text")
+	case "text", "cdata", "comment", "directive":
+		n.Text = xmlField(obj, "text")
+	case "document":
+	default:
+		return nil, fmt.Errorf("unknown node type %q", n.Type)
+	}
+	if n.Type == "document" || n.Type == "element" {
+		if children, ok := obj.Get("children").(*goja.Object); ok {
+			for i := range children.Get("length").ToInteger() {
+				c, err := xmlFromJS(vm, children.Get(fmt.Sprint(i)))
+				if err != nil {
+					return nil, err
+				}
+				n.Children = append(n.Children, c)
+			}
+		}
+	}
+	return n, nil
+}
+
+// xmlField returns obj[name] as a string, or "" when it is not set.
+func xmlField(obj *goja.Object, name string) string {
+	v := obj.Get(name)
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return ""
+	}
+	return v.String()
+}
+
+// xmlNameRE matches an XML name, prefix included.
+var xmlNameRE = regexp.MustCompile(`^[\pL_:][\pL\pN_.:\x{B7}-]*$`)
+
+// xmlWriter writes nodes as XML text.
+type xmlWriter struct {
+	b      strings.Builder
+	indent string
+}
+
+// write writes n at the given depth. pretty is false inside mixed content,
+// where added whitespace would change the text.
+func (w *xmlWriter) write(n *xmlNode, depth int, pretty bool) error {
+	switch n.Type {
+	case "document":
+		for i, c := range n.Children {
+			if i > 0 {
+				w.b.WriteByte('\n')
+			}
+			if err := w.write(c, 0, pretty); err != nil {
+				return err
+			}
+		}
+	case "element":
+		w.b.WriteString("<" + n.Name)
+		for _, a := range n.Attrs {
+			w.b.WriteString(" " + a.Name + `="` + xmlAttrEscaper.Replace(a.Value) + `"`)
+		}
+		if len(n.Children) == 0 {
+			w.b.WriteString("/>")
+			return nil
+		}
+		w.b.WriteByte('>')
+		pretty = pretty && !n.hasText()
+		for _, c := range n.Children {
+			if pretty {
+				w.b.WriteString("\n" + strings.Repeat(w.indent, depth+1))
+			}
+			if err := w.write(c, depth+1, pretty); err != nil {
+				return err
+			}
+		}
+		if pretty {
+			w.b.WriteString("\n" + strings.Repeat(w.indent, depth))
+		}
+		w.b.WriteString("</" + n.Name + ">")
+	case "text":
+		w.b.WriteString(xmlTextEscaper.Replace(n.Text))
+	case "cdata":
+		w.b.WriteString("<![CDATA[" + strings.ReplaceAll(n.Text, "]]>", "]]]]><![CDATA[>") + "]]>")
+	case "comment":
+		if strings.Contains(n.Text, "--") || strings.HasSuffix(n.Text, "-") {
+			return fmt.Errorf("comment %q cannot contain \"--\" or end with \"-\"", n.Text)
+		}
+		w.b.WriteString("<!--" + n.Text + "-->")
+	case "instruction":
+		if strings.Contains(n.Text, "?>") {
+			return fmt.Errorf("instruction %q cannot contain \"?>\"", n.Target)
+		}
+		w.b.WriteString("<?" + n.Target)
+		if n.Text != "" {
+			w.b.WriteString(" " + n.Text)
+		}
+		w.b.WriteString("?>")
+	case "directive":
+		w.b.WriteString("<!" + n.Text + ">")
+	}
+	return nil
+}
+
+// hasText reports whether n directly holds text or CDATA.
+func (n *xmlNode) hasText() bool {
+	for _, c := range n.Children {
+		if c.Type == "text" || c.Type == "cdata" {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;",
+		"\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+// xmlTextContent returns the text and CDATA under a node's JS object,
+// concatenated in document order.
+func xmlTextContent(vm *goja.Runtime, n *goja.Object) string {
+	switch xmlField(n, "type") {
+	case "text", "cdata":
+		return xmlField(n, "text")
+	}
+	var b strings.Builder
+	for _, c := range xmlChildren(n) {
+		b.WriteString(xmlTextContent(vm, c))
+	}
+	return b.String()
+}
+
+// xmlChildren returns the child node objects of a node's JS object.
+func xmlChildren(n *goja.Object) []*goja.Object {
+	children, ok := n.Get("children").(*goja.Object)
+	if !ok {
+		return nil
+	}
+	var out []*goja.Object
+	for i := range children.Get("length").ToInteger() {
+		if c, ok := children.Get(fmt.Sprint(i)).(*goja.Object); ok {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// xmlCompact returns the compact form of an element.
+func xmlCompact(vm *goja.Runtime, n *xmlNode) goja.Value {
+	var text strings.Builder
+	var elements []*xmlNode
+	for _, c := range n.Children {
+		switch c.Type {
+		case "text", "cdata":
+			text.WriteString(c.Text)
+		case "element":
+			elements = append(elements, c)
+		}
+	}
+	if len(n.Attrs) == 0 && len(elements) == 0 {
+		return vm.ToValue(text.String())
+	}
+	obj := vm.NewObject()
+	for _, a := range n.Attrs {
+		obj.Set("@"+a.Name, a.Value)
+	}
+	var order []string
+	groups := map[string][]any{}
+	for _, c := range elements {
+		if _, ok := groups[c.Name]; !ok {
+			order = append(order, c.Name)
+		}
+		groups[c.Name] = append(groups[c.Name], xmlCompact(vm, c))
+	}
+	for _, name := range order {
+		if items := groups[name]; len(items) == 1 {
+			obj.Set(name, items[0])
+		} else {
+			obj.Set(name, vm.NewArray(items...))
+		}
+	}
+	if s := strings.TrimSpace(text.String()); s != "" {
+		obj.Set("#text", s)
+	}
+	return obj
+}
+
+// xmlExpand builds the element name from its compact form v.
+func xmlExpand(vm *goja.Runtime, name string, v goja.Value) (*xmlNode, error) {
+	if !xmlNameRE.MatchString(name) {
+		return nil, fmt.Errorf("invalid element name %q", name)
+	}
+	el := &xmlNode{Type: "element", Name: name}
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return el, nil
+	}
+	obj, ok := v.(*goja.Object)
+	if !ok {
+		el.Children = []*xmlNode{{Type: "text", Text: v.String()}}
+		return el, nil
+	}
+	switch obj.ClassName() {
+	case "Object":
+	case "Array":
+		return nil, fmt.Errorf("<%s> is an array inside an array", name)
+	default:
+		el.Children = []*xmlNode{{Type: "text", Text: v.String()}}
+		return el, nil
+	}
+	for _, k := range obj.Keys() {
+		item := obj.Get(k)
+		if attr, ok := strings.CutPrefix(k, "@"); ok {
+			if !xmlNameRE.MatchString(attr) {
+				return nil, fmt.Errorf("invalid attribute name %q on <%s>", attr, name)
+			}
+			el.Attrs = append(el.Attrs, xmlAttr{Name: attr, Value: item.String()})
+			continue
+		}
+		if k == "#text" {
+			el.Children = append(el.Children, &xmlNode{Type: "text", Text: item.String()})
+			continue
+		}
+		items := []goja.Value{item}
+		if arr, ok := item.(*goja.Object); ok && arr.ClassName() == "Array" {
+			items = items[:0]
+			for i := range arr.Get("length").ToInteger() {
+				items = append(items, arr.Get(fmt.Sprint(i)))
+			}
+		}
+		for _, it := range items {
+			c, err := xmlExpand(vm, k, it)
+			if err != nil {
+				return nil, err
+			}
+			el.Children = append(el.Children, c)
+		}
+	}
+	return el, nil
+}
@@ -0,0 +1,373 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// xmlPath is a parsed xml.query path: a subset of XPath 1.0 abbreviated
+// syntax. Steps are separated by / (child) or // (descendant); a leading /
+// starts from the document, or from the element queried as if it were the
+// root. A step is a name (matching the local name, or the whole name when it
+// has a prefix), *, ., @name, @*, text() or node(), followed by any number of
+// predicates: [n], [last()], [@a], [@a='v'], [child], [child='v'] or
+// [text()='v'], with != also accepted.
+type xmlPath struct {
+	absolute bool
+	steps    []xmlStep
+}
+
+// xmlStep is one step of an xmlPath.
+type xmlStep struct {
+	descendant bool   // reached with // rather than /
+	test       string // name, "*", ".", "@name", "@*", "text()" or "node()"
+	preds      []xmlPred
+}
+
+// xmlPred is a predicate: a position when index is set, otherwise a test
+// that operand (an @attribute, text() or child name) exists or compares
+// equal, or unequal when negate is set, to value.
+type xmlPred struct {
+	index   int // 1-based; -1 for last()
+	operand string
+	compare bool
+	negate  bool
+	value   string
+}
+
+// parseXMLPath parses an xml.query path.
+func parseXMLPath(src string) (*xmlPath, error) {
+	p := &xmlPath{}
+	rest := strings.TrimSpace(src)
+	if rest == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+	descendant := false
+	switch {
+	case strings.HasPrefix(rest, "//"):
+		p.absolute, descendant, rest = true, true, rest[2:]
+	case strings.HasPrefix(rest, "/"):
+		p.absolute, rest = true, rest[1:]
+	}
+	for {
+		end := xmlStepEnd(rest)
+		step, err := parseXMLStep(rest[:end])
+		if err != nil {
+			return nil, fmt.Errorf("path %q: %w", src, err)
+		}
+		step.descendant = descendant
+		p.steps = append(p.steps, step)
+		rest = rest[end:]
+		if rest == "" {
+			return p, nil
+		}
+		descendant = strings.HasPrefix(rest, "//")
+		rest = strings.TrimPrefix(strings.TrimPrefix(rest, "/"), "/")
+	}
+}
+
+// xmlStepEnd returns the length of the step at the start of s: up to the
+// next / outside brackets and quotes.
+func xmlStepEnd(s string) int {
+	depth, quote := 0, byte(0)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '/' && depth == 0:
+			return i
+		}
+	}
+	return len(s)
+}
+
+// parseXMLStep parses one step with its predicates.
+func parseXMLStep(s string) (xmlStep, error) {
+	s = strings.TrimSpace(s)
+	test, preds, hasPreds := strings.Cut(s, "[")
+	step := xmlStep{test: strings.TrimSpace(test)}
+	if step.test == "" {
+		return step, fmt.Errorf("empty step")
+	}
+	if name := strings.TrimPrefix(step.test, "@"); step.test != "." && step.test != "*" &&
+		step.test != "text()" && step.test != "node()" && name != "*" && !xmlNameRE.MatchString(name) {
+		return step, fmt.Errorf("invalid step %q", step.test)
+	}
+	if !hasPreds {
+		return step, nil
+	}
+	preds = "[" + preds
+	for preds != "" {
+		if preds[0] != '[' {
+			return step, fmt.Errorf("unexpected %q in step %q", preds, s)
+		}
+		end := xmlPredEnd(preds)
+		if end < 0 {
+			return step, fmt.Errorf("unclosed [ in step %q", s)
+		}
+		pred, err := parseXMLPred(strings.TrimSpace(preds[1:end]))
+		if err != nil {
+			return step, err
+		}
+		step.preds = append(step.preds, pred)
+		preds = strings.TrimSpace(preds[end+1:])
+	}
+	return step, nil
+}
+
+// xmlPredEnd returns the index of the ] closing the predicate s starts with,
+// or -1.
+func xmlPredEnd(s string) int {
+	quote := byte(0)
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseXMLPred parses the inside of a predicate.
+func parseXMLPred(s string) (xmlPred, error) {
+	if s == "last()" {
+		return xmlPred{index: -1}, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 {
+			return xmlPred{}, fmt.Errorf("position [%d] must be 1 or more", n)
+		}
+		return xmlPred{index: n}, nil
+	}
+	pred := xmlPred{operand: s}
+	if i := strings.Index(s, "="); i > 0 {
+		pred.compare = true
+		left := s[:i]
+		if strings.HasSuffix(left, "!") {
+			pred.negate, left = true, left[:len(left)-1]
+		}
+		pred.operand = strings.TrimSpace(left)
+		value := strings.TrimSpace(s[i+1:])
+		if len(value) < 2 || (value[0] != '\'' && value[0] != '"') || value[len(value)-1] != value[0] {
+			return pred, fmt.Errorf("predicate [%s]: the value must be quoted", s)
+		}
+		pred.value = value[1 : len(value)-1]
+	}
+	if name := strings.TrimPrefix(pred.operand, "@"); pred.operand != "text()" && !xmlNameRE.MatchString(name) {
+		return pred, fmt.Errorf("invalid predicate [%s]", s)
+	}
+	return pred, nil
+}
+
+// eval runs the path from ctx, returning element and other node objects, or
+// strings for @attribute and text() steps, in document order.
+func (p *xmlPath) eval(vm *goja.Runtime, ctx *goja.Object) []any {
+	current := []goja.Value{ctx}
+	if p.absolute && xmlField(ctx, "type") != "document" {
+		// Query an element as the root of a document of its own.
+		doc := vm.NewObject()
+		doc.Set("type", "document")
+		doc.Set("children", vm.NewArray(ctx))
+		current = []goja.Value{doc}
+	}
+	for _, step := range p.steps {
+		var next []goja.Value
+		seen := map[*goja.Object]bool{}
+		for _, v := range current {
+			obj, ok := v.(*goja.Object)
+			if !ok {
+				continue // attribute values and text have no children
+			}
+			for _, m := range step.apply(vm, obj) {
+				if o, ok := m.(*goja.Object); ok {
+					if seen[o] {
+						continue
+					}
+					seen[o] = true
+				}
+				next = append(next, m)
+			}
+		}
+		current = next
+	}
+	out := make([]any, len(current))
+	for i, v := range current {
+		out[i] = v
+	}
+	return out
+}
+
+// apply returns what the step selects from n, in document order.
+func (s xmlStep) apply(vm *goja.Runtime, n *goja.Object) []goja.Value {
+	if !s.descendant {
+		return s.values(vm, s.selectFrom(vm, n))
+	}
+	var out []goja.Value
+	var walk func(*goja.Object)
+	walk = func(o *goja.Object) {
+		selected := s.selectFrom(vm, o)
+		if s.test == "." || strings.HasPrefix(s.test, "@") {
+			// The node itself and its attributes come before its children.
+			out = append(out, selected...)
+			selected = nil
+		}
+		chosen := map[goja.Value]bool{}
+		for _, v := range selected {
+			if obj, ok := v.(*goja.Object); ok {
+				chosen[obj] = true
+			}
+		}
+		for _, c := range xmlChildren(o) {
+			if chosen[c] {
+				out = append(out, c)
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return s.values(vm, out)
+}
+
+// selectFrom returns what the step selects from the one node n, with its
+// predicates applied. Text is returned as its nodes.
+func (s xmlStep) selectFrom(vm *goja.Runtime, n *goja.Object) []goja.Value {
+	var matched []goja.Value
+	switch {
+	case s.test == ".":
+		matched = append(matched, n)
+	case s.test == "@*":
+		if attrs, ok := n.Get("attributes").(*goja.Object); ok {
+			for _, k := range attrs.Keys() {
+				matched = append(matched, attrs.Get(k))
+			}
+		}
+	case strings.HasPrefix(s.test, "@"):
+		if v, ok := xmlAttrValue(n, s.test[1:]); ok {
+			matched = append(matched, vm.ToValue(v))
+		}
+	default:
+		for _, c := range xmlChildren(n) {
+			switch t := xmlField(c, "type"); {
+			case s.test == "node()",
+				s.test == "text()" && (t == "text" || t == "cdata"),
+				t == "element" && xmlNameMatches(s.test, xmlField(c, "name")):
+				matched = append(matched, c)
+			}
+		}
+	}
+	return s.filter(vm, matched)
+}
+
+// values turns the text nodes a text() step selected into their text.
+func (s xmlStep) values(vm *goja.Runtime, nodes []goja.Value) []goja.Value {
+	if s.test != "text()" {
+		return nodes
+	}
+	out := make([]goja.Value, len(nodes))
+	for i, v := range nodes {
+		out[i] = vm.ToValue(xmlField(v.(*goja.Object), "text"))
+	}
+	return out
+}
+
+// filter applies the step's predicates to the nodes one parent selected.
+func (s xmlStep) filter(vm *goja.Runtime, nodes []goja.Value) []goja.Value {
+	for _, pred := range s.preds {
+		var kept []goja.Value
+		for i, v := range nodes {
+			switch {
+			case pred.index == -1:
+				if i == len(nodes)-1 {
+					kept = append(kept, v)
+				}
+			case pred.index > 0:
+				if i == pred.index-1 {
+					kept = append(kept, v)
+				}
+			default:
+				if obj, ok := v.(*goja.Object); ok && pred.matches(vm, obj) {
+					kept = append(kept, v)
+				}
+			}
+		}
+		nodes = kept
+	}
+	return nodes
+}
+
+// matches reports whether the element n satisfies a non-positional predicate.
+func (p xmlPred) matches(vm *goja.Runtime, n *goja.Object) bool {
+	var values []string
+	switch {
+	case strings.HasPrefix(p.operand, "@"):
+		if v, ok := xmlAttrValue(n, p.operand[1:]); ok {
+			values = append(values, v)
+		}
+	case p.operand == "text()":
+		for _, c := range xmlChildren(n) {
+			if t := xmlField(c, "type"); t == "text" || t == "cdata" {
+				values = append(values, xmlField(c, "text"))
+			}
+		}
+	default:
+		for _, c := range xmlChildren(n) {
+			if xmlField(c, "type") == "element" && xmlNameMatches(p.operand, xmlField(c, "name")) {
+				values = append(values, xmlTextContent(vm, c))
+			}
+		}
+	}
+	if !p.compare {
+		return len(values) > 0
+	}
+	for _, v := range values {
+		if (v == p.value) != p.negate {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlAttrValue returns the value of the attribute name on the element n,
+// matching the local name when name has no prefix.
+func xmlAttrValue(n *goja.Object, name string) (string, bool) {
+	attrs, ok := n.Get("attributes").(*goja.Object)
+	if !ok {
+		return "", false
+	}
+	for _, k := range attrs.Keys() {
+		if xmlNameMatches(name, k) {
+			return attrs.Get(k).String(), true
+		}
+	}
+	return "", false
+}
+
+// xmlNameMatches reports whether the name test matches a name as written.
+// A test without a prefix matches the local name in any namespace.
+func xmlNameMatches(test, name string) bool {
+	if test == "*" || test == name {
+		return true
+	}
+	if strings.Contains(test, ":") {
+		return false
+	}
+	prefix, local, found := strings.Cut(name, ":")
+	return found && prefix != "xmlns" && local == test
+}
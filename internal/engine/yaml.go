@@ -0,0 +1,432 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlModuleLoader exposes yaml.parse, yaml.parseAll, yaml.stringify and
+// yaml.stringifyAll to scripts.
+//
+// Mappings keep the order they are written in, except that JS lists keys
+// that look like array indices ("1", "2") first. Every object parse returns
+// remembers the document it came from, so stringify of that object, changed
+// or not, keeps the comments and scalar styles of the entries still present.
+// Timestamps are left as the strings they are written as.
+func yamlModuleLoader(vm *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+	source := goja.NewSymbol("yaml.source")
+
+	exports.Set("parse", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("yaml.parse requires a string argument"))
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(call.Argument(0).String()), &doc); err != nil {
+			panic(vm.NewGoError(fmt.Errorf("yaml.parse: %w", err)))
+		}
+		v, err := yamlDocToJS(vm, source, &doc)
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("yaml.parse: %w", err)))
+		}
+		return v
+	})
+
+	exports.Set("parseAll", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("yaml.parseAll requires a string argument"))
+		}
+		dec := yaml.NewDecoder(strings.NewReader(call.Argument(0).String()))
+		var docs []any
+		for i := 1; ; i++ {
+			var doc yaml.Node
+			err := dec.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("yaml.parseAll: document %d: %w", i, err)))
+			}
+			v, err := yamlDocToJS(vm, source, &doc)
+			if err != nil {
+				panic(vm.NewGoError(fmt.Errorf("yaml.parseAll: document %d: %w", i, err)))
+			}
+			docs = append(docs, v)
+		}
+		return vm.NewArray(docs...)
+	})
+
+	exports.Set("stringify", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("yaml.stringify requires an argument"))
+		}
+		opts := yamlOptions(vm, "yaml.stringify", call.Argument(1))
+		doc := yamlDocFromJS(vm, source, call.Argument(0), opts)
+		return vm.ToValue(yamlEncode(vm, "yaml.stringify", opts, doc))
+	})
+
+	exports.Set("stringifyAll", func(call goja.FunctionCall) goja.Value {
+		var items []goja.Value
+		if arr, ok := call.Argument(0).(*goja.Object); ok && arr.ClassName() == "Array" {
+			for i := range arr.Get("length").ToInteger() {
+				items = append(items, arr.Get(fmt.Sprint(i)))
+			}
+		} else {
+			panic(vm.NewTypeError("yaml.stringifyAll requires an array of documents"))
+		}
+		opts := yamlOptions(vm, "yaml.stringifyAll", call.Argument(1))
+		docs := make([]*yaml.Node, len(items))
+		for i, item := range items {
+			docs[i] = yamlDocFromJS(vm, source, item, opts)
+		}
+		return vm.ToValue(yamlEncode(vm, "yaml.stringifyAll", opts, docs...))
+	})
+}
+
+// yamlStringifyOptions are the options stringify and stringifyAll accept.
+type yamlStringifyOptions struct {
+	indent int  // spaces per level; yaml.v3's own default is 4
+	flow   bool // write collections inline, as {a: 1, b: [x, y]}
+}
+
+// yamlOptions reads the options object passed to api.
+func yamlOptions(vm *goja.Runtime, api string, v goja.Value) yamlStringifyOptions {
+	opts := yamlStringifyOptions{indent: 4}
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return opts
+	}
+	obj := v.ToObject(vm)
+	if indent := obj.Get("indent"); indent != nil && !goja.IsUndefined(indent) {
+		opts.indent = int(indent.ToInteger())
+		if opts.indent < 2 || opts.indent > 8 {
+			panic(vm.NewTypeError(api + ": indent must be between 2 and 8"))
+		}
+	}
+	if flow := obj.Get("flow"); flow != nil {
+		opts.flow = flow.ToBoolean()
+	}
+	return opts
+}
+
+// yamlEncode writes docs as one YAML stream.
+func yamlEncode(vm *goja.Runtime, api string, opts yamlStringifyOptions, docs ...*yaml.Node) string {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(opts.indent)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			panic(vm.NewGoError(fmt.Errorf("%s: %w", api, err)))
+		}
+	}
+	if err := enc.Close(); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("%s: %w", api, err)))
+	}
+	return buf.String()
+}
+
+// yamlDocToJS converts a parsed document to a JS value, tagging objects and
+// arrays with the document under source so stringify can reuse it.
+func yamlDocToJS(vm *goja.Runtime, source *goja.Symbol, doc *yaml.Node) (goja.Value, error) {
+	if doc.Kind == 0 {
+		return goja.Null(), nil
+	}
+	// Decoding once applies yaml.v3's checks for invalid scalars, recursive
+	// anchors and runaway alias expansion before the tree is walked by hand.
+	var check any
+	if err := doc.Decode(&check); err != nil {
+		return nil, err
+	}
+	v := yamlToJS(vm, doc)
+	if obj, ok := v.(*goja.Object); ok {
+		if err := obj.DefineDataPropertySymbol(source, vm.ToValue(doc),
+			goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// yamlToJS converts a node to a JS value, keeping mapping order.
+func yamlToJS(vm *goja.Runtime, n *yaml.Node) goja.Value {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return goja.Null()
+		}
+		return yamlToJS(vm, n.Content[0])
+	case yaml.AliasNode:
+		return yamlToJS(vm, n.Alias)
+	case yaml.SequenceNode:
+		items := make([]any, len(n.Content))
+		for i, item := range n.Content {
+			items[i] = yamlToJS(vm, item)
+		}
+		return vm.NewArray(items...)
+	case yaml.MappingNode:
+		obj := vm.NewObject()
+		yamlEachPair(n, func(k, v *yaml.Node) {
+			obj.Set(yamlKey(k), yamlToJS(vm, v))
+		})
+		return obj
+	}
+	return vm.ToValue(yamlScalar(n))
+}
+
+// yamlEachPair calls fn for each entry of the mapping n, with the entries of
+// any << merge keys first and only where n does not set the key itself.
+func yamlEachPair(n *yaml.Node, fn func(k, v *yaml.Node)) {
+	own := map[string]bool{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if k := n.Content[i]; k.ShortTag() != "!!merge" {
+			own[yamlKey(k)] = true
+		}
+	}
+	seen := map[string]bool{}
+	var merge func(src *yaml.Node)
+	merge = func(src *yaml.Node) {
+		switch src.Kind {
+		case yaml.AliasNode:
+			merge(src.Alias)
+		case yaml.SequenceNode:
+			for _, item := range src.Content {
+				merge(item)
+			}
+		case yaml.MappingNode:
+			yamlEachPair(src, func(k, v *yaml.Node) {
+				if key := yamlKey(k); !own[key] && !seen[key] {
+					seen[key] = true
+					fn(k, v)
+				}
+			})
+		}
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].ShortTag() == "!!merge" {
+			merge(n.Content[i+1])
+		}
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if k := n.Content[i]; k.ShortTag() != "!!merge" {
+			fn(k, n.Content[i+1])
+		}
+	}
+}
+
+// yamlKey returns the object key a mapping key node stands for.
+func yamlKey(k *yaml.Node) string {
+	if k.Kind == yaml.AliasNode {
+		return yamlKey(k.Alias)
+	}
+	if k.Kind == yaml.ScalarNode {
+		return k.Value
+	}
+	return fmt.Sprint(yamlValue(k))
+}
+
+// yamlScalar decodes a scalar node. Timestamps and binary data are left as
+// the text they are written as.
+func yamlScalar(n *yaml.Node) any {
+	switch n.ShortTag() {
+	case "!!timestamp", "!!binary":
+		return n.Value
+	}
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return n.Value
+	}
+	return v
+}
+
+// yamlValue converts a node to plain Go values for comparison: maps, slices
+// and scalars, with every number as a float64.
+func yamlValue(n *yaml.Node) any {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return yamlValue(n.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(n.Alias)
+	case yaml.SequenceNode:
+		items := make([]any, len(n.Content))
+		for i, item := range n.Content {
+			items[i] = yamlValue(item)
+		}
+		return items
+	case yaml.MappingNode:
+		m := map[string]any{}
+		yamlEachPair(n, func(k, v *yaml.Node) { m[yamlKey(k)] = yamlValue(v) })
+		return m
+	}
+	switch v := yamlScalar(n).(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+// yamlDocFromJS returns the document to write for v. An object parse
+// returned is merged into the document it came from.
+func yamlDocFromJS(vm *goja.Runtime, source *goja.Symbol, v goja.Value, opts yamlStringifyOptions) *yaml.Node {
+	fresh := yamlFromJS(vm, v, map[*goja.Object]bool{})
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{fresh}}
+	if obj, ok := v.(*goja.Object); ok {
+		if tagged := obj.GetSymbol(source); tagged != nil {
+			if orig, ok := tagged.Export().(*yaml.Node); ok && len(orig.Content) == 1 {
+				doc = yamlMergeDoc(orig, fresh)
+			}
+		}
+	}
+	if opts.flow {
+		yamlSetFlow(doc.Content[0])
+	}
+	return doc
+}
+
+// yamlSetFlow writes n and every collection under it inline.
+func yamlSetFlow(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+		n.Style |= yaml.FlowStyle
+	}
+	for _, c := range n.Content {
+		yamlSetFlow(c)
+	}
+}
+
+// yamlFromJS converts a JS value to a node, keeping object key order.
+func yamlFromJS(vm *goja.Runtime, v goja.Value, seen map[*goja.Object]bool) *yaml.Node {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	switch x := v.Export().(type) {
+	case bool, string, int64, float64, time.Time:
+		return yamlEncodeScalar(vm, x)
+	}
+
+	obj := v.ToObject(vm)
+	if seen[obj] {
+		panic(vm.NewTypeError("yaml.stringify: cannot write a circular structure"))
+	}
+	seen[obj] = true
+	defer delete(seen, obj)
+	switch obj.ClassName() {
+	case "Array":
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i := range obj.Get("length").ToInteger() {
+			n.Content = append(n.Content, yamlFromJS(vm, obj.Get(fmt.Sprint(i)), seen))
+		}
+		return n
+	case "Object":
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range obj.Keys() {
+			item := obj.Get(k)
+			if _, ok := goja.AssertFunction(item); ok || goja.IsUndefined(item) {
+				continue // as JSON.stringify does
+			}
+			n.Content = append(n.Content, yamlEncodeScalar(vm, k), yamlFromJS(vm, item, seen))
+		}
+		return n
+	}
+	panic(vm.NewTypeError(fmt.Sprintf("yaml.stringify: cannot write a %s as YAML", obj.ClassName())))
+}
+
+// yamlEncodeScalar returns the node yaml.v3 would write for v, quoted where
+// the plain text would read back as another type.
+func yamlEncodeScalar(vm *goja.Runtime, v any) *yaml.Node {
+	var n yaml.Node
+	if err := n.Encode(v); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("yaml.stringify: %w", err)))
+	}
+	return &n
+}
+
+// yamlMergeDoc returns the document orig with its content replaced by fresh.
+// If nothing changed orig is written as is, anchors included; otherwise the
+// two are merged entry by entry, with aliases expanded.
+func yamlMergeDoc(orig, fresh *yaml.Node) *yaml.Node {
+	doc := *orig
+	if reflect.DeepEqual(yamlValue(orig.Content[0]), yamlValue(fresh)) {
+		yamlUntagMerges(&doc)
+		return &doc
+	}
+	doc.Content = []*yaml.Node{yamlMerge(orig.Content[0], fresh)}
+	return &doc
+}
+
+// yamlMerge returns fresh, reusing the nodes of orig, with their comments
+// and styles, wherever the value they hold is unchanged.
+func yamlMerge(orig, fresh *yaml.Node) *yaml.Node {
+	if orig.Kind == yaml.AliasNode {
+		orig = orig.Alias
+	}
+	switch {
+	case orig.Kind == yaml.MappingNode && fresh.Kind == yaml.MappingNode:
+		merged := yamlCopyNode(orig)
+		byKey := map[string][2]*yaml.Node{}
+		yamlEachPair(orig, func(k, v *yaml.Node) { byKey[yamlKey(k)] = [2]*yaml.Node{k, v} })
+		for i := 0; i+1 < len(fresh.Content); i += 2 {
+			k, v := fresh.Content[i], fresh.Content[i+1]
+			if old, ok := byKey[k.Value]; ok {
+				k, v = yamlCopyNode(old[0]), yamlMerge(old[1], v)
+			}
+			merged.Content = append(merged.Content, k, v)
+		}
+		return merged
+	case orig.Kind == yaml.SequenceNode && fresh.Kind == yaml.SequenceNode:
+		merged := yamlCopyNode(orig)
+		for i, v := range fresh.Content {
+			if i < len(orig.Content) {
+				v = yamlMerge(orig.Content[i], v)
+			}
+			merged.Content = append(merged.Content, v)
+		}
+		return merged
+	case orig.Kind == yaml.ScalarNode && fresh.Kind == yaml.ScalarNode &&
+		reflect.DeepEqual(yamlValue(orig), yamlValue(fresh)):
+		return yamlCopyNode(orig)
+	}
+	fresh.HeadComment, fresh.LineComment, fresh.FootComment = orig.HeadComment, orig.LineComment, orig.FootComment
+	return fresh
+}
+
+// yamlCopyNode returns a shallow copy of n without its anchor or children,
+// which the caller fills in; scalars are copied whole.
+func yamlCopyNode(n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	c := *n
+	c.Anchor = ""
+	c.Content = nil
+	return &c
+}
+
+// yamlUntagMerges clears the resolved tag of << merge keys under n, which
+// yaml.v3 would otherwise write out as "!!merge <<". The key still resolves
+// to a merge when read.
+func yamlUntagMerges(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if k := n.Content[i]; k.Tag == "!!merge" && k.Style == 0 {
+				k.Tag = ""
+			}
+		}
+	}
+	for _, c := range n.Content {
+		yamlUntagMerges(c)
+	}
+}
@@ -18,6 +18,11 @@ type Library interface {
 	// Results are sorted by match score descending; ties broken by All() order.
 	Search(query string) []Script
 
+	// Find returns the script whose Name matches name case-insensitively.
+	// When several do (a user script named like a built-in), the first in
+	// All() order wins.
+	Find(name string) (Script, bool)
+
 	// Len returns the total number of loaded scripts.
 	Len() int
 }
@@ -54,6 +59,16 @@ func (lib *ScriptLibrary) All() []Script {
 	return out
 }
 
+// Find implements Library.
+func (lib *ScriptLibrary) Find(name string) (Script, bool) {
+	for _, s := range lib.sorted {
+		if strings.EqualFold(s.Name, name) {
+			return s, true
+		}
+	}
+	return Script{}, false
+}
+
 // Len implements Library.
 func (lib *ScriptLibrary) Len() int {
 	return len(lib.sorted)
@@ -144,6 +144,7 @@ func (l *loader) loadUserScripts(dir string, result *LoadResult) {
 
 		script.Source = UserProvided
 		script.FilePath = absPath
+		script.Hash = ContentHash(script.Content)
 		result.Scripts = append(result.Scripts, script)
 		result.UserCount++
 	}
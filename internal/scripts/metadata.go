@@ -3,6 +3,7 @@ package scripts
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -19,14 +20,23 @@ const (
 type Script struct {
 	Name        string
 	Description string
-	Icon        string   // FontAwesome HTML or icon name; empty if not declared
-	Tags        []string // Empty slice if not declared
-	Bias        float64  // Default 0.0 — lower values sort earlier
+	Icon        string       // FontAwesome HTML or icon name; empty if not declared
+	Tags        []string     // Empty slice if not declared
+	Bias        float64      // Default 0.0 — lower values sort earlier
+	Params      []Param      // Inputs declared with @param, in header order; nil if none
+	Permissions []Permission // Capabilities declared with @permissions; nil if none
+	Output      string       // Language ID declared with @output (e.g. "sql"); empty if not declared
+	Streaming   bool         // Defines mapLine or mapRecord, so it can run over a stream
+	Pure        bool         // Declared @pure: its result depends only on its input, so it may be reused
 	Source      ScriptSource
 	FilePath    string // Virtual path for built-ins; absolute path for user scripts
 	Content     string // Full JavaScript source (including header)
+	Hash        string // ContentHash of Content for user scripts; empty for built-ins
 }
 
+// streamFuncRE finds the top-level functions of a streaming script.
+var streamFuncRE = regexp.MustCompile(`(?m)^function\s+(mapLine|mapRecord)\s*\(`)
+
 // errNoHeader is returned when the file does not start with /**!.
 var errNoHeader = errors.New("missing /**! header")
 
@@ -37,6 +47,8 @@ var errNoHeader = errors.New("missing /**! header")
 // Returns an error when:
 //   - The content does not start with "/**!" (errNoHeader)
 //   - @name or @description are missing or empty after trimming
+//   - an @param line is malformed or declares a duplicate name
+//   - @permissions names an unknown permission
 func ParseHeader(content string) (Script, error) {
 	s := Script{
 		Content: content,
@@ -67,9 +79,13 @@ func ParseHeader(content string) (Script, error) {
 			continue
 		}
 
-		// Split on first whitespace to separate key from value
+		// Split on first whitespace to separate key from value. Only flags
+		// such as @pure stand alone.
 		idx := strings.IndexAny(trimmed, " \t")
 		if idx < 0 {
+			if trimmed == "@pure" {
+				s.Pure = true
+			}
 			continue
 		}
 		key := trimmed[1:idx] // strip leading '@'
@@ -92,10 +108,38 @@ func ParseHeader(content string) (Script, error) {
 			if f, err := strconv.ParseFloat(val, 64); err == nil {
 				s.Bias = f
 			}
+		case "param":
+			p, err := parseParam(val)
+			if err != nil {
+				return s, fmt.Errorf("invalid @param %q: %w", val, err)
+			}
+			for _, existing := range s.Params {
+				if existing.Name == p.Name {
+					return s, fmt.Errorf("duplicate @param %q", p.Name)
+				}
+			}
+			s.Params = append(s.Params, p)
+		case "output":
+			s.Output = strings.ToLower(val)
+		case "pure":
+			s.Pure = val != "false"
+		case "permissions":
+			perms, err := parsePermissions(val)
+			if err != nil {
+				return s, fmt.Errorf("invalid @permissions: %w", err)
+			}
+			for _, p := range perms {
+				if !s.HasPermission(p) {
+					s.Permissions = append(s.Permissions, p)
+				}
+			}
+		default:
 			// Unknown keys are silently ignored
 		}
 	}
 
+	s.Streaming = streamFuncRE.MatchString(body)
+
 	if strings.TrimSpace(s.Name) == "" {
 		return s, fmt.Errorf("/**! header missing @name")
 	}
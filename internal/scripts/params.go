@@ -0,0 +1,211 @@
+package scripts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ParamType is the value type of a script parameter declared with @param.
+type ParamType string
+
+const (
+	ParamString ParamType = "string"  // Free text; exposed to JS as a string
+	ParamNumber ParamType = "number"  // Exposed to JS as a number
+	ParamBool   ParamType = "boolean" // Exposed to JS as true/false
+)
+
+// Param describes one input a script asks for before it runs. It is declared
+// in the header as:
+//
+//	@param name:type "Label" default
+//
+// The type defaults to string and the label to the name when omitted. The
+// default may be written as a Go-style quoted string to include spaces or
+// escapes such as "\n".
+type Param struct {
+	Name    string    // Key in state.params
+	Type    ParamType // Value type
+	Label   string    // Human-readable label shown in the parameter form
+	Default string    // Raw default value; always valid for Type
+}
+
+// Value converts a raw (form or command-line) value into the Go value exposed
+// to scripts: string, float64 or bool depending on the parameter type.
+func (p Param) Value(raw string) (any, error) {
+	switch p.Type {
+	case ParamNumber:
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a number", p.Label, raw)
+		}
+		return f, nil
+	case ParamBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not true or false", p.Label, raw)
+		}
+		return b, nil
+	default:
+		return raw, nil
+	}
+}
+
+// ResolveParams validates raw values against the declared parameters and
+// returns the typed values for ExecutionInput.Params. Parameters without a
+// raw value fall back to their default; raw keys that the script does not
+// declare are rejected so that typos on the command line do not go unnoticed.
+func ResolveParams(params []Param, raw map[string]string) (map[string]any, error) {
+	declared := make(map[string]bool, len(params))
+	out := make(map[string]any, len(params))
+	for _, p := range params {
+		declared[p.Name] = true
+		v, ok := raw[p.Name]
+		if !ok {
+			v = p.Default
+		}
+		val, err := p.Value(v)
+		if err != nil {
+			return nil, err
+		}
+		out[p.Name] = val
+	}
+	for name := range raw {
+		if !declared[name] {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	return out, nil
+}
+
+// parseParam parses the value of an @param header line.
+func parseParam(val string) (Param, error) {
+	spec, rest := val, ""
+	if i := strings.IndexAny(val, " \t"); i >= 0 {
+		spec, rest = val[:i], strings.TrimSpace(val[i+1:])
+	}
+
+	name, typ, hasType := strings.Cut(spec, ":")
+	if name == "" || strings.ContainsAny(name, "\"'") {
+		return Param{}, errors.New("missing parameter name")
+	}
+	p := Param{Name: name, Type: ParamString, Label: name}
+	if hasType {
+		switch typ {
+		case "string":
+			p.Type = ParamString
+		case "number":
+			p.Type = ParamNumber
+		case "boolean", "bool":
+			p.Type = ParamBool
+		default:
+			return Param{}, fmt.Errorf("unknown type %q (want string, number or boolean)", typ)
+		}
+	}
+
+	if strings.HasPrefix(rest, `"`) {
+		label, after, err := cutQuoted(rest)
+		if err != nil {
+			return Param{}, fmt.Errorf("label: %w", err)
+		}
+		p.Label = label
+		rest = strings.TrimSpace(after)
+	}
+
+	if strings.HasPrefix(rest, `"`) {
+		def, after, err := cutQuoted(rest)
+		if err != nil {
+			return Param{}, fmt.Errorf("default: %w", err)
+		}
+		if strings.TrimSpace(after) != "" {
+			return Param{}, fmt.Errorf("unexpected text after default: %q", after)
+		}
+		p.Default = def
+	} else {
+		p.Default = rest
+	}
+
+	if p.Default == "" {
+		switch p.Type {
+		case ParamNumber:
+			p.Default = "0"
+		case ParamBool:
+			p.Default = "false"
+		}
+	}
+	if _, err := p.Value(p.Default); err != nil {
+		return Param{}, fmt.Errorf("default: %w", err)
+	}
+	return p, nil
+}
+
+// cutQuoted splits a leading Go-style double-quoted string off s and returns
+// its unquoted value and the remainder.
+func cutQuoted(s string) (value, rest string, err error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++ // skip the escaped character
+		case '"':
+			value, err = strconv.Unquote(s[:i+1])
+			return value, s[i+1:], err
+		}
+	}
+	return "", "", errors.New("unterminated quoted string")
+}
+
+// ParamMemory remembers the raw parameter values last used for each script so
+// that the parameter form can be pre-filled on the next run. Values are keyed
+// by Script.FilePath and persisted as JSON. It is not safe for concurrent use.
+type ParamMemory struct {
+	path   string
+	values map[string]map[string]string
+}
+
+// LoadParamMemory reads remembered values from path. A missing or unreadable
+// file yields an empty memory; it is created on the first Remember call.
+func LoadParamMemory(path string) *ParamMemory {
+	m := &ParamMemory{path: path, values: map[string]map[string]string{}}
+	if data, err := os.ReadFile(path); err == nil {
+		if json.Unmarshal(data, &m.values) != nil || m.values == nil {
+			m.values = map[string]map[string]string{}
+		}
+	}
+	return m
+}
+
+// Values returns the raw values to pre-fill for s: the remembered value for
+// each declared parameter, or its default when nothing was remembered.
+func (m *ParamMemory) Values(s Script) map[string]string {
+	saved := m.values[s.FilePath]
+	out := make(map[string]string, len(s.Params))
+	for _, p := range s.Params {
+		if v, ok := saved[p.Name]; ok {
+			out[p.Name] = v
+		} else {
+			out[p.Name] = p.Default
+		}
+	}
+	return out
+}
+
+// Remember stores values as the last-used values for s and writes the memory
+// to disk.
+func (m *ParamMemory) Remember(s Script, values map[string]string) error {
+	m.values[s.FilePath] = values
+	data, err := json.MarshalIndent(m.values, "", "  ")
+	if err != nil {
+		return fmt.Errorf("params: marshal: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o755); err != nil {
+		return fmt.Errorf("params: create dir: %w", err)
+	}
+	if err := os.WriteFile(m.path, data, 0o644); err != nil {
+		return fmt.Errorf("params: write: %w", err)
+	}
+	return nil
+}
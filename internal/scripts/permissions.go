@@ -0,0 +1,217 @@
+package scripts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Permission is a capability beyond the default sandbox that a script must
+// declare in its header before it is granted:
+//
+//	@permissions clipboard, storage
+type Permission string
+
+const (
+	PermClipboard   Permission = "clipboard"    // state.clipboard.read() / write()
+	PermStorage     Permission = "storage"      // state.storage, persisted between runs
+	PermReadFile    Permission = "read-file"    // state.readFile(path)
+	PermLongTimeout Permission = "long-timeout" // Run for up to a minute instead of 5 s
+	PermNetworkNone Permission = "network-none" // Promise not to need the network; informational
+)
+
+// knownPermissions lists every permission ParseHeader accepts.
+var knownPermissions = []Permission{
+	PermClipboard, PermStorage, PermReadFile, PermLongTimeout, PermNetworkNone,
+}
+
+// Description returns a short human-readable explanation of what p allows,
+// for display when asking the user to approve it.
+func (p Permission) Description() string {
+	switch p {
+	case PermClipboard:
+		return "Read and replace the clipboard contents"
+	case PermStorage:
+		return "Keep data between runs"
+	case PermReadFile:
+		return "Read any file your user account can read"
+	case PermLongTimeout:
+		return "Run for up to a minute"
+	case PermNetworkNone:
+		return "No network access"
+	default:
+		return string(p)
+	}
+}
+
+// Elevated reports whether p grants more than the default sandbox and
+// therefore needs the user's approval. network-none only restates what the
+// sandbox already enforces.
+func (p Permission) Elevated() bool {
+	return p != PermNetworkNone
+}
+
+// parsePermissions parses the comma- or space-separated value of an
+// @permissions header line.
+func parsePermissions(val string) ([]Permission, error) {
+	var out []Permission
+	for _, f := range strings.FieldsFunc(val, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		p := Permission(strings.ToLower(f))
+		if !slices.Contains(knownPermissions, p) {
+			return nil, fmt.Errorf("unknown permission %q", f)
+		}
+		if !slices.Contains(out, p) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// PermissionNames returns perms as plain strings, the form
+// engine.ExecutionInput.Permissions takes.
+func PermissionNames(perms []Permission) []string {
+	out := make([]string, len(perms))
+	for i, p := range perms {
+		out[i] = string(p)
+	}
+	return out
+}
+
+// HasPermission reports whether s declares p in its header.
+func (s Script) HasPermission(p Permission) bool {
+	return slices.Contains(s.Permissions, p)
+}
+
+// ContentHash returns the hex SHA-256 of content, identifying one exact
+// version of a script.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// TrustStore records which user script versions the user has approved to
+// run, and with which elevated permissions. Approvals are keyed by content
+// hash, so editing a script — or replacing it with a different one under the
+// same file name — asks again. Built-in scripts are always trusted and
+// granted what they declare. It is not safe for concurrent use.
+type TrustStore struct {
+	path   string
+	grants map[string][]Permission // content hash → approved permissions
+}
+
+// LoadTrustStore reads approvals from path. A missing or unreadable file
+// yields no approvals; it is created on the first Grant call.
+func LoadTrustStore(path string) *TrustStore {
+	t := &TrustStore{path: path, grants: map[string][]Permission{}}
+	if data, err := os.ReadFile(path); err == nil {
+		if json.Unmarshal(data, &t.grants) != nil || t.grants == nil {
+			t.grants = map[string][]Permission{}
+		}
+	}
+	return t
+}
+
+// hash returns the key s is approved under: the hash recorded by the loader,
+// or one computed from Content for scripts built elsewhere.
+func hash(s Script) string {
+	if s.Hash != "" {
+		return s.Hash
+	}
+	return ContentHash(s.Content)
+}
+
+// Approved reports whether s may run: built-ins always may, user scripts only
+// once the user has approved this exact version.
+func (t *TrustStore) Approved(s Script) bool {
+	if s.Source == BuiltIn {
+		return true
+	}
+	_, ok := t.grants[hash(s)]
+	return ok
+}
+
+// Granted reports whether s may use p: it must declare p and p must either be
+// non-elevated, or s must be a built-in or approved by the user.
+func (t *TrustStore) Granted(s Script, p Permission) bool {
+	if !s.HasPermission(p) {
+		return false
+	}
+	if !p.Elevated() || s.Source == BuiltIn {
+		return true
+	}
+	return slices.Contains(t.grants[hash(s)], p)
+}
+
+// GrantedPermissions returns the declared permissions s may use, in header
+// order.
+func (t *TrustStore) GrantedPermissions(s Script) []Permission {
+	var out []Permission
+	for _, p := range s.Permissions {
+		if t.Granted(s, p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Pending returns the elevated permissions s declares that the user has not
+// approved yet, in header order.
+func (t *TrustStore) Pending(s Script) []Permission {
+	var out []Permission
+	for _, p := range s.Permissions {
+		if !t.Granted(s, p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Grant approves the current version of s to run with every permission it
+// declares and writes the store to disk.
+func (t *TrustStore) Grant(s Script) error {
+	perms := slices.Clone(s.Permissions)
+	if perms == nil {
+		perms = []Permission{} // stored as [], still an approval
+	}
+	t.grants[hash(s)] = perms
+	return t.save()
+}
+
+func (t *TrustStore) save() error {
+	data, err := json.MarshalIndent(t.grants, "", "  ")
+	if err != nil {
+		return fmt.Errorf("trust: marshal: %w", err)
+	}
+	dir := filepath.Dir(t.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("trust: create dir: %w", err)
+	}
+	// Write to a temp file and rename it into place, so a crash mid-write
+	// cannot truncate the store and drop every approval.
+	tmp, err := os.CreateTemp(dir, ".trust-*.tmp")
+	if err != nil {
+		return fmt.Errorf("trust: create temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }() // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("trust: write: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("trust: sync: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("trust: close: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), t.path); err != nil {
+		return fmt.Errorf("trust: rename: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+// clipboardReadTimeout bounds how long a script waits for the clipboard
+// owner to hand over its contents.
+const clipboardReadTimeout = 2 * time.Second
+
+// gdkClipboard implements engine.Clipboard on top of a GDK clipboard. The
+// executor calls it from its own goroutine, so every GDK call is marshalled
+// to the GTK main thread with glib.IdleAdd.
+type gdkClipboard struct {
+	clip *gdk.Clipboard
+}
+
+// Read returns the clipboard text, blocking until GDK delivers it.
+func (c gdkClipboard) Read() (string, error) {
+	type reply struct {
+		text string
+		err  error
+	}
+	ch := make(chan reply, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), clipboardReadTimeout)
+	defer cancel()
+
+	glib.IdleAdd(func() {
+		c.clip.ReadTextAsync(ctx, func(res gio.AsyncResulter) {
+			text, err := c.clip.ReadTextFinish(res)
+			ch <- reply{text, err}
+		})
+	})
+
+	select {
+	case r := <-ch:
+		return r.text, r.err
+	case <-ctx.Done():
+		return "", errors.New("timed out waiting for clipboard contents")
+	}
+}
+
+// Write replaces the clipboard contents with text.
+func (c gdkClipboard) Write(text string) error {
+	glib.IdleAdd(func() { c.clip.SetText(text) })
+	return nil
+}
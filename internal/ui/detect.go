@@ -3,8 +3,10 @@ package ui
 import (
 	"encoding/json"
 	"encoding/xml"
+	"regexp"
 	"strings"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,7 +20,7 @@ const maxDetectBytes = 4 * 1024 * 1024 // 4 MB
 // first gate, and a stdlib parse validates the candidate before the result is
 // accepted. Returns ("", "") when no format can be identified with confidence.
 //
-// Supported auto-detection: JSON, HTML, XML, YAML.
+// Supported auto-detection: JSON, HTML, XML, TOML, YAML.
 // SQL and Markdown are excluded — they lack reliable detection heuristics that
 // satisfy the zero-false-positive requirement.
 func Detect(content string) (langID, langName string) {
@@ -35,6 +37,8 @@ func Detect(content string) (langID, langName string) {
 		return "json", "JSON"
 	case isXML(content):
 		return "xml", "XML"
+	case isTOML(content):
+		return "toml", "TOML"
 	case isYAML(content):
 		return "yaml", "YAML"
 	}
@@ -90,6 +94,21 @@ func isYAML(s string) bool {
 	return yaml.Unmarshal([]byte(s), &v) == nil && v != nil
 }
 
+// tomlLineRE matches a TOML table header ([table] or [[array.of.tables]]) or
+// a key = value pair with a bare, dotted or quoted key.
+var tomlLineRE = regexp.MustCompile(`^(\[\[?\s*[A-Za-z0-9_.\-"' ]+\s*\]\]?|[A-Za-z0-9_.\-"']+\s*=\s*\S)`)
+
+// isTOML returns true when the first line that is not a comment is a TOML
+// table header or key = value pair, and the TOML decoder accepts content.
+func isTOML(s string) bool {
+	if !tomlLineRE.MatchString(firstNonCommentLine(s)) {
+		return false
+	}
+	var v map[string]any
+	_, err := toml.Decode(s, &v)
+	return err == nil && len(v) > 0
+}
+
 // looksLikeYAMLLine returns true when line is a plausible YAML mapping entry:
 // a simple key (no internal spaces or slashes) followed by ':' at end of line
 // or by ': ' / ':\t' (key-value pair).
@@ -122,6 +141,20 @@ func firstNonEmptyLine(s string) string {
 	return ""
 }
 
+// firstNonCommentLine returns the first line of s that is neither blank nor a
+// # comment, scanning at most the first ten lines.
+func firstNonCommentLine(s string) string {
+	for i, line := range strings.SplitN(s, "\n", 11) {
+		if i == 10 {
+			break
+		}
+		if trimmed := strings.TrimSpace(line); trimmed != "" && trimmed[0] != '#' {
+			return trimmed
+		}
+	}
+	return ""
+}
+
 // isLetter reports whether b is an ASCII letter.
 func isLetter(b byte) bool {
 	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
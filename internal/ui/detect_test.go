@@ -35,6 +35,14 @@ func TestDetect(t *testing.T) {
 		{"yaml mapping", "key: value\n", "yaml", "YAML"},
 		// YAML: nested
 		{"yaml nested", "outer:\n  inner: 42\n", "yaml", "YAML"},
+		// TOML: table header
+		{"toml table", "[package]\nname = \"goop\"\nversion = \"0.1.0\"\n", "toml", "TOML"},
+		// TOML: key/value pairs after a comment
+		{"toml pairs", "# Hugo config\nbaseURL = 'https://example.org/'\ntitle = \"Blog\"\n", "toml", "TOML"},
+		// TOML: array of tables
+		{"toml array of tables", "[[bin]]\nname = \"goop\"\n", "toml", "TOML"},
+		// No match: equation that is not valid TOML
+		{"toml invalid", "x = y + 1", "", ""},
 		// No match: plain text
 		{"plain text", "hello world", "", ""},
 		// No match: empty string
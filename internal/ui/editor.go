@@ -1,6 +1,9 @@
 package ui
 
 import (
+	"encoding/hex"
+	"fmt"
+
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 	gtksource "libdb.so/gotk4-sourceview/pkg/gtksource/v5"
 )
@@ -10,8 +13,17 @@ import (
 type Editor struct {
 	View   *gtksource.View
 	buffer *gtksource.Buffer
+
+	// data is the binary payload shown as a hex dump; nil while the buffer
+	// holds ordinary text. Any edit to the buffer turns it back into text.
+	data        []byte
+	settingData bool
 }
 
+// maxHexDumpBytes caps how much of a binary payload is rendered as a hex
+// dump; the payload itself is kept whole.
+const maxHexDumpBytes = 1 << 20 // 1 MB
+
 // NewEditor creates and initialises an Editor widget.
 func NewEditor() *Editor {
 	buf := gtksource.NewBuffer(nil)
@@ -33,7 +45,33 @@ func NewEditor() *Editor {
 	view.SetLeftMargin(24)
 	view.SetRightMargin(24)
 
-	return &Editor{View: view, buffer: buf}
+	e := &Editor{View: view, buffer: buf}
+	buf.ConnectChanged(func() {
+		if !e.settingData {
+			e.data = nil
+		}
+	})
+	return e
+}
+
+// SetBytes replaces the document with a binary payload, shown as a hex dump.
+// Bytes returns it until the buffer is next changed.
+func (e *Editor) SetBytes(data []byte) {
+	dump := hex.Dump(data[:min(len(data), maxHexDumpBytes)])
+	if len(data) > maxHexDumpBytes {
+		dump += fmt.Sprintf("… %d more bytes not shown\n", len(data)-maxHexDumpBytes)
+	}
+	e.settingData = true
+	e.buffer.SetText(dump)
+	e.settingData = false
+	e.data = data
+	e.ClearLanguage()
+}
+
+// Bytes returns the binary payload the editor holds, or nil when it holds
+// text.
+func (e *Editor) Bytes() []byte {
+	return e.data
 }
 
 // GetFullText returns the complete text currently in the buffer.
@@ -72,6 +110,17 @@ func (e *Editor) GetSelection() (start, end int) {
 	return startIter.Offset(), endIter.Offset()
 }
 
+// Select selects the characters between the 0-based offsets start and end,
+// leaving the cursor at end, and scrolls it into view. start == end places the
+// cursor without a selection. Offsets beyond the buffer are clamped to its end.
+func (e *Editor) Select(start, end int) {
+	n := e.buffer.CharCount()
+	start = min(max(start, 0), n)
+	end = min(max(end, 0), n)
+	e.buffer.SelectRange(e.buffer.IterAtOffset(end), e.buffer.IterAtOffset(start))
+	e.View.ScrollMarkOnscreen(e.buffer.GetInsert())
+}
+
 // ReplaceSelection replaces the current selection with text, or replaces the
 // full document if nothing is selected.
 func (e *Editor) ReplaceSelection(text string) {
@@ -131,6 +180,24 @@ func (e *Editor) SetLanguage(langID string) {
 	}
 }
 
+// Language returns the ID of the language currently highlighted, or "" when
+// highlighting is off. Must be called on the GTK main thread.
+func (e *Editor) Language() string {
+	if lang := e.buffer.Language(); lang != nil {
+		return lang.ID()
+	}
+	return ""
+}
+
+// LanguageName returns the display name of the GtkSourceView language langID,
+// or "" when no such language is installed.
+func LanguageName(langID string) string {
+	if lang := gtksource.LanguageManagerGetDefault().Language(langID); lang != nil {
+		return lang.Name()
+	}
+	return ""
+}
+
 // ClearLanguage removes all syntax highlighting from the editor buffer.
 // Safe to call when no language is active. Must be called on the GTK main thread.
 func (e *Editor) ClearLanguage() {
@@ -0,0 +1,135 @@
+package ui
+
+import (
+	"codeberg.org/sigterm-de/goop/internal/scripts"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// ShowParamDialog opens a modal form asking for the values of the parameters
+// declared by s. initial pre-fills the form (typically the last-used values).
+// onRun is called on the GTK main thread with the raw values once the user
+// confirms and every value is valid for its type; cancelling calls nothing.
+func ShowParamDialog(parent *gtk.Window, s scripts.Script, initial map[string]string, onRun func(map[string]string)) {
+	win := gtk.NewWindow()
+	win.SetTitle(s.Name)
+	if parent != nil {
+		win.SetTransientFor(parent)
+	}
+	win.SetModal(true)
+	win.SetDefaultSize(380, 0)
+	win.SetResizable(false)
+	win.SetDestroyWithParent(true)
+
+	grid := gtk.NewGrid()
+	grid.SetRowSpacing(10)
+	grid.SetColumnSpacing(12)
+	grid.SetMarginTop(20)
+	grid.SetMarginBottom(12)
+	grid.SetMarginStart(20)
+	grid.SetMarginEnd(20)
+
+	errLabel := gtk.NewLabel("")
+	errLabel.SetXAlign(0)
+	errLabel.SetWrap(true)
+	errLabel.AddCSSClass("error")
+	errLabel.SetVisible(false)
+
+	// Each parameter contributes a getter returning its current raw value.
+	getters := make(map[string]func() string, len(s.Params))
+	var first gtk.Widgetter
+
+	submit := func() {
+		values := make(map[string]string, len(s.Params))
+		for _, p := range s.Params {
+			raw := getters[p.Name]()
+			if _, err := p.Value(raw); err != nil {
+				errLabel.SetText(err.Error())
+				errLabel.SetVisible(true)
+				return
+			}
+			values[p.Name] = raw
+		}
+		win.Close()
+		onRun(values)
+	}
+
+	for row, p := range s.Params {
+		val, ok := initial[p.Name]
+		if !ok {
+			val = p.Default
+		}
+
+		if p.Type == scripts.ParamBool {
+			check := gtk.NewCheckButtonWithLabel(p.Label)
+			check.SetActive(val == "true")
+			getters[p.Name] = func() string {
+				if check.Active() {
+					return "true"
+				}
+				return "false"
+			}
+			grid.Attach(check, 1, row, 1, 1)
+			if first == nil {
+				first = check
+			}
+			continue
+		}
+
+		lbl := gtk.NewLabel(p.Label + ":")
+		lbl.SetXAlign(1)
+		entry := gtk.NewEntry()
+		entry.SetText(val)
+		entry.SetHExpand(true)
+		if p.Type == scripts.ParamNumber {
+			entry.SetInputPurpose(gtk.InputPurposeNumber)
+		}
+		entry.ConnectActivate(submit)
+		getters[p.Name] = entry.Text
+		grid.Attach(lbl, 0, row, 1, 1)
+		grid.Attach(entry, 1, row, 1, 1)
+		if first == nil {
+			first = entry
+		}
+	}
+
+	cancelBtn := gtk.NewButtonWithLabel("Cancel")
+	cancelBtn.ConnectClicked(func() { win.Close() })
+	runBtn := gtk.NewButtonWithLabel("Run")
+	runBtn.AddCSSClass("suggested-action")
+	runBtn.ConnectClicked(submit)
+
+	buttons := gtk.NewBox(gtk.OrientationHorizontal, 8)
+	buttons.SetHAlign(gtk.AlignEnd)
+	buttons.SetMarginTop(8)
+	buttons.SetMarginEnd(20)
+	buttons.SetMarginBottom(16)
+	buttons.Append(cancelBtn)
+	buttons.Append(runBtn)
+
+	errLabel.SetMarginStart(20)
+	errLabel.SetMarginEnd(20)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 0)
+	box.Append(grid)
+	box.Append(errLabel)
+	box.Append(buttons)
+
+	// Close on Escape, like the preferences window.
+	keyCtrl := gtk.NewEventControllerKey()
+	keyCtrl.SetPropagationPhase(gtk.PhaseCapture)
+	keyCtrl.ConnectKeyPressed(func(keyval, _ uint, _ gdk.ModifierType) bool {
+		if keyval == gdk.KEY_Escape {
+			win.Close()
+			return true
+		}
+		return false
+	})
+	win.AddController(keyCtrl)
+
+	win.SetChild(box)
+	win.Present()
+	if first != nil {
+		gtk.BaseWidget(first).GrabFocus()
+	}
+}
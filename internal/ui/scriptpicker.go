@@ -2,6 +2,9 @@ package ui
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"codeberg.org/sigterm-de/goop/internal/engine"
 	"codeberg.org/sigterm-de/goop/internal/logging"
@@ -21,26 +24,40 @@ type ScriptPicker struct {
 	editor  *Editor
 	status  *StatusBar
 	logPath string
+	params  *scripts.ParamMemory
+	trust   *scripts.TrustStore
+	locked  bool   // refuse unapproved user scripts instead of asking
+	storage string // directory holding per-script state.storage files
+	libDir  string // directory of @user/ modules
 
 	listBox     *gtk.ListBox
 	searchEntry *gtk.SearchEntry
 	allScripts  []scripts.Script
 	onHide      func()
-	postScript  func() // called after every successful script execution
+	postScript  func(lang string) // called after every successful script execution
 }
 
 // NewScriptPicker creates the script picker panel.
+// params remembers the values entered in the parameter form between runs;
+// trust records which user scripts and @permissions the user has approved;
+// storageDir is where each script's state.storage file lives and libDir holds
+// the modules scripts load with require('@user/...').
 // postScript, if non-nil, is called on the GTK main thread after every
-// successful script execution — use it to run syntax detection or other
-// post-transform work without coupling ScriptPicker to those details.
+// successful script execution with the language the script declared for its
+// output, if any — use it to run syntax detection or other post-transform
+// work without coupling ScriptPicker to those details.
 func NewScriptPicker(
 	lib scripts.Library,
 	exec engine.Executor,
 	editor *Editor,
 	status *StatusBar,
 	logPath string,
+	params *scripts.ParamMemory,
+	trust *scripts.TrustStore,
+	storageDir string,
+	libDir string,
 	onHide func(),
-	postScript func(),
+	postScript func(lang string),
 ) *ScriptPicker {
 	sp := &ScriptPicker{
 		library:    lib,
@@ -48,6 +65,10 @@ func NewScriptPicker(
 		editor:     editor,
 		status:     status,
 		logPath:    logPath,
+		params:     params,
+		trust:      trust,
+		storage:    storageDir,
+		libDir:     libDir,
 		allScripts: lib.All(),
 		onHide:     onHide,
 		postScript: postScript,
@@ -71,6 +92,7 @@ func NewScriptPicker(
 	// Key controller on the search entry: intercept Down (move to list) and
 	// Enter (run first/selected script). ConnectNextMatch fires on Ctrl+G,
 	// not Down arrow, so we need an explicit controller here.
+	sp.searchEntry.SetTooltipText("Enter runs the script · Shift+Enter runs it on each line · Ctrl+Enter on each blank-line separated block")
 	searchCtrl := gtk.NewEventControllerKey()
 	searchCtrl.ConnectKeyPressed(func(keyval, keycode uint, state gdk.ModifierType) bool {
 		switch keyval {
@@ -78,7 +100,7 @@ func NewScriptPicker(
 			sp.focusList()
 			return true
 		case gdk.KEY_Return, gdk.KEY_KP_Enter:
-			sp.activateSelected()
+			sp.activateSelected(eachMode(state))
 			return true
 		}
 		return false
@@ -94,7 +116,7 @@ func NewScriptPicker(
 		if idx < 0 || idx >= len(sp.allScripts) {
 			return
 		}
-		sp.runScript(sp.allScripts[idx])
+		sp.runScript(sp.allScripts[idx], engine.EachNone)
 	})
 
 	// Key controller on the list in PhaseCapture so we intercept Up/Down
@@ -128,7 +150,7 @@ func NewScriptPicker(
 			}
 			return true
 		case gdk.KEY_Return, gdk.KEY_KP_Enter:
-			sp.activateSelected()
+			sp.activateSelected(eachMode(state))
 			return true
 		case gdk.KEY_Escape:
 			if sp.onHide != nil {
@@ -184,12 +206,17 @@ func (sp *ScriptPicker) setScripts(list []scripts.Script) {
 	}
 
 	for _, s := range list {
-		sp.listBox.Append(buildScriptRow(s))
+		var onRunFile func()
+		if s.Streaming {
+			onRunFile = func() { sp.runScriptOnFile(s) }
+		}
+		sp.listBox.Append(buildScriptRow(s, onRunFile))
 	}
 }
 
-// buildScriptRow creates a single list row for a script.
-func buildScriptRow(s scripts.Script) *gtk.Box {
+// buildScriptRow creates a single list row for a script. onRunFile, if
+// non-nil, adds a button that runs the script on a file.
+func buildScriptRow(s scripts.Script, onRunFile func()) *gtk.Box {
 	nameLabel := gtk.NewLabel(s.Name)
 	nameLabel.SetXAlign(0)
 	nameLabel.AddCSSClass("script-name")
@@ -204,6 +231,21 @@ func buildScriptRow(s scripts.Script) *gtk.Box {
 	textBox.Append(nameLabel)
 	textBox.Append(descLabel)
 
+	if len(s.Permissions) > 0 {
+		names := make([]string, len(s.Permissions))
+		descs := make([]string, len(s.Permissions))
+		for i, p := range s.Permissions {
+			names[i] = string(p)
+			descs[i] = p.Description()
+		}
+		permLabel := gtk.NewLabel("Permissions: " + strings.Join(names, ", "))
+		permLabel.SetXAlign(0)
+		permLabel.AddCSSClass("script-permissions")
+		permLabel.SetEllipsize(pango.EllipsizeEnd)
+		permLabel.SetTooltipText(strings.Join(descs, "\n"))
+		textBox.Append(permLabel)
+	}
+
 	row := gtk.NewBox(gtk.OrientationHorizontal, 8)
 	row.SetMarginTop(6)
 	row.SetMarginBottom(6)
@@ -223,6 +265,15 @@ func buildScriptRow(s scripts.Script) *gtk.Box {
 		row.Append(badge)
 	}
 
+	if onRunFile != nil {
+		fileBtn := gtk.NewButtonFromIconName("document-open-symbolic")
+		fileBtn.AddCSSClass("flat")
+		fileBtn.SetVAlign(gtk.AlignCenter)
+		fileBtn.SetTooltipText("Run on a file…")
+		fileBtn.ConnectClicked(onRunFile)
+		row.Append(fileBtn)
+	}
+
 	return row
 }
 
@@ -301,8 +352,8 @@ func (sp *ScriptPicker) focusList() {
 }
 
 // activateSelected runs the currently selected script, or the first script
-// if nothing is selected.
-func (sp *ScriptPicker) activateSelected() {
+// if nothing is selected, on the selection as a whole or one unit at a time.
+func (sp *ScriptPicker) activateSelected(each engine.EachMode) {
 	row := sp.listBox.SelectedRow()
 	if row == nil {
 		row = sp.listBox.RowAtIndex(0)
@@ -312,40 +363,127 @@ func (sp *ScriptPicker) activateSelected() {
 	}
 	idx := row.Index()
 	if idx >= 0 && idx < len(sp.allScripts) {
-		sp.runScript(sp.allScripts[idx])
+		sp.runScript(sp.allScripts[idx], each)
 	}
 }
 
+// eachMode returns how Enter with the given modifiers runs a script: once per
+// line with Shift, once per block with Ctrl, otherwise once.
+func eachMode(state gdk.ModifierType) engine.EachMode {
+	switch {
+	case state&gdk.ControlMask != 0:
+		return engine.EachBlock
+	case state&gdk.ShiftMask != 0:
+		return engine.EachLine
+	}
+	return engine.EachNone
+}
+
 // Reset clears the search and restores the full script list.
 func (sp *ScriptPicker) Reset() {
 	sp.searchEntry.SetText("")
 	sp.setScripts(sp.library.All()) // also sets sp.allScripts internally
 }
 
+// parentWindow returns the toplevel window containing the picker, for use as
+// the transient parent of dialogs. Returns nil before the picker is realised.
+func (sp *ScriptPicker) parentWindow() *gtk.Window {
+	root := sp.Box.Root()
+	if root == nil {
+		return nil
+	}
+	switch w := root.Cast().(type) {
+	case *gtk.ApplicationWindow:
+		return &w.Window
+	case *gtk.Window:
+		return w
+	}
+	return nil
+}
+
 // runScript executes the given script against the current editor content.
-// The execution runs in a goroutine; results are marshalled back to the GTK
-// main thread via glib.IdleAdd.
-func (sp *ScriptPicker) runScript(s scripts.Script) {
+// A user script that is new or changed since it was last approved is first
+// shown for confirmation — or refused outright in locked mode — and the
+// script then asks for its @param values when it declares any. each selects
+// whether it runs on the selection as a whole or once per line or block.
+func (sp *ScriptPicker) runScript(s scripts.Script, each engine.EachMode) {
+	sp.approve(s, func() {
+		sp.askParams(s, func(s scripts.Script, params map[string]any) { sp.execute(s, params, each) })
+	})
+}
+
+// runScriptOnFile streams a file through the given streaming script into
+// another file, after the same confirmation and parameter steps as runScript.
+func (sp *ScriptPicker) runScriptOnFile(s scripts.Script) {
+	sp.approve(s, func() { sp.askParams(s, sp.chooseStreamFiles) })
+}
+
+// approve hides the picker and calls run once s may run: at once for
+// approved scripts, after confirmation for new or changed user scripts, never
+// for those in locked mode.
+func (sp *ScriptPicker) approve(s scripts.Script, run func()) {
 	if sp.onHide != nil {
 		sp.onHide()
 	}
 
-	sp.editor.SetEnabled(false)
-	sp.status.SetBusy(true)
+	if sp.trust.Approved(s) {
+		run()
+		return
+	}
+	if sp.locked {
+		msg := fmt.Sprintf("%s has not been approved; turn off locked mode in Preferences to review it", s.Name)
+		logging.Log(logging.WARN, s.Name, msg)
+		sp.status.ShowError(msg, "")
+		return
+	}
+	ShowTrustDialog(sp.parentWindow(), s, sp.trust.Pending(s), func() {
+		if err := sp.trust.Grant(s); err != nil {
+			logging.Log(logging.WARN, s.Name, err.Error())
+		}
+		run()
+	})
+}
 
-	fullText := sp.editor.GetFullText()
-	selText := sp.editor.GetSelectedText()
-	selStart, selEnd := sp.editor.GetSelection()
+// SetLocked switches locked mode, in which user scripts that have not been
+// approved are refused instead of shown for confirmation.
+func (sp *ScriptPicker) SetLocked(locked bool) {
+	sp.locked = locked
+}
 
-	inp := engine.ExecutionInput{
-		ScriptSource:   s.Content,
-		ScriptName:     s.Name,
-		FullText:       fullText,
-		SelectionText:  selText,
-		SelectionStart: selStart,
-		SelectionEnd:   selEnd,
-		Timeout:        5e9, // 5 seconds
+// askParams calls run with s and its @param values, first asking for them
+// when it declares any.
+func (sp *ScriptPicker) askParams(s scripts.Script, run func(scripts.Script, map[string]any)) {
+	if len(s.Params) == 0 {
+		run(s, nil)
+		return
 	}
+	ShowParamDialog(sp.parentWindow(), s, sp.params.Values(s), func(raw map[string]string) {
+		if err := sp.params.Remember(s, raw); err != nil {
+			logging.Log(logging.WARN, s.Name, err.Error())
+		}
+		values, err := scripts.ResolveParams(s.Params, raw)
+		if err != nil {
+			sp.status.ShowError(err.Error(), "")
+			return
+		}
+		run(s, values)
+	})
+}
+
+// execute runs the script with the given parameter values. The execution runs
+// in a goroutine; results are marshalled back to the GTK main thread via
+// glib.IdleAdd.
+func (sp *ScriptPicker) execute(s scripts.Script, params map[string]any, each engine.EachMode) {
+	sp.editor.SetEnabled(false)
+	sp.status.SetBusy(true)
+
+	inp := sp.input(s, params)
+	inp.FullText = sp.editor.GetFullText()
+	inp.SelectionText = sp.editor.GetSelectedText()
+	inp.SelectionStart, inp.SelectionEnd = sp.editor.GetSelection()
+	inp.Data = sp.editor.Bytes()
+	inp.Language = sp.editor.Language()
+	inp.Each = each
 
 	go func() {
 		result := sp.exec.Execute(context.Background(), inp)
@@ -358,11 +496,63 @@ func (sp *ScriptPicker) runScript(s scripts.Script) {
 	}()
 }
 
+// input returns the ExecutionInput for running s with params, apart from the
+// document it runs on.
+func (sp *ScriptPicker) input(s scripts.Script, params map[string]any) engine.ExecutionInput {
+	return engine.ExecutionInput{
+		ScriptSource:   s.Content,
+		ScriptName:     s.Name,
+		Timeout:        5e9, // 5 seconds
+		OutputLanguage: s.Output,
+		Pure:           s.Pure,
+		LibDir:         sp.libDir,
+		Params:         params,
+		Permissions:    scripts.PermissionNames(sp.trust.GrantedPermissions(s)),
+		Storage:        engine.NewStorage(sp.storage, s.FilePath, s.Name),
+		Clipboard:      gdkClipboard{clip: sp.Box.Clipboard()},
+		Scripts:        sp.scriptResolver(),
+		Progress: func(fraction float64, message string) {
+			glib.IdleAdd(func() { sp.status.SetProgress(fraction, message) })
+		},
+	}
+}
+
+// scriptResolver returns the resolver for goop.run() calls. Approval is
+// decided up front on the GTK main thread, since the trust store is not safe
+// for concurrent use. Unapproved user scripts cannot be called: there is no
+// way to ask about them while another script is running.
+func (sp *ScriptPicker) scriptResolver() engine.ScriptResolver {
+	all := sp.library.All() // sp.allScripts only holds the current search results
+	approved := make([]bool, len(all))
+	for i, s := range all {
+		approved[i] = sp.trust.Approved(s)
+	}
+	return func(name string, raw map[string]string) (engine.NestedScript, error) {
+		for i, s := range all {
+			if !strings.EqualFold(s.Name, name) {
+				continue
+			}
+			if !approved[i] {
+				return engine.NestedScript{}, fmt.Errorf("%s has not been approved; run it from the picker once to review it", s.Name)
+			}
+			values, err := scripts.ResolveParams(s.Params, raw)
+			if err != nil {
+				return engine.NestedScript{}, fmt.Errorf("%s: %w", s.Name, err)
+			}
+			return engine.NestedScript{Name: s.Name, Source: s.Content, Params: values}, nil
+		}
+		return engine.NestedScript{}, fmt.Errorf("no script named %q", name)
+	}
+}
+
 // applyResult applies the execution result to the editor and status bar.
 func (sp *ScriptPicker) applyResult(result engine.ExecutionResult) {
 	if !result.Success {
-		logging.Log(logging.ERROR, result.ScriptName, result.ErrorMessage)
-		sp.status.ShowError(result.ErrorMessage, sp.logPath)
+		msg := result.ErrorMessage
+		if len(result.Failures) > 0 {
+			msg = result.ScriptName + " " + msg // "failed on 3 of 3 lines; …"
+		}
+		sp.status.ShowError(msg, sp.logPath)
 		return
 	}
 
@@ -373,15 +563,29 @@ func (sp *ScriptPicker) applyResult(result engine.ExecutionResult) {
 		sp.editor.ReplaceSelection(result.NewText)
 	case engine.MutationInsertAtCursor:
 		sp.editor.InsertAtCursor(result.InsertText)
+	case engine.MutationReplaceBytes:
+		// Bytes that decode as text are shown as text.
+		if utf8.Valid(result.NewBytes) {
+			sp.editor.SetFullText(string(result.NewBytes))
+		} else {
+			sp.editor.SetBytes(result.NewBytes)
+		}
+	}
+
+	// Selection requests refer to the document after the mutation above.
+	if result.SelectionSet {
+		sp.editor.Select(result.NewSelectionStart, result.NewSelectionEnd)
 	}
 
-	if result.InfoMessage != "" {
+	if len(result.Failures) > 0 {
+		sp.status.ShowError(result.ScriptName+" "+result.InfoMessage, sp.logPath)
+	} else if result.InfoMessage != "" {
 		sp.status.ShowSuccess(result.InfoMessage)
 	} else {
 		sp.status.ShowSuccess(result.ScriptName + " applied")
 	}
 
 	if sp.postScript != nil {
-		sp.postScript()
+		sp.postScript(result.Language)
 	}
 }
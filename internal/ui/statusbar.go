@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"fmt"
+
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 	"github.com/diamondburned/gotk4/pkg/pango"
@@ -16,12 +18,13 @@ const successRevertDelay = 5000
 // StatusBar displays transformation results and the idle usage hint at the
 // bottom of the application window. It contains three independent zones:
 //   - notification zone (left): transient event messages that auto-revert
-//   - spinner (centre-right): animates while a script is executing
+//   - spinner and progress bar (centre-right): shown while a script is executing
 //   - syntax zone (right): persistent detected-language indicator
 type StatusBar struct {
 	Box         *gtk.Box
 	label       *gtk.Label        // notification zone
 	spinner     *gtk.Spinner      // busy indicator
+	progress    *gtk.ProgressBar  // shown once a running script reports progress
 	syntaxLabel *gtk.Label        // syntax zone — right-aligned, empty when inactive
 	timerTag    glib.SourceHandle // 0 when no timer is pending
 	idleText    string
@@ -45,6 +48,14 @@ func NewStatusBar() *StatusBar {
 	spinner.SetMarginEnd(4)
 	spinner.SetVisible(false)
 
+	// Progress bar — hidden until the running script calls state.progress().
+	progress := gtk.NewProgressBar()
+	progress.SetShowText(true)
+	progress.SetVAlign(gtk.AlignCenter)
+	progress.SetMarginStart(6)
+	progress.SetVisible(false)
+	progress.AddCSSClass("statusbar-progress")
+
 	// Syntax zone — right-aligned, shows the detected language name when active.
 	syntaxLabel := gtk.NewLabel("")
 	syntaxLabel.SetXAlign(1)
@@ -60,12 +71,14 @@ func NewStatusBar() *StatusBar {
 	box.SetMarginEnd(12)
 	box.Append(label)
 	box.Append(spinner)
+	box.Append(progress)
 	box.Append(syntaxLabel)
 
 	return &StatusBar{
 		Box:         box,
 		label:       label,
 		spinner:     spinner,
+		progress:    progress,
 		syntaxLabel: syntaxLabel,
 		idleText:    defaultIdleText,
 		isIdle:      true,
@@ -82,7 +95,22 @@ func (s *StatusBar) SetBusy(busy bool) {
 	} else {
 		s.spinner.Stop()
 		s.spinner.SetVisible(false)
+		s.progress.SetVisible(false)
+		s.progress.SetFraction(0)
+	}
+}
+
+// SetProgress shows the running script's progress: fraction from 0 to 1 and
+// an optional message, shown as the bar's text alongside the percentage.
+// SetBusy(false) hides it again. Must be called on the GTK main thread.
+func (s *StatusBar) SetProgress(fraction float64, message string) {
+	text := fmt.Sprintf("%.0f%%", fraction*100)
+	if message != "" {
+		text = message + " — " + text
 	}
+	s.progress.SetFraction(fraction)
+	s.progress.SetText(text)
+	s.progress.SetVisible(true)
 }
 
 // SetSyntaxLanguage shows the detected language name in the right-aligned
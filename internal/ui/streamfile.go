@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+	"codeberg.org/sigterm-de/goop/internal/logging"
+	"codeberg.org/sigterm-de/goop/internal/scripts"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// streamProgressInterval throttles progress updates while a file streams.
+const streamProgressInterval = 100 * time.Millisecond
+
+// chooseStreamFiles asks for an input file and then for where to save the
+// output, and streams one into the other through s. Dismissing either dialog
+// cancels the run.
+func (sp *ScriptPicker) chooseStreamFiles(s scripts.Script, params map[string]any) {
+	openDlg := gtk.NewFileDialog()
+	openDlg.SetTitle("Run " + s.Name + " on a file")
+	openDlg.Open(context.Background(), sp.parentWindow(), func(res gio.AsyncResulter) {
+		in, err := openDlg.OpenFinish(res)
+		if err != nil || in.Path() == "" {
+			return
+		}
+		inPath := in.Path()
+
+		saveDlg := gtk.NewFileDialog()
+		saveDlg.SetTitle("Save " + s.Name + " output")
+		saveDlg.SetInitialName(filepath.Base(inPath))
+		saveDlg.Save(context.Background(), sp.parentWindow(), func(res gio.AsyncResulter) {
+			out, err := saveDlg.SaveFinish(res)
+			if err != nil || out.Path() == "" {
+				return
+			}
+			sp.streamFile(s, params, inPath, out.Path())
+		})
+	})
+}
+
+// streamFile runs s over the file at inPath line by line, writing to outPath.
+// Output goes to a temporary file beside outPath that replaces it only once
+// the whole input has streamed, so a failed run leaves outPath untouched.
+func (sp *ScriptPicker) streamFile(s scripts.Script, params map[string]any, inPath, outPath string) {
+	if same, _ := sameFile(inPath, outPath); same {
+		sp.status.ShowError("Choose a different file for the output: "+s.Name+" cannot overwrite its input", "")
+		return
+	}
+
+	inp := sp.input(s, params)
+	inp.Progress = nil // progress is reported from the bytes read instead
+
+	sp.status.SetBusy(true)
+	go func() {
+		res, err := streamFileThrough(sp.exec, inp, inPath, outPath, func(fraction float64) {
+			glib.IdleAdd(func() { sp.status.SetProgress(fraction, filepath.Base(inPath)) })
+		})
+
+		glib.IdleAdd(func() {
+			sp.status.SetBusy(false)
+			if err != nil {
+				logging.Log(logging.ERROR, s.Name, err.Error())
+				sp.status.ShowError(err.Error(), sp.logPath)
+				return
+			}
+			if !res.Success {
+				sp.status.ShowError(res.ErrorMessage, sp.logPath) // logged by the executor
+				return
+			}
+			if res.InfoMessage != "" {
+				sp.status.ShowSuccess(res.InfoMessage)
+				return
+			}
+			sp.status.ShowSuccess(fmt.Sprintf("%s applied to %s → %s",
+				s.Name, filepath.Base(inPath), filepath.Base(outPath)))
+		})
+	}()
+}
+
+// streamFileThrough executes inp with the file at inPath as its stream input
+// and outPath as its output, calling progress with the fraction of the input
+// read so far.
+func streamFileThrough(exec engine.Executor, inp engine.ExecutionInput, inPath, outPath string,
+	progress func(float64)) (engine.ExecutionResult, error) {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return engine.ExecutionResult{}, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return engine.ExecutionResult{}, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(outPath), "."+filepath.Base(outPath)+".*")
+	if err != nil {
+		return engine.ExecutionResult{}, fmt.Errorf("create output: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // a no-op once renamed into place
+
+	inp.Stream = &engine.StreamIO{
+		In:  &progressReader{r: in, size: info.Size(), report: progress},
+		Out: tmp,
+	}
+	res := exec.Execute(context.Background(), inp)
+	if err := tmp.Close(); err != nil {
+		return res, fmt.Errorf("write output: %w", err)
+	}
+	if !res.Success {
+		return res, nil
+	}
+	if err := os.Rename(tmp.Name(), outPath); err != nil {
+		return res, fmt.Errorf("save output: %w", err)
+	}
+	return res, nil
+}
+
+// sameFile reports whether a and b name the same existing file.
+func sameFile(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(ai, bi), nil
+}
+
+// progressReader reports how much of a file of known size has been read, at
+// most once per streamProgressInterval.
+type progressReader struct {
+	r      io.Reader
+	size   int64
+	read   int64
+	report func(float64)
+	last   time.Time
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if p.size > 0 && time.Since(p.last) >= streamProgressInterval {
+		p.last = time.Now()
+		p.report(min(float64(p.read)/float64(p.size), 1))
+	}
+	if err == io.EOF {
+		p.report(1)
+	}
+	return n, err
+}
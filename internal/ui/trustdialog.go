@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"fmt"
+
+	"codeberg.org/sigterm-de/goop/internal/scripts"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// ShowTrustDialog asks the user to confirm a user script that is new or has
+// changed since it was last approved. It shows where the script lives, the
+// elevated permissions it asks for and its full source. onRun is called on
+// the GTK main thread when the user confirms; cancelling calls nothing.
+func ShowTrustDialog(parent *gtk.Window, s scripts.Script, perms []scripts.Permission, onRun func()) {
+	win := gtk.NewWindow()
+	win.SetTitle("Run new script?")
+	if parent != nil {
+		win.SetTransientFor(parent)
+	}
+	win.SetModal(true)
+	win.SetDefaultSize(560, 480)
+	win.SetDestroyWithParent(true)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 8)
+	box.SetMarginTop(20)
+	box.SetMarginBottom(16)
+	box.SetMarginStart(20)
+	box.SetMarginEnd(20)
+
+	heading := gtk.NewLabel(fmt.Sprintf("“%s” is new or has changed since it last ran.", s.Name))
+	heading.SetXAlign(0)
+	heading.SetWrap(true)
+	heading.AddCSSClass("heading")
+	box.Append(heading)
+
+	path := gtk.NewLabel(s.FilePath)
+	path.SetXAlign(0)
+	path.SetWrap(true)
+	path.SetSelectable(true)
+	path.AddCSSClass("dim-label")
+	box.Append(path)
+
+	if len(perms) > 0 {
+		asks := gtk.NewLabel("It asks for:")
+		asks.SetXAlign(0)
+		asks.SetMarginTop(4)
+		box.Append(asks)
+		for _, p := range perms {
+			lbl := gtk.NewLabel("• " + p.Description())
+			lbl.SetXAlign(0)
+			lbl.SetWrap(true)
+			lbl.SetTooltipText(string(p))
+			box.Append(lbl)
+		}
+	}
+
+	source := gtk.NewTextView()
+	source.SetEditable(false)
+	source.SetMonospace(true)
+	source.SetLeftMargin(8)
+	source.SetTopMargin(8)
+	source.Buffer().SetText(s.Content)
+
+	scroll := gtk.NewScrolledWindow()
+	scroll.SetChild(source)
+	scroll.SetVExpand(true)
+	scroll.SetMarginTop(4)
+	box.Append(scroll)
+
+	note := gtk.NewLabel("Only run scripts you trust. You will not be asked again unless the script changes.")
+	note.SetXAlign(0)
+	note.SetWrap(true)
+	note.AddCSSClass("dim-label")
+	box.Append(note)
+
+	cancelBtn := gtk.NewButtonWithLabel("Cancel")
+	cancelBtn.ConnectClicked(func() { win.Close() })
+	runBtn := gtk.NewButtonWithLabel("Trust and Run")
+	runBtn.AddCSSClass("suggested-action")
+	runBtn.ConnectClicked(func() {
+		win.Close()
+		onRun()
+	})
+
+	buttons := gtk.NewBox(gtk.OrientationHorizontal, 8)
+	buttons.SetHAlign(gtk.AlignEnd)
+	buttons.SetMarginTop(8)
+	buttons.Append(cancelBtn)
+	buttons.Append(runBtn)
+	box.Append(buttons)
+
+	keyCtrl := gtk.NewEventControllerKey()
+	keyCtrl.SetPropagationPhase(gtk.PhaseCapture)
+	keyCtrl.ConnectKeyPressed(func(keyval, _ uint, _ gdk.ModifierType) bool {
+		if keyval == gdk.KEY_Escape {
+			win.Close()
+			return true
+		}
+		return false
+	})
+	win.AddController(keyCtrl)
+
+	win.SetChild(box)
+	win.Present()
+	cancelBtn.GrabFocus()
+}
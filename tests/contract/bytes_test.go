@@ -0,0 +1,97 @@
+// Package contract — acceptance tests for state.bytes and state.setBytes().
+package contract_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// binaryInput returns an input for src whose document is the binary payload
+// data.
+func binaryInput(data []byte, src string) engine.ExecutionInput {
+	inp := noSelInput("ignored", src)
+	inp.Data = data
+	return inp
+}
+
+// TestBytesOfText verifies state.bytes holds the UTF-8 encoding of a text
+// document.
+func TestBytesOfText(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("é!",
+		`function main(state) { state.text = Array.from(state.bytes).join(","); }`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "195,169,33" {
+		t.Errorf("NewText = %q", result.NewText)
+	}
+}
+
+// TestBinaryDocument verifies a binary document reaches the script unchanged
+// as state.bytes and as a binary string, so btoa() encodes it correctly.
+func TestBinaryDocument(t *testing.T) {
+	data := []byte{0x1f, 0x8b, 0x00, 0xff}
+	result := newExec().Execute(context.Background(), binaryInput(data, `function main(state) {
+    var b = state.bytes;
+    b[0] = 0; // a copy: must not change the document
+    state.text = btoa(state.text) + " " + state.bytes[0] + " " + state.fullText.length + " " + state.selection.end;
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.MutationKind != engine.MutationReplaceDoc {
+		t.Errorf("MutationKind = %v, want MutationReplaceDoc for a binary document", result.MutationKind)
+	}
+	if result.NewFullText != "H4sA/w== 31 4 4" {
+		t.Errorf("NewFullText = %q", result.NewFullText)
+	}
+}
+
+// TestSetBytes verifies every accepted argument type and that setBytes takes
+// precedence over text mutations.
+func TestSetBytes(t *testing.T) {
+	want := []byte{0x00, 0x80, 0xff}
+	cases := map[string]string{
+		"Uint8Array":    `state.setBytes(new Uint8Array([0, 128, 255]));`,
+		"subarray":      `state.setBytes(new Uint8Array([9, 0, 128, 255, 9]).subarray(1, 4));`,
+		"ArrayBuffer":   `state.setBytes(new Uint8Array([0, 128, 255]).buffer);`,
+		"array":         `state.setBytes([0, 128, 255]);`,
+		"binary string": `state.setBytes("\x00\x80\xff");`,
+		"over text":     `state.text = "x"; state.setBytes([0, 128, 255]); state.insert("y");`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			result := newExec().Execute(context.Background(), noSelInput("abc", "function main(state) { "+body+" }"))
+			if !result.Success {
+				t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+			}
+			if result.MutationKind != engine.MutationReplaceBytes || !bytes.Equal(result.NewBytes, want) {
+				t.Errorf("MutationKind = %v, NewBytes = %v", result.MutationKind, result.NewBytes)
+			}
+		})
+	}
+
+	result := newExec().Execute(context.Background(), noSelInput("abc", `function main(state) { state.setBytes("€"); }`))
+	if result.Success {
+		t.Error("expected a binary string with a character above U+00FF to fail")
+	}
+}
+
+// TestBinaryRoundTrip verifies bytes decoded by one script come back
+// unchanged when encoded by another.
+func TestBinaryRoundTrip(t *testing.T) {
+	data := []byte{0, 1, 2, 0xfd, 0xfe, 0xff}
+	result := newExec().Execute(context.Background(), binaryInput(data,
+		`function main(state) { state.text = btoa(state.text); }`))
+	if !result.Success || result.NewFullText != "AAEC/f7/" {
+		t.Fatalf("encode: success=%v text=%q err=%q", result.Success, result.NewFullText, result.ErrorMessage)
+	}
+	result = newExec().Execute(context.Background(), binaryInput(data,
+		`function main(state) { state.setBytes(state.fullText); }`))
+	if !result.Success || !bytes.Equal(result.NewBytes, data) {
+		t.Errorf("binary string round trip: success=%v bytes=%v err=%q", result.Success, result.NewBytes, result.ErrorMessage)
+	}
+}
@@ -0,0 +1,76 @@
+// Package contract — acceptance tests for state.clipboard.
+package contract_test
+
+import (
+	"context"
+	"testing"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// fakeClipboard records writes and serves a fixed read value.
+type fakeClipboard struct {
+	text   string
+	writes []string
+}
+
+func (c *fakeClipboard) Read() (string, error) { return c.text, nil }
+
+func (c *fakeClipboard) Write(text string) error {
+	c.writes = append(c.writes, text)
+	return nil
+}
+
+// TestClipboardReadWrite verifies reads, that a read after a write sees the
+// pending value and that only the last write reaches the clipboard.
+func TestClipboardReadWrite(t *testing.T) {
+	clip := &fakeClipboard{text: "from clipboard"}
+	inp := noSelInput("sel", `function main(state) {
+    var before = state.clipboard.read();
+    state.clipboard.write("first");
+    state.clipboard.write(state.text);
+    state.text = before + "|" + state.clipboard.read();
+}`)
+	inp.Clipboard = clip
+	inp.Permissions = []string{engine.PermClipboard}
+
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "from clipboard|sel" {
+		t.Errorf("NewText = %q", result.NewText)
+	}
+	if len(clip.writes) != 1 || clip.writes[0] != "sel" {
+		t.Errorf("writes = %q, want [\"sel\"]", clip.writes)
+	}
+}
+
+// TestClipboardDiscardedOnError verifies a failing script never writes.
+func TestClipboardDiscardedOnError(t *testing.T) {
+	clip := &fakeClipboard{}
+	inp := noSelInput("", `function main(state) { state.clipboard.write("x"); throw new Error("boom"); }`)
+	inp.Clipboard = clip
+	inp.Permissions = []string{engine.PermClipboard}
+
+	if result := newExec().Execute(context.Background(), inp); result.Success {
+		t.Fatal("expected failure")
+	}
+	if len(clip.writes) != 0 {
+		t.Errorf("writes = %q, want none", clip.writes)
+	}
+}
+
+// TestClipboardUnavailable verifies state.clipboard is undefined unless the
+// caller provides a clipboard and grants the permission.
+func TestClipboardUnavailable(t *testing.T) {
+	src := `function main(state) { state.text = typeof state.clipboard; }`
+	ungranted := noSelInput("", src)
+	ungranted.Clipboard = &fakeClipboard{}
+	for _, inp := range []engine.ExecutionInput{noSelInput("", src), ungranted} {
+		result := newExec().Execute(context.Background(), inp)
+		if !result.Success || result.NewText != "undefined" {
+			t.Errorf("got success=%v text=%q err=%s", result.Success, result.NewText, result.ErrorMessage)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package contract — acceptance tests for goop.run().
+package contract_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// fakeScripts is a ScriptResolver over name → source, passing raw parameter
+// strings through unchanged.
+func fakeScripts(sources map[string]string) engine.ScriptResolver {
+	return func(name string, raw map[string]string) (engine.NestedScript, error) {
+		src, ok := sources[name]
+		if !ok {
+			return engine.NestedScript{}, fmt.Errorf("no script named %q", name)
+		}
+		params := map[string]any{}
+		for k, v := range raw {
+			params[k] = v
+		}
+		return engine.NestedScript{Name: name, Source: src, Params: params}, nil
+	}
+}
+
+// composeInput returns an input for src whose goop.run() calls resolve
+// against sources.
+func composeInput(src string, sources map[string]string) engine.ExecutionInput {
+	inp := noSelInput("hello world", src)
+	inp.Scripts = fakeScripts(sources)
+	return inp
+}
+
+// TestGoopRun verifies goop.run returns the called script's text for each
+// kind of mutation and passes params through.
+func TestGoopRun(t *testing.T) {
+	sources := map[string]string{
+		"Upper":  `function main(s) { s.text = s.text.toUpperCase(); }`,
+		"Wrap":   `function main(s) { s.fullText = s.params.open + s.fullText + s.params.close; }`,
+		"Bang":   `function main(s) { s.insert("!"); }`,
+		"NoOp":   `function main(s) {}`,
+		"Nested": `function main(s) { s.text = goop.run("Upper", s.text) + "?"; }`,
+	}
+	result := newExec().Execute(context.Background(), composeInput(`function main(state) {
+    var t = goop.run("Upper", state.text);
+    t = goop.run("Wrap", t, { params: { open: "[", close: "]" } });
+    t = goop.run("Bang", t);
+    t = goop.run("NoOp", t);
+    state.text = t + " " + goop.run("Nested", "x");
+}`, sources))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "[HELLO WORLD]! X?" {
+		t.Errorf("NewText = %q", result.NewText)
+	}
+}
+
+// TestGoopRunErrors verifies a called script's postError message, an unknown
+// script and a missing library are thrown to the caller, where they can be
+// caught.
+func TestGoopRunErrors(t *testing.T) {
+	sources := map[string]string{
+		"Fail": `function main(s) { s.postError("bad input"); }`,
+	}
+	result := newExec().Execute(context.Background(), composeInput(`function main(state) {
+    try { goop.run("Fail", "x"); } catch (e) { state.text = e.message; }
+}`, sources))
+	if !result.Success || result.NewText != "bad input" {
+		t.Errorf("caught postError: success=%v text=%q err=%q", result.Success, result.NewText, result.ErrorMessage)
+	}
+
+	result = newExec().Execute(context.Background(), composeInput(
+		`function main(state) { goop.run("Fail", "x"); }`, sources))
+	if result.Success || !strings.Contains(result.ErrorMessage, "bad input") {
+		t.Errorf("uncaught postError: success=%v err=%q", result.Success, result.ErrorMessage)
+	}
+
+	result = newExec().Execute(context.Background(), composeInput(
+		`function main(state) { goop.run("Missing", "x"); }`, sources))
+	if result.Success || !strings.Contains(result.ErrorMessage, `no script named "Missing"`) {
+		t.Errorf("unknown script: success=%v err=%q", result.Success, result.ErrorMessage)
+	}
+
+	inp := noSelInput("x", `function main(state) { goop.run("Fail", "x"); }`)
+	result = newExec().Execute(context.Background(), inp)
+	if result.Success || !strings.Contains(result.ErrorMessage, "no script library") {
+		t.Errorf("no resolver: success=%v err=%q", result.Success, result.ErrorMessage)
+	}
+}
+
+// TestGoopRunDepth verifies a script calling itself fails on the nesting
+// bound instead of running until the timeout.
+func TestGoopRunDepth(t *testing.T) {
+	sources := map[string]string{
+		"Loop": `function main(s) { s.text = goop.run("Loop", s.text); }`,
+	}
+	start := time.Now()
+	result := newExec().Execute(context.Background(), composeInput(sources["Loop"], sources))
+	if result.Success || result.TimedOut {
+		t.Fatalf("expected a depth error, got success=%v timedOut=%v", result.Success, result.TimedOut)
+	}
+	if !strings.Contains(result.ErrorMessage, "nested more than") {
+		t.Errorf("ErrorMessage = %q", result.ErrorMessage)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("depth error took %v", time.Since(start))
+	}
+}
+
+// TestGoopRunTimeout verifies a called script only gets what is left of the
+// caller's time budget.
+func TestGoopRunTimeout(t *testing.T) {
+	sources := map[string]string{
+		"Spin": `function main(s) { while (true) {} }`,
+	}
+	inp := composeInput(`function main(state) {
+    try { goop.run("Spin", "x"); } catch (e) { state.text = e.message; }
+}`, sources)
+	inp.Timeout = 300 * time.Millisecond
+	start := time.Now()
+	result := newExec().Execute(context.Background(), inp)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("nested run outlived the caller's budget: %v", elapsed)
+	}
+	if result.Success || !result.TimedOut {
+		t.Errorf("expected the caller to time out, got success=%v text=%q err=%q", result.Success, result.NewText, result.ErrorMessage)
+	}
+}
+
+// TestGoopRunNoPermissions verifies a called script runs without the
+// caller's elevated permissions.
+func TestGoopRunNoPermissions(t *testing.T) {
+	sources := map[string]string{
+		"Peek": `function main(s) { s.text = typeof s.readFile; }`,
+	}
+	inp := composeInput(`function main(state) { state.text = goop.run("Peek", "") + " " + typeof state.readFile; }`, sources)
+	inp.Permissions = []string{engine.PermReadFile}
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "undefined function" {
+		t.Errorf("NewText = %q", result.NewText)
+	}
+}
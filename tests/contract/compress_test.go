@@ -0,0 +1,112 @@
+// Package contract — acceptance tests for the @boop/compress module.
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestCompressRoundTrip verifies that every format, level and encoding
+// decompresses back to its input.
+func TestCompressRoundTrip(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var compress = require('@boop/compress');
+function main(state) {
+    var text = new Array(200).join('Grüße, world! ');
+    state.text = compress.formats().map(function (f) {
+        var b64 = compress.compress(f, text);
+        var hex = compress.compress(f, text, {encoding: 'hex', level: f === 'zstd' ? 19 : 1});
+        var raw = compress.compress(f, new Uint8Array([0, 255, 1]), {encoding: 'bytes'});
+        return f + " " + (b64.length < text.length) + " " +
+            (compress.decompress(f, b64) === text) + " " +
+            (compress.decompress(f, hex, {encoding: 'hex'}) === text) + " " +
+            compress.decompress(f, raw, {output: 'bytes'}).join();
+    }).join("\n");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := strings.Join([]string{
+		"brotli true true true 0,255,1",
+		"deflate true true true 0,255,1",
+		"gzip true true true 0,255,1",
+		"zlib true true true 0,255,1",
+		"zstd true true true 0,255,1",
+	}, "\n")
+	if result.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.NewText, want)
+	}
+}
+
+// TestCompressDecompressKnown verifies decompression of payloads made by
+// other tools, and format detection.
+func TestCompressDecompressKnown(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var compress = require('@boop/compress');
+function main(state) {
+    var gz = 'H4sIAAAAAAACA8tIzcnJ\n11Eozy/KSeECAFN0JPQNAAAA';
+    var zl = '789ccb48cdc9c9d75128cf2fca49e1020021e70493';
+    var zs = compress.compress('zstd', 'hello, world\n', {encoding: 'bytes'});
+    state.text = [
+        compress.decompress('gzip', gz),
+        compress.decompress('auto', gz),
+        compress.decompress('zlib', zl, {encoding: 'hex'}),
+        compress.decompress('auto', zl, {encoding: 'hex'}),
+        compress.decompress('deflate', 'y0jNycnXUSjPL8pJ4QIA'),
+        compress.decompress('auto', zs)
+    ].join("");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if want := strings.Repeat("hello, world\n", 6); result.NewText != want {
+		t.Errorf("got %q, want %q", result.NewText, want)
+	}
+}
+
+// TestCompressBomb verifies that output beyond maxSize is refused rather
+// than held in memory.
+func TestCompressBomb(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var compress = require('@boop/compress');
+function main(state) {
+    var bomb = compress.compress('zstd', new Uint8Array(20 << 20), {encoding: 'bytes'});
+    var errors = [];
+    try { compress.decompress('zstd', bomb, {output: 'bytes'}); } catch (e) { errors.push(e.message); }
+    try { compress.decompress('zstd', bomb, {output: 'bytes', maxSize: 1000}); } catch (e) { errors.push(e.message); }
+    state.text = bomb.length + "\n" + errors.join("\n") + "\n" +
+        compress.decompress('zstd', bomb, {output: 'bytes', maxSize: 32 << 20}).length;
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	lines := strings.Split(result.NewText, "\n")
+	if len(lines) != 4 || len(lines[0]) > 4 ||
+		!strings.Contains(lines[1], "larger than the 16777216 B limit") ||
+		!strings.Contains(lines[2], "larger than the 1000 B limit") || lines[3] != "20971520" {
+		t.Errorf("unexpected result:\n%s", result.NewText)
+	}
+}
+
+// TestCompressErrors verifies that invalid data and options are reported.
+func TestCompressErrors(t *testing.T) {
+	for name, call := range map[string]string{
+		"unknown format":   `compress.compress('lz4', 'x')`,
+		"level":            `compress.compress('gzip', 'x', {level: 10})`,
+		"unknown encoding": `compress.compress('gzip', 'x', {encoding: 'base32'})`,
+		"not gzip":         `compress.decompress('gzip', 'aGVsbG8=')`,
+		"not detected":     `compress.decompress('auto', 'aGVsbG8=')`,
+		"invalid base64":   `compress.decompress('gzip', '!!!')`,
+		"not UTF-8":        `compress.decompress('gzip', compress.compress('gzip', new Uint8Array([255])))`,
+		"maxSize":          `compress.decompress('gzip', 'H4sIAAAAAAACA8tIzcnJ11Eozy/KSeECAFN0JPQNAAAA', {maxSize: 0})`,
+		"missing data":     `compress.decompress('gzip')`,
+	} {
+		result := newExec().Execute(context.Background(), noSelInput("", `
+var compress = require('@boop/compress');
+function main(state) { `+call+`; }`))
+		if result.Success {
+			t.Errorf("%s: expected %s to fail", name, call)
+		}
+	}
+}
@@ -46,7 +46,7 @@ func TestConsoleLog(t *testing.T) {
 	}
 }
 
-// TestYAMLNestedArrayNormalise verifies normaliseYAML handles nested arrays.
+// TestYAMLNestedArrayNormalise verifies yaml.parse handles nested arrays.
 func TestYAMLNestedArrayNormalise(t *testing.T) {
 	result := newExec().Execute(context.Background(), noSelInput(
 		"",
@@ -0,0 +1,155 @@
+// Package contract — acceptance tests for the @boop/crypto module.
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestCryptoHash verifies digests against known vectors, in each encoding,
+// for strings and bytes.
+func TestCryptoHash(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var crypto = require('@boop/crypto');
+function main(state) {
+    state.text = [
+        crypto.hash('sha256', 'abc'),
+        crypto.hash('SHA3-256', 'abc'),
+        crypto.hash('blake2s-256', 'abc'),
+        crypto.hash('sha256', new Uint8Array([97, 98, 99]), {encoding: 'base64'}),
+        crypto.hash('md5', '', {encoding: 'base64url'}),
+        crypto.hash('sha1', 'abc', {encoding: 'bytes'}).length,
+        crypto.hmac('sha256', 'key', 'The quick brown fox jumps over the lazy dog')
+    ].join("\n");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := strings.Join([]string{
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		"3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532",
+		"508c5e8c327c14e2e1a72ba34eeb452f37458b209ed63a294d999b4c86675982",
+		"ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0=",
+		"1B2M2Y8AsgTpgAmY7PhCfg",
+		"20",
+		"f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+	}, "\n")
+	if result.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.NewText, want)
+	}
+}
+
+// TestCryptoBLAKE3 verifies BLAKE3 digests against the reference
+// implementation's test vectors.
+func TestCryptoBLAKE3(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var crypto = require('@boop/crypto');
+function main(state) {
+    state.text = [crypto.hash('blake3', ''), crypto.hash('BLAKE3', 'abc')].join("\n");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262\n" +
+		"6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"
+	if result.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.NewText, want)
+	}
+}
+
+// TestCryptoPBKDF2 verifies PBKDF2-HMAC-SHA1 against the vectors of RFC 6070
+// and the SHA-256 default against a published vector.
+func TestCryptoPBKDF2(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var crypto = require('@boop/crypto');
+function main(state) {
+    var sha1 = function (n, length) { return {hash: 'sha1', iterations: n, length: length || 20}; };
+    state.text = [
+        crypto.pbkdf2('password', 'salt', sha1(1)),
+        crypto.pbkdf2('password', 'salt', sha1(2)),
+        crypto.pbkdf2('password', 'salt', sha1(4096)),
+        crypto.pbkdf2('passwordPASSWORDpassword', 'saltSALTsaltSALTsaltSALTsaltSALTsalt', sha1(4096, 25)),
+        crypto.pbkdf2('pass\0word', 'sa\0lt', sha1(4096, 16)),
+        crypto.pbkdf2('password', 'salt', {iterations: 1}),
+        crypto.pbkdf2('password', new Uint8Array([115, 97, 108, 116]), {iterations: 1, encoding: 'bytes'}).length
+    ].join("\n");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := strings.Join([]string{
+		"0c60c80f961f0e71f3a9b524af6012062fe037a6",
+		"ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957",
+		"4b007901b765489abead49d926f721d065a429c1",
+		"3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038",
+		"56fa6aa75548099dcc37d7f03425e0c3",
+		"120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b",
+		"32",
+	}, "\n")
+	if result.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.NewText, want)
+	}
+}
+
+// TestCryptoPasswords verifies bcrypt and Argon2id hashes verify against the
+// right password only.
+func TestCryptoPasswords(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var crypto = require('@boop/crypto');
+function main(state) {
+    var b = crypto.hashPassword('s3cret', {cost: 4});
+    var a = crypto.hashPassword('s3cret', {algorithm: 'argon2id', time: 1, memory: 1024, threads: 1});
+    state.text = [
+        b.slice(0, 7), a.slice(0, 29),
+        crypto.verifyPassword('s3cret', b), crypto.verifyPassword('wrong', b),
+        crypto.verifyPassword('s3cret', a), crypto.verifyPassword('wrong', a)
+    ].join(" ");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if want := "$2a$04$ $argon2id$v=19$m=1024,t=1,p=1 true false true false"; result.NewText != want {
+		t.Errorf("got %q, want %q", result.NewText, want)
+	}
+}
+
+// TestCryptoRandomBytes verifies the length and encodings of random bytes.
+func TestCryptoRandomBytes(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var crypto = require('@boop/crypto');
+function main(state) {
+    var a = crypto.randomBytes(16), b = crypto.randomBytes(16);
+    if (a === b) throw new Error("two random values are equal");
+    state.text = [a.length, crypto.randomBytes(4, {encoding: 'bytes'}).length,
+        crypto.randomBytes(3, {encoding: 'base64'}).length].join(" ");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "32 4 4" {
+		t.Errorf("got %q", result.NewText)
+	}
+}
+
+// TestCryptoErrors verifies bad arguments throw.
+func TestCryptoErrors(t *testing.T) {
+	for _, c := range []struct{ src, want string }{
+		{`crypto.hash('sha999', 'x')`, `unknown algorithm "sha999"`},
+		{`crypto.hash('sha256', 'x', {encoding: 'hexx'})`, `unknown encoding "hexx"`},
+		{`crypto.hashPassword('x', {cost: 31})`, "cost must be between 4 and 15"},
+		{`crypto.hashPassword('x', {algorithm: 'md5'})`, `unknown algorithm "md5"`},
+		{`crypto.verifyPassword('x', 'not a hash')`, "not a bcrypt or argon2id hash"},
+		{`crypto.verifyPassword('x', '$argon2id$v=19$m=9999999,t=1,p=1$c2FsdA$a2V5')`, "outside the supported limits"},
+		{`crypto.pbkdf2('x', 'salt', {iterations: 1e9})`, "iterations must be between 1 and 5000000"},
+		{`crypto.pbkdf2('x', 'salt', {hash: 'md4'})`, `unknown algorithm "md4"`},
+		{`crypto.randomBytes(-1)`, "length must be between"},
+	} {
+		result := newExec().Execute(context.Background(), noSelInput("", `
+var crypto = require('@boop/crypto');
+function main(state) { `+c.src+`; }`))
+		if result.Success || !strings.Contains(result.ErrorMessage, c.want) {
+			t.Errorf("%s: success=%v err=%q, want %q", c.src, result.Success, result.ErrorMessage, c.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Package contract — acceptance tests for per-line and per-block execution.
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// TestEachLine verifies main runs once per non-blank line and the results are
+// joined back around the original blank lines and line endings.
+func TestEachLine(t *testing.T) {
+	inp := noSelInput("a\n\nb\r\nc", `function main(state) { state.text = "[" + state.text + "]"; }`)
+	inp.Each = engine.EachLine
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.MutationKind != engine.MutationReplaceSelect || result.NewText != "[a]\n\n[b]\r\n[c]" {
+		t.Errorf("got kind %v text %q", result.MutationKind, result.NewText)
+	}
+}
+
+// TestEachBlock verifies blocks are the runs of lines between blank lines.
+func TestEachBlock(t *testing.T) {
+	inp := noSelInput("a\nb\n\n\nc\n", `function main(state) { state.text = state.text.split("\n").join("+"); }`)
+	inp.Each = engine.EachBlock
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "a+b\n\n\nc\n" {
+		t.Errorf("got %q", result.NewText)
+	}
+}
+
+// TestEachFailures verifies failing lines are kept, reported by line number,
+// and only fail the run when every line fails.
+func TestEachFailures(t *testing.T) {
+	src := `function main(state) {
+    if (state.text === "bad") throw new Error("bad input");
+    if (state.text === "worse") { state.postError("worse input"); return; }
+    state.text = state.text.toUpperCase();
+}`
+	inp := noSelInput("ok\nbad\nfine\nworse", src)
+	inp.Each = engine.EachLine
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "OK\nbad\nFINE\nworse" {
+		t.Errorf("got %q", result.NewText)
+	}
+	if len(result.Failures) != 2 || result.Failures[0].Line != 2 || result.Failures[1].Line != 4 {
+		t.Fatalf("failures = %+v", result.Failures)
+	}
+	if result.Failures[1].Message != "worse input" {
+		t.Errorf("message = %q", result.Failures[1].Message)
+	}
+	if !strings.HasPrefix(result.InfoMessage, "failed on 2 of 4 lines; line 2: ") {
+		t.Errorf("info = %q", result.InfoMessage)
+	}
+
+	inp = noSelInput("bad\nbad", src)
+	inp.Each = engine.EachLine
+	result = newExec().Execute(context.Background(), inp)
+	if result.Success || len(result.Failures) != 2 {
+		t.Errorf("success=%v failures=%+v, want the run to fail", result.Success, result.Failures)
+	}
+}
+
+// TestEachFreshRuntime verifies every line runs the script as-is, selected in
+// full: top-level variables start over for each line rather than carrying
+// over from the last.
+func TestEachFreshRuntime(t *testing.T) {
+	inp := noSelInput("a\nb\nc", `let n = 0;
+var seen = [];
+function main(state) {
+    n++;
+    seen.push(state.text);
+    if (state.fullText !== state.text) state.postError("fullText is not the line");
+    if (state.selection.start !== 0 || state.selection.end !== state.text.length)
+        state.postError("selection is " + JSON.stringify(state.selection));
+    state.text = n + state.text + seen.length;
+}`)
+	inp.Each = engine.EachLine
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success || result.NewText != "1a1\n1b1\n1c1" {
+		t.Errorf("success=%v text=%q err=%q", result.Success, result.NewText, result.ErrorMessage)
+	}
+}
+
+// TestEachSharedModules verifies modules keep working when each line loads
+// them afresh, and a seeded run's random values still differ between lines.
+func TestEachSharedModules(t *testing.T) {
+	inp := noSelInput("a\nb", `var ids = require('@boop/ids'), b64 = require('@boop/base64');
+function main(state) { state.text = b64.encode(state.text) + " " + ids.uuidv4(); }`)
+	inp.Each = engine.EachLine
+	inp.Seed = 42
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	lines := strings.Split(result.NewText, "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "YQ== ") || !strings.HasPrefix(lines[1], "Yg== ") ||
+		lines[0][5:] == lines[1][5:] {
+		t.Errorf("got %q", result.NewText)
+	}
+}
+
+// TestEachSyntaxError verifies a script that does not compile fails once,
+// rather than on every line.
+func TestEachSyntaxError(t *testing.T) {
+	inp := noSelInput("a\nb", `function main(state) {`)
+	inp.Each = engine.EachLine
+	result := newExec().Execute(context.Background(), inp)
+	if result.Success || len(result.Failures) != 0 || result.ErrorMessage == "" {
+		t.Errorf("success=%v failures=%+v err=%q", result.Success, result.Failures, result.ErrorMessage)
+	}
+}
+
+// TestEachBinary verifies a binary document cannot be split into lines.
+func TestEachBinary(t *testing.T) {
+	inp := noSelInput("", `function main(state) {}`)
+	inp.Data = []byte{0xff, 0x00}
+	inp.Each = engine.EachLine
+	if result := newExec().Execute(context.Background(), inp); result.Success {
+		t.Error("expected an error for a binary document")
+	}
+}
@@ -0,0 +1,211 @@
+// Package contract — acceptance tests for the @boop/encoding module.
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEncodingVectors verifies each format against known encodings, and that
+// each decodes back to its input.
+func TestEncodingVectors(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var encoding = require('@boop/encoding');
+function check(format, data, opts) {
+    var out = encoding.encode(format, data, opts);
+    var back = encoding.decode(format, out, {output: typeof data === 'string' ? 'text' : 'bytes', mode: opts && opts.mode});
+    var same = typeof data === 'string' ? back === data : back.join() === Array.prototype.join.call(data);
+    return out + (same ? "" : " !");
+}
+function main(state) {
+    state.text = [
+        check('base32', 'foobar'),
+        check('base32', 'foobar', {padding: false}),
+        check('base32hex', 'foobar'),
+        check('base32crockford', 'foobar'),
+        check('base58', 'Hello World!'),
+        check('base58', new Uint8Array([0, 0, 1])),
+        check('base58flickr', 'Hello World!'),
+        check('base64', 'hi?>'),
+        check('base64url', new Uint8Array([0xfb, 0xff])),
+        check('base64url', new Uint8Array([0xfb, 0xff]), {padding: true}),
+        check('ascii85', 'Man is'),
+        check('ascii85', 'Man is', {delimiters: true}),
+        check('z85', new Uint8Array([0x86, 0x4F, 0xD2, 0x6F, 0xB5, 0x59, 0xF7, 0x5B])),
+        check('quoted-printable', 'héllo = 1'),
+        check('percent', 'a b/ü~'),
+        check('percent', 'a b/ü~', {mode: 'path'}),
+        check('percent', 'a b/ü~', {mode: 'query'}),
+        check('percent', 'ab', {mode: 'all'}),
+        check('hex', 'hi'),
+        check('hex', new Uint8Array([0xab, 0xcd]), {uppercase: true, separator: ':'})
+    ].join("\n");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := strings.Join([]string{
+		"MZXW6YTBOI======",
+		"MZXW6YTBOI",
+		"CPNMUOJ1E8======",
+		"CSQPYRK1E8",
+		"2NEpo7TZRRrLZSi2U",
+		"112",
+		"2nePN7syqqRkyrH2t",
+		"aGk/Pg==",
+		"-_8",
+		"-_8=",
+		"9jqo^Bla",
+		"<~9jqo^Bla~>",
+		"HelloWorld",
+		"h=C3=A9llo =3D 1",
+		"a%20b%2F%C3%BC~",
+		"a%20b/%C3%BC~",
+		"a+b%2F%C3%BC~",
+		"%61%62",
+		"6869",
+		"AB:CD",
+	}, "\n")
+	if result.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.NewText, want)
+	}
+}
+
+// TestEncodingLenientDecode verifies that decoding takes wrapped text, either
+// padding and the usual variants of each format.
+func TestEncodingLenientDecode(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var encoding = require('@boop/encoding');
+function main(state) {
+    state.text = [
+        encoding.decode('base32', 'mzxw6ytb\n  oi'),
+        encoding.decode('base32crockford', 'csqp-yrk1-e8'),
+        encoding.decode('base32crockford', 'CSQPYRKIE8'),
+        encoding.decode('base64', 'aGk/\nPg'),
+        encoding.decode('ascii85', '<~9jqo^\n Bla~>'),
+        encoding.decode('quoted-printable', 'soft=\nbreak=3D'),
+        encoding.decode('hex', '0x68, 0x69 \\x21'),
+        encoding.decode('uuencode', '#0V%T\n'),
+        encoding.decode('percent', 'a+b%20c'),
+        encoding.decode('percent', 'a+b%20c', {mode: 'query'})
+    ].join("|");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if want := "foobar|foobar|foobar|hi?>|Man is|softbreak=|hi!|Cat|a+b c|a b c"; result.NewText != want {
+		t.Errorf("got %q, want %q", result.NewText, want)
+	}
+}
+
+// TestEncodingUUEncode verifies the uuencode file layout and a round trip
+// over several lines.
+func TestEncodingUUEncode(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var encoding = require('@boop/encoding');
+function main(state) {
+    var long = new Array(101).join('x');
+    var out = encoding.encode('uuencode', long, {name: 'x.txt', mode: '600'});
+    state.text = encoding.encode('uuencode', 'Cat') + "|" + out.split("\n")[0] + "|" +
+        (encoding.decode('uuencode', out) === long);
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if want := "begin 644 data\n#0V%T\n`\nend\n|begin 600 x.txt|true"; result.NewText != want {
+		t.Errorf("got %q, want %q", result.NewText, want)
+	}
+}
+
+// TestEncodingHexdump verifies the dump matches xxd's layout and that dumps
+// by xxd and hexdump -C decode back, while plain hex spaced out in groups
+// still reads as plain hex.
+func TestEncodingHexdump(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var encoding = require('@boop/encoding');
+function main(state) {
+    var data = "Hello, world!\nThis is a hex dump test \u0000\u0001";
+    var dump = encoding.encode('hexdump', data);
+    var bytes = encoding.decode('hexdump', "00000000  48 65 6c 6c 6f 2c 20 77  6f 72 6c 64 21 0a ff     |Hello, world!..|\n0000000f\n", {output: 'auto'});
+    state.text = dump + encoding.encode('hexdump', 'Hello', {group: 0}) +
+        encoding.encode('hexdump', 'Hello, w', {group: 1, columns: 4}) +
+        (encoding.decode('hexdump', dump) === data) + " " +
+        (bytes instanceof Uint8Array) + " " + bytes.length + " " +
+        encoding.decode('hexdump', '48656c6c6f\n2c') + " " +
+        encoding.decode('hexdump', '48  65 6c 6c 6f') + " " +
+        encoding.decode('hexdump', '48656c6c6f  2c20');
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := "00000000: 4865 6c6c 6f2c 2077 6f72 6c64 210a 5468  Hello, world!.Th\n" +
+		"00000010: 6973 2069 7320 6120 6865 7820 6475 6d70  is is a hex dump\n" +
+		"00000020: 2074 6573 7420 0001                       test ..\n" +
+		"00000000: 48656c6c6f                        Hello\n" +
+		"00000000: 48 65 6c 6c  Hell\n" +
+		"00000004: 6f 2c 20 77  o, w\n" +
+		"true true 15 Hello, Hello Hello, "
+	if result.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.NewText, want)
+	}
+}
+
+// TestEncodingBase58Limit verifies base58, whose conversion is quadratic and
+// cannot be interrupted, refuses large input at once and still takes input up
+// to its limit.
+func TestEncodingBase58Limit(t *testing.T) {
+	start := time.Now()
+	for _, call := range []string{
+		`encoding.encode('base58', 'x'.repeat(200000))`,
+		`encoding.decode('base58', '2'.repeat(200000))`,
+	} {
+		result := newExec().Execute(context.Background(), noSelInput("", `
+var encoding = require('@boop/encoding');
+function main(state) { `+call+`; }`))
+		if result.Success || !strings.Contains(result.ErrorMessage, "is limited to") {
+			t.Errorf("%s: success=%v err=%q", call, result.Success, result.ErrorMessage)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("refusing large base58 input took %v", elapsed)
+	}
+
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var encoding = require('@boop/encoding');
+function main(state) {
+    var s = 'x'.repeat(8192);
+    state.text = String(encoding.decode('base58', encoding.encode('base58', s)) === s);
+}`))
+	if !result.Success || result.NewText != "true" {
+		t.Errorf("8 KiB round trip: success=%v text=%q err=%q", result.Success, result.NewText, result.ErrorMessage)
+	}
+}
+
+// TestEncodingErrors verifies that invalid input and options are reported.
+func TestEncodingErrors(t *testing.T) {
+	for name, call := range map[string]string{
+		"unknown format": `encoding.encode('base99', 'x')`,
+		"invalid base32": `encoding.decode('base32', '1111')`,
+		"invalid base58": `encoding.decode('base58', '0OIl')`,
+		"z85 length":     `encoding.encode('z85', 'abc')`,
+		"not UTF-8":      `encoding.decode('hex', 'ff')`,
+		"odd hex":        `encoding.decode('hex', 'abc')`,
+		"unknown mode":   `encoding.encode('percent', 'x', {mode: 'form'})`,
+		"unknown output": `encoding.decode('hex', '00', {output: 'array'})`,
+		"columns":        `encoding.encode('hexdump', 'x', {columns: 0})`,
+		"not a hex dump": `encoding.decode('hexdump', '00000000: 4865\nhello')`,
+		"uuencode mode":  `encoding.encode('uuencode', 'x', {mode: 'rw'})`,
+		"not uuencoded":  `encoding.decode('uuencode', 'begin 644 x\nabc\u0001')`,
+		"bad percent":    `encoding.decode('percent', '%zz')`,
+		"missing data":   `encoding.encode('hex')`,
+	} {
+		result := newExec().Execute(context.Background(), noSelInput("", `
+var encoding = require('@boop/encoding');
+function main(state) { `+call+`; }`))
+		if result.Success {
+			t.Errorf("%s: expected %s to fail", name, call)
+		}
+	}
+}
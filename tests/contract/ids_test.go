@@ -0,0 +1,144 @@
+// Package contract — acceptance tests for the @boop/ids module.
+package contract_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestIDsGenerate verifies the shape of generated IDs, and that a seed and a
+// fixed clock make them repeatable.
+func TestIDsGenerate(t *testing.T) {
+	src := `
+var ids = require('@boop/ids');
+function main(state) {
+    var v7 = ids.uuidv7(), u = ids.ulid(), k = ids.ksuid();
+    state.text = [
+        ids.uuidv4(), v7, u, k,
+        ids.decode(v7).time.toISOString(),
+        ids.decode(u).time.toISOString(),
+        ids.decode(k).time.toISOString()
+    ].join("\n");
+}`
+	run := func(seed int64) []string {
+		t.Helper()
+		inp := noSelInput("", src)
+		inp.Seed = seed
+		inp.Now = time.Date(2024, 2, 29, 12, 30, 0, 250e6, time.UTC)
+		result := newExec().Execute(context.Background(), inp)
+		if !result.Success {
+			t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+		}
+		return strings.Split(result.NewText, "\n")
+	}
+	got := run(42)
+	patterns := []string{
+		`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+		`^018df4d7-ce3a-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+		`^01HQTDFKHT[0-9A-HJKMNP-TV-Z]{16}$`,
+		`^[0-9A-Za-z]{27}$`,
+		`^2024-02-29T12:30:00.250Z$`,
+		`^2024-02-29T12:30:00.250Z$`,
+		`^2024-02-29T12:30:00.000Z$`,
+	}
+	for i, p := range patterns {
+		if !regexp.MustCompile(p).MatchString(got[i]) {
+			t.Errorf("line %d: %q does not match %s", i, got[i], p)
+		}
+	}
+	if again := run(42); strings.Join(again, "\n") != strings.Join(got, "\n") {
+		t.Errorf("same seed gave different IDs:\n%v\n%v", got, again)
+	}
+	if other := run(43); other[0] == got[0] {
+		t.Errorf("seeds 42 and 43 gave the same UUID %q", got[0])
+	}
+}
+
+// TestIDsDecode verifies decoding against the examples of RFC 9562 and the
+// ULID, KSUID and Discord documentation.
+func TestIDsDecode(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var ids = require('@boop/ids');
+function show(id, opts) {
+    var info = ids.decode(id, opts);
+    return Object.keys(info).map(function (k) {
+        var v = info[k];
+        return k + "=" + (v instanceof Date ? v.toISOString() : v);
+    }).join(" ");
+}
+function main(state) {
+    state.text = [
+        show('017F22E2-79B0-7CC3-98C4-DC0C0C07398F'),
+        show('{c232ab00-9414-11ec-b3c8-9f6bdeced846}'),
+        show('urn:uuid:1ec9414c-232a-6b00-b3c8-9f6bdeced846'),
+        show('00000000-0000-0000-0000-000000000000'),
+        show('01ARZ3NDEKTSV4RRFFQ69G5FAV'),
+        show('0ujtsYcgvSTl8PAuAdqWYSMnLOv'),
+        show('175928847299117063', {epoch: 'discord'})
+    ].join("\n");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := strings.Join([]string{
+		"type=uuid uuid=017f22e2-79b0-7cc3-98c4-dc0c0c07398f version=7 variant=RFC 9562 time=2022-02-22T19:22:22.000Z",
+		"type=uuid uuid=c232ab00-9414-11ec-b3c8-9f6bdeced846 version=1 variant=RFC 9562 time=2022-02-22T19:22:22.000Z",
+		"type=uuid uuid=1ec9414c-232a-6b00-b3c8-9f6bdeced846 version=6 variant=RFC 9562 time=2022-02-22T19:22:22.000Z",
+		"type=uuid uuid=00000000-0000-0000-0000-000000000000 version=0 variant=NCS nil=true",
+		"type=ulid time=2016-07-30T23:54:10.259Z random=d6764c61efb99302bd5b",
+		"type=ksuid time=2017-10-10T04:00:47.000Z payload=b5a1cd34b5f99d1154fb6853345c9735",
+		"type=snowflake epoch=discord time=2016-04-30T11:18:25.796Z worker=1 process=0 sequence=7",
+	}, "\n")
+	if result.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.NewText, want)
+	}
+}
+
+// TestIDsFormatUUID verifies every notation, and that each reads back.
+func TestIDsFormatUUID(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var ids = require('@boop/ids');
+function main(state) {
+    var u = '017f22e2-79b0-7cc3-98c4-dc0c0c07398f';
+    state.text = ['hyphenated', 'uppercase', 'braces', 'urn', 'hex', 'base64', 'base64url'].map(function (f) {
+        var out = ids.formatUUID(u, f);
+        return out + (ids.formatUUID(out) === u ? "" : " !");
+    }).join("\n");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := strings.Join([]string{
+		"017f22e2-79b0-7cc3-98c4-dc0c0c07398f",
+		"017F22E2-79B0-7CC3-98C4-DC0C0C07398F",
+		"{017f22e2-79b0-7cc3-98c4-dc0c0c07398f}",
+		"urn:uuid:017f22e2-79b0-7cc3-98c4-dc0c0c07398f",
+		"017f22e279b07cc398c4dc0c0c07398f",
+		"AX8i4nmwfMOYxNwMDAc5jw==",
+		"AX8i4nmwfMOYxNwMDAc5jw",
+	}, "\n")
+	if result.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.NewText, want)
+	}
+}
+
+// TestIDsErrors verifies that invalid IDs and options are reported.
+func TestIDsErrors(t *testing.T) {
+	for name, call := range map[string]string{
+		"not an ID":      `ids.decode('hello')`,
+		"bad KSUID":      `ids.decode('zzzzzzzzzzzzzzzzzzzzzzzzzzz')`,
+		"unknown epoch":  `ids.decode('1', {epoch: 'mastodon'})`,
+		"unknown format": `ids.formatUUID('017f22e2-79b0-7cc3-98c4-dc0c0c07398f', 'octal')`,
+		"not a UUID":     `ids.formatUUID('01ARZ3NDEKTSV4RRFFQ69G5FAV')`,
+	} {
+		result := newExec().Execute(context.Background(), noSelInput("", `
+var ids = require('@boop/ids');
+function main(state) { `+call+`; }`))
+		if result.Success {
+			t.Errorf("%s: expected %s to fail", name, call)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Package contract — acceptance tests for the @boop/jq module.
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+const jqSample = `{"items":[{"n":1,"name":"a"},{"n":2,"name":"b"}],"id":12345678901234567890}`
+
+// TestJQQuery verifies query returns every output as JS values, binds vars
+// and reads several JSON values from one string.
+func TestJQQuery(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(jqSample, `
+var jq = require('@boop/jq');
+function main(state) {
+    var names = jq.query(state.text, '.items[] | select(.n >= $min) | .name', {vars: {min: 2}});
+    var sums = jq.query('{"a":[1,2]} {"a":[3]}', '.a | add');
+    var fromValue = jq.query({x: {y: true}}, '.x.y');
+    state.text = JSON.stringify([names, sums, fromValue]);
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if want := `[["b"],[3,3],[true]]`; result.NewText != want {
+		t.Errorf("got %s, want %s", result.NewText, want)
+	}
+}
+
+// TestJQText verifies text output is indented JSON, compact on request, with
+// large integers kept exact.
+func TestJQText(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(jqSample, `
+var jq = require('@boop/jq');
+function main(state) {
+    state.text = jq.query(state.text, '{id, first: .items[0].name}', {text: true})[0] + "|" +
+        jq.query(state.text, '.items[0]', {text: true, compact: true})[0];
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := "{\n  \"first\": \"a\",\n  \"id\": 12345678901234567890\n}|{\"n\":1,\"name\":\"a\"}"
+	if result.NewText != want {
+		t.Errorf("got %q, want %q", result.NewText, want)
+	}
+}
+
+// TestJQErrors verifies a filter that does not compile throws a SyntaxError,
+// runtime errors and invalid input throw catchable errors, and the
+// environment is hidden.
+func TestJQErrors(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(jqSample, `
+var jq = require('@boop/jq');
+function main(state) {
+    var msgs = [];
+    ['.items[', 'nosuch(1)', '.id | keys'].forEach(function (f) {
+        try { jq.query(state.text, f); msgs.push("no error"); }
+        catch (e) { msgs.push((e instanceof SyntaxError ? "syntax: " : "error: ") + e.message); }
+    });
+    try { jq.query('{', '.'); } catch (e) { msgs.push(e.message); }
+    msgs.push(JSON.stringify(jq.query('null', '[env, $ENV]')));
+    state.text = msgs.join("\n");
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	lines := strings.Split(result.NewText, "\n")
+	wants := []string{
+		`syntax: jq: unexpected EOF at position 7 of ".items["`,
+		"syntax: jq: function not defined: nosuch/1",
+		"error: jq.query: keys cannot be applied to",
+		"jq.query: invalid JSON input",
+		"[[{},{}]]",
+	}
+	if len(lines) != len(wants) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(wants), result.NewText)
+	}
+	for i, want := range wants {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("line %d: got %q, want prefix %q", i+1, lines[i], want)
+		}
+	}
+}
+
+// TestJQTimeout verifies the script timeout interrupts a runaway filter.
+func TestJQTimeout(t *testing.T) {
+	inp := noSelInput("", `
+var jq = require('@boop/jq');
+function main(state) { jq.query('null', '[range(1e12)] | length'); }`)
+	inp.Timeout = 200 * time.Millisecond
+	start := time.Now()
+	result := newExec().Execute(context.Background(), inp)
+	if result.Success || !result.TimedOut {
+		t.Errorf("success=%v timedOut=%v err=%q, want a timeout", result.Success, result.TimedOut, result.ErrorMessage)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("query ran for %v after the timeout", elapsed)
+	}
+}
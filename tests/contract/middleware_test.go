@@ -0,0 +1,196 @@
+// Package contract — acceptance tests for executor middleware.
+package contract_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// countingExec counts the runs that reach the real executor.
+func countingExec(n *int) engine.Executor {
+	exec := engine.NewExecutor()
+	return engine.ExecutorFunc(func(ctx context.Context, input engine.ExecutionInput) engine.ExecutionResult {
+		*n++
+		return exec.Execute(ctx, input)
+	})
+}
+
+// TestChainOrder verifies the first middleware is the outermost.
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	mark := func(name string) engine.Middleware {
+		return func(next engine.Executor) engine.Executor {
+			return engine.ExecutorFunc(func(ctx context.Context, input engine.ExecutionInput) engine.ExecutionResult {
+				calls = append(calls, name+">")
+				res := next.Execute(ctx, input)
+				calls = append(calls, "<"+name)
+				return res
+			})
+		}
+	}
+	exec := engine.Chain(newExec(), mark("a"), mark("b"))
+	result := exec.Execute(context.Background(), noSelInput("x", `function main(state) { state.text = "y"; }`))
+	if !result.Success || result.NewText != "y" {
+		t.Fatalf("success=%v text=%q err=%q", result.Success, result.NewText, result.ErrorMessage)
+	}
+	if got := strings.Join(calls, " "); got != "a> b> <b <a" {
+		t.Errorf("calls = %s", got)
+	}
+}
+
+// TestMemoize verifies only successful runs of @pure scripts are replayed,
+// and only for identical input.
+func TestMemoize(t *testing.T) {
+	runs := 0
+	exec := engine.Chain(countingExec(&runs), engine.Memoize(1<<20))
+	run := func(text, src string, pure bool) engine.ExecutionResult {
+		inp := noSelInput(text, src)
+		inp.Pure = pure
+		return exec.Execute(context.Background(), inp)
+	}
+	upper := `function main(state) { state.text = state.text.toUpperCase(); }`
+
+	first := run("abc", upper, true)
+	second := run("abc", upper, true)
+	if runs != 1 || !second.Cached || first.Cached || second.NewText != "ABC" {
+		t.Errorf("runs=%d cached=%v/%v text=%q, want one run replayed", runs, first.Cached, second.Cached, second.NewText)
+	}
+
+	run("abd", upper, true)
+	if runs != 2 {
+		t.Errorf("runs=%d, want a run for different input", runs)
+	}
+
+	runs = 0
+	run("abc", upper, false)
+	run("abc", upper, false)
+	if runs != 2 {
+		t.Errorf("runs=%d, want scripts not marked pure to run every time", runs)
+	}
+
+	runs = 0
+	fail := `function main(state) { state.postError("no"); }`
+	run("abc", fail, true)
+	run("abc", fail, true)
+	if runs != 2 {
+		t.Errorf("runs=%d, want failures not to be kept", runs)
+	}
+
+	runs = 0
+	for range 2 {
+		inp := noSelInput("abc", upper)
+		inp.Pure = true
+		inp.Permissions = []string{engine.PermStorage}
+		exec.Execute(context.Background(), inp)
+	}
+	if runs != 2 {
+		t.Errorf("runs=%d, want runs with elevated permissions never replayed", runs)
+	}
+}
+
+// TestMemoizeComposed verifies a @pure script that calls another script is
+// not replayed, so an edit to the called script takes effect.
+func TestMemoizeComposed(t *testing.T) {
+	runs := 0
+	exec := engine.Chain(countingExec(&runs), engine.Memoize(1<<20))
+	sources := map[string]string{"Inner": `function main(s) { s.text = "v1"; }`}
+	run := func() engine.ExecutionResult {
+		inp := composeInput(`function main(state) { state.text = goop.run("Inner", state.text); }`, sources)
+		inp.Pure = true
+		return exec.Execute(context.Background(), inp)
+	}
+
+	if res := run(); !res.Success || res.NewText != "v1" {
+		t.Fatalf("success=%v text=%q err=%q", res.Success, res.NewText, res.ErrorMessage)
+	}
+	sources["Inner"] = `function main(s) { s.text = "v2"; }`
+	res := run()
+	if !res.Success || res.Cached || res.NewText != "v2" {
+		t.Errorf("cached=%v text=%q err=%q, want the edited script's result", res.Cached, res.NewText, res.ErrorMessage)
+	}
+	if runs != 2 {
+		t.Errorf("runs=%d, want 2", runs)
+	}
+}
+
+// TestMemoizeUserModules verifies a @pure script that loads a @user/ module
+// is not replayed, so an edit to the module takes effect.
+func TestMemoizeUserModules(t *testing.T) {
+	runs := 0
+	exec := engine.Chain(countingExec(&runs), engine.Memoize(1<<20))
+	dir := writeLib(t, map[string]string{"v.js": `exports.v = "v1";`})
+	run := func() engine.ExecutionResult {
+		inp := userInput(dir, `var lib = require('@user/v'); function main(state) { state.text = lib.v; }`)
+		inp.Pure = true
+		return exec.Execute(context.Background(), inp)
+	}
+
+	if res := run(); !res.Success || res.NewText != "v1" {
+		t.Fatalf("success=%v text=%q err=%q", res.Success, res.NewText, res.ErrorMessage)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "v.js"), []byte(`exports.v = "v2";`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res := run()
+	if !res.Success || res.Cached || res.NewText != "v2" {
+		t.Errorf("cached=%v text=%q err=%q, want the edited module's result", res.Cached, res.NewText, res.ErrorMessage)
+	}
+	if runs != 2 {
+		t.Errorf("runs=%d, want 2", runs)
+	}
+}
+
+// TestMemoizeBudget verifies the oldest result is dropped once the budget is
+// exceeded.
+func TestMemoizeBudget(t *testing.T) {
+	runs := 0
+	exec := engine.Chain(countingExec(&runs), engine.Memoize(10))
+	src := `function main(state) { state.text = state.text + state.text; }`
+	for _, text := range []string{"aa", "bb", "aa", "cc", "dd", "aa"} {
+		inp := noSelInput(text, src)
+		inp.Pure = true
+		exec.Execute(context.Background(), inp)
+	}
+	// Each result is 4 bytes, so two fit: aa is replayed once, then evicted
+	// by cc and dd.
+	if runs != 5 {
+		t.Errorf("runs = %d, want 5", runs)
+	}
+}
+
+// TestMetrics verifies runs, failures and replays are counted per script.
+func TestMetrics(t *testing.T) {
+	metrics := &engine.Metrics{}
+	exec := engine.Instrument(newExec(), metrics)
+	for _, src := range []string{
+		`function main(state) { state.text = "ok"; }`,
+		`function main(state) { state.text = "ok"; }`,
+		`function main(state) { throw new Error("boom"); }`,
+	} {
+		inp := noSelInput("x", src)
+		inp.Pure = true
+		exec.Execute(context.Background(), inp)
+	}
+	snap := metrics.Snapshot()
+	if len(snap) != 1 {
+		t.Fatalf("got %d scripts, want 1: %+v", len(snap), snap)
+	}
+	m := snap[0]
+	if m.Name != "test-script" || m.Runs != 3 || m.Failures != 1 || m.Cached != 1 {
+		t.Errorf("metrics = %+v", m)
+	}
+	if m.Max <= 0 || m.Mean() <= 0 || m.Mean() > m.Max {
+		t.Errorf("durations: mean %v max %v", m.Mean(), m.Max)
+	}
+
+	var none *engine.Metrics
+	if res := engine.Chain(newExec(), none.Middleware()).Execute(context.Background(),
+		noSelInput("x", `function main(state) {}`)); !res.Success {
+		t.Errorf("nil metrics: %s", res.ErrorMessage)
+	}
+}
@@ -0,0 +1,49 @@
+// Package contract — acceptance tests for state.params.
+package contract_test
+
+import (
+	"context"
+	"testing"
+)
+
+// TestParamsExposedToScript verifies typed parameter values reach the script.
+func TestParamsExposedToScript(t *testing.T) {
+	inp := noSelInput("a\nb", `function main(state) {
+    var p = state.params;
+    state.text = state.text.split("\n").join(p.delim) + typeof p.width + p.width + typeof p.upper;
+}`)
+	inp.Params = map[string]any{"delim": "-", "width": 3.0, "upper": true}
+
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "a-bnumber3boolean" {
+		t.Errorf("NewText = %q", result.NewText)
+	}
+}
+
+// TestParamsReadOnly verifies scripts cannot overwrite their parameters and
+// that state.params is an empty object when none are supplied.
+func TestParamsReadOnly(t *testing.T) {
+	inp := noSelInput("x", `function main(state) {
+    state.params.delim = "changed";
+    state.text = state.params.delim + "," + Object.keys(state.params).length;
+}`)
+	inp.Params = map[string]any{"delim": "orig"}
+
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "orig,1" {
+		t.Errorf("NewText = %q, want %q", result.NewText, "orig,1")
+	}
+
+	result = newExec().Execute(context.Background(), noSelInput("x",
+		`function main(state) { state.text = String(Object.keys(state.params).length); }`))
+	if !result.Success || result.NewText != "0" {
+		t.Errorf("expected empty params object, got success=%v text=%q err=%s",
+			result.Success, result.NewText, result.ErrorMessage)
+	}
+}
@@ -0,0 +1,67 @@
+// Package contract — acceptance tests for permission-gated capabilities.
+package contract_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// TestReadFile verifies state.readFile() reads text files once granted and
+// rejects relative paths.
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "glossary.txt")
+	if err := os.WriteFile(path, []byte("λ lambda"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	inp := noSelInput("", `function main(state) { state.text = state.readFile(`+jsString(path)+`); }`)
+	inp.Permissions = []string{engine.PermReadFile}
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success || result.NewText != "λ lambda" {
+		t.Errorf("got success=%v text=%q err=%s", result.Success, result.NewText, result.ErrorMessage)
+	}
+
+	inp = noSelInput("", `function main(state) { state.readFile("glossary.txt"); }`)
+	inp.Permissions = []string{engine.PermReadFile}
+	result = newExec().Execute(context.Background(), inp)
+	if result.Success || !strings.Contains(result.ErrorMessage, "absolute") {
+		t.Errorf("expected relative path to be rejected, got success=%v err=%s", result.Success, result.ErrorMessage)
+	}
+
+	result = newExec().Execute(context.Background(), noSelInput("",
+		`function main(state) { state.text = typeof state.readFile; }`))
+	if !result.Success || result.NewText != "undefined" {
+		t.Errorf("readFile must be unbound without the permission, got %q", result.NewText)
+	}
+}
+
+// TestLongTimeout verifies the long-timeout permission lifts the caller's
+// timeout.
+func TestLongTimeout(t *testing.T) {
+	src := `function main(state) {
+    var end = Date.now() + 200;
+    while (Date.now() < end) {}
+    state.text = "done";
+}`
+	inp := noSelInput("", src)
+	inp.Timeout = 20 * time.Millisecond
+	if result := newExec().Execute(context.Background(), inp); !result.TimedOut {
+		t.Fatalf("expected timeout without the permission, got success=%v", result.Success)
+	}
+
+	inp.Permissions = []string{engine.PermLongTimeout}
+	if result := newExec().Execute(context.Background(), inp); !result.Success {
+		t.Errorf("expected success with long-timeout, got: %s", result.ErrorMessage)
+	}
+}
+
+// jsString quotes s as a JavaScript string literal.
+func jsString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
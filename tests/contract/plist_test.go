@@ -0,0 +1,124 @@
+// Package contract — acceptance tests for the @boop/plist module.
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"howett.net/plist"
+)
+
+const samplePlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Name</key>
+	<string>goop</string>
+	<key>Count</key>
+	<integer>3</integer>
+	<key>Ratio</key>
+	<real>0.5</real>
+	<key>Enabled</key>
+	<true/>
+	<key>Tags</key>
+	<array><string>a</string><string>b</string></array>
+	<key>Icon</key>
+	<data>aGk=</data>
+	<key>Built</key>
+	<date>2024-01-02T15:04:05Z</date>
+</dict>
+</plist>`
+
+// TestPlistParse verifies XML property lists become plain JS values.
+func TestPlistParse(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(samplePlist, `
+var plist = require('@boop/plist');
+function main(state) { state.text = JSON.stringify(plist.parse(state.text)); }`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := `{"Built":"2024-01-02T15:04:05.000Z","Count":3,"Enabled":true,"Icon":"aGk=","Name":"goop","Ratio":0.5,"Tags":["a","b"]}`
+	if result.NewText != want {
+		t.Errorf("got  %s\nwant %s", result.NewText, want)
+	}
+}
+
+// TestPlistStringify verifies JS values are written as typed XML elements
+// and null values are left out.
+func TestPlistStringify(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var plist = require('@boop/plist');
+function main(state) {
+    state.text = plist.stringify({ n: 3, r: 0.5, s: "x", b: false, none: null,
+        when: new Date(Date.UTC(2024, 0, 2)), raw: new Uint8Array([104, 105]), list: [1, null] });
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	for _, want := range []string{
+		"<key>n</key>\n\t\t<integer>3</integer>",
+		"<real>0.5</real>",
+		"<false/>",
+		"<date>2024-01-02T00:00:00Z</date>",
+		"<data>aGk=</data>",
+		"<array>\n\t\t\t<integer>1</integer>\n\t\t</array>",
+	} {
+		if !strings.Contains(result.NewText, want) {
+			t.Errorf("output lacks %q:\n%s", want, result.NewText)
+		}
+	}
+	if strings.Contains(result.NewText, "none") {
+		t.Errorf("null value was written:\n%s", result.NewText)
+	}
+}
+
+// TestPlistBinaryRoundTrip verifies binary property lists in both
+// directions, given as base64 or as bytes.
+func TestPlistBinaryRoundTrip(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var plist = require('@boop/plist');
+function main(state) {
+    var b64 = plist.stringifyBinary({ name: "goop", n: [1, 2] });
+    if (atob(b64).slice(0, 8) !== "bplist00") throw new Error("not a binary plist");
+    state.text = JSON.stringify(plist.parseBinary(b64));
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if want := `{"n":[1,2],"name":"goop"}`; result.NewText != want {
+		t.Errorf("got %s, want %s", result.NewText, want)
+	}
+
+	data, err := plist.Marshal(map[string]any{"name": "goop", "size": 7}, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inp := noSelInput("", `
+var plist = require('@boop/plist');
+function main(state) { state.text = JSON.stringify(plist.parseBinary(state.bytes)); }`)
+	inp.Data = data
+	result = newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if want := `{"name":"goop","size":7}`; result.NewFullText != want {
+		t.Errorf("got %s, want %s", result.NewFullText, want)
+	}
+}
+
+// TestPlistErrors verifies malformed input and unsupported values throw.
+func TestPlistErrors(t *testing.T) {
+	for _, src := range []string{
+		`require('@boop/plist').parse("<plist><dict><key>a</key></plist>");`,
+		`require('@boop/plist').parseBinary("not base64!");`,
+		`require('@boop/plist').stringify(null);`,
+		`require('@boop/plist').stringify({ f: function () {} });`,
+	} {
+		result := newExec().Execute(context.Background(), noSelInput("",
+			"function main(state) { "+src+" }"))
+		if result.Success {
+			t.Errorf("%s: expected an error", src)
+		}
+	}
+}
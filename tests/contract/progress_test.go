@@ -0,0 +1,57 @@
+// Package contract — acceptance tests for state.progress().
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+type progressReport struct {
+	fraction float64
+	message  string
+}
+
+// TestProgress verifies reports are throttled, clamped and always include
+// completion.
+func TestProgress(t *testing.T) {
+	var got []progressReport
+	inp := noSelInput("x", `function main(state) {
+    for (var i = 0; i < 1000; i++) state.progress(i / 1000, "line " + i);
+    state.progress(7);
+}`)
+	inp.Progress = func(f float64, msg string) { got = append(got, progressReport{f, msg}) }
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if len(got) < 2 || len(got) > 10 {
+		t.Fatalf("got %d reports, want a throttled handful: %v", len(got), got)
+	}
+	if got[0] != (progressReport{0, "line 0"}) {
+		t.Errorf("first report = %v", got[0])
+	}
+	if last := got[len(got)-1]; last != (progressReport{1, ""}) {
+		t.Errorf("last report = %v, want completion clamped to 1", last)
+	}
+}
+
+// TestProgressWithoutCallback verifies state.progress is callable without a
+// Progress callback and rejects a missing fraction.
+func TestProgressWithoutCallback(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("x",
+		`function main(state) { state.progress(0.5, "half"); state.text = "ok"; }`))
+	if !result.Success || result.NewText != "ok" {
+		t.Errorf("success=%v text=%q err=%q", result.Success, result.NewText, result.ErrorMessage)
+	}
+
+	result = newExec().Execute(context.Background(), engine.ExecutionInput{
+		ScriptName:   "test-script",
+		ScriptSource: `function main(state) { state.progress(); }`,
+	})
+	if result.Success || !strings.Contains(result.ErrorMessage, "state.progress expects a fraction") {
+		t.Errorf("success=%v err=%q", result.Success, result.ErrorMessage)
+	}
+}
@@ -5,6 +5,7 @@ package contract_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -213,3 +214,82 @@ func TestTC_E11_BtoaRejectsNonLatin1(t *testing.T) {
 		t.Fatal("expected non-empty error message")
 	}
 }
+
+// TC-E-12: state.language exposes the caller's language ID read-only, and the
+// declared output language is reported in the result.
+func TestTC_E12_Language(t *testing.T) {
+	inp := noSelInput("SELECT 1", `function main(state) {
+    state.language = "xml";
+    state.text = state.language;
+}`)
+	inp.Language = "json"
+	inp.OutputLanguage = "sql"
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "json" {
+		t.Errorf("state.language = %q, want %q", result.NewText, "json")
+	}
+	if result.Language != "sql" {
+		t.Errorf("Language = %q, want the @output value %q", result.Language, "sql")
+	}
+}
+
+// TC-E-13: state.setLanguage overrides @output; null withdraws it, and a
+// failed run declares nothing.
+func TestTC_E13_SetLanguage(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{`function main(state) { state.setLanguage(" Markdown "); }`, "markdown"},
+		{`function main(state) { state.setLanguage(null); }`, ""},
+		{`function main(state) { state.setLanguage("css"); state.postError("no"); }`, ""},
+	}
+	for _, tc := range cases {
+		inp := noSelInput("x", tc.src)
+		inp.OutputLanguage = "sql"
+		result := newExec().Execute(context.Background(), inp)
+		if result.Language != tc.want {
+			t.Errorf("%s: Language = %q, want %q", tc.src, result.Language, tc.want)
+		}
+	}
+
+	result := newExec().Execute(context.Background(), noSelInput("x", `function main(state) { state.setLanguage(); }`))
+	if result.Success {
+		t.Error("expected state.setLanguage() without an argument to fail")
+	}
+}
+
+// TC-E-14: a non-zero Seed makes Math.random repeatable, and Now freezes
+// Date.now() and new Date().
+func TestTC_E14_Deterministic(t *testing.T) {
+	src := `function main(state) {
+    var r = [];
+    for (var i = 0; i < 5; i++) r.push(Math.random());
+    state.text = r.join(",") + "|" + Date.now() + "|" + new Date().toISOString();
+}`
+	run := func(seed int64, now time.Time) string {
+		t.Helper()
+		inp := noSelInput("", src)
+		inp.Seed = seed
+		inp.Now = now
+		result := newExec().Execute(context.Background(), inp)
+		if !result.Success {
+			t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+		}
+		return result.NewText
+	}
+	now := time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC)
+	first := run(42, now)
+	if again := run(42, now); again != first {
+		t.Errorf("same seed gave %q, then %q", first, again)
+	}
+	if other := run(43, now); strings.Split(other, "|")[0] == strings.Split(first, "|")[0] {
+		t.Errorf("seeds 42 and 43 gave the same numbers: %q", other)
+	}
+	if !strings.HasSuffix(first, "|1709209800000|2024-02-29T12:30:00.000Z") {
+		t.Errorf("frozen clock not applied: %q", first)
+	}
+}
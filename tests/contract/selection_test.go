@@ -0,0 +1,107 @@
+// Package contract — acceptance tests for state.select() and state.cursor.
+package contract_test
+
+import (
+	"context"
+	"testing"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// TestSelectAfterReplace verifies that a script can leave its output selected.
+func TestSelectAfterReplace(t *testing.T) {
+	result := newExec().Execute(context.Background(), input("say hi", "hi", 4, 6,
+		`function main(state) {
+    state.text = '"' + state.text + '"';
+    state.select(state.selection.start, state.selection.start + state.text.length);
+}`,
+	))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.MutationKind != engine.MutationReplaceSelect || result.NewText != `"hi"` {
+		t.Fatalf("unexpected mutation: kind=%d text=%q", result.MutationKind, result.NewText)
+	}
+	if !result.SelectionSet {
+		t.Fatal("expected SelectionSet=true")
+	}
+	if result.NewSelectionStart != 4 || result.NewSelectionEnd != 8 {
+		t.Errorf("selection = [%d, %d), want [4, 8)", result.NewSelectionStart, result.NewSelectionEnd)
+	}
+}
+
+// TestSelectionRequests covers the different ways of setting the cursor and
+// selection, including the no-op and error cases.
+func TestSelectionRequests(t *testing.T) {
+	cases := []struct {
+		name      string
+		src       string
+		wantOK    bool
+		wantSet   bool
+		wantStart int
+		wantEnd   int
+	}{
+		{"cursor assignment", `function main(state) { state.fullText = "abcdef"; state.cursor = 3; }`, true, true, 3, 3},
+		{"select single offset", `function main(state) { state.select(2); }`, true, true, 2, 2},
+		{"select reversed range", `function main(state) { state.select(5, 1); }`, true, true, 1, 5},
+		{"last call wins", `function main(state) { state.select(0, 4); state.cursor = 1; }`, true, true, 1, 1},
+		{"no request", `function main(state) { state.text = "x"; }`, true, false, 0, 0},
+		{"postError discards selection", `function main(state) { state.select(0, 1); state.postError("no"); }`, false, false, 0, 0},
+		{"negative offset throws", `function main(state) { state.cursor = -1; }`, false, false, 0, 0},
+		{"non-numeric offset throws", `function main(state) { state.select("abc"); }`, false, false, 0, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := newExec().Execute(context.Background(), noSelInput("hello", tc.src))
+			if result.Success != tc.wantOK {
+				t.Fatalf("Success = %v, want %v (error: %s)", result.Success, tc.wantOK, result.ErrorMessage)
+			}
+			if result.SelectionSet != tc.wantSet {
+				t.Fatalf("SelectionSet = %v, want %v", result.SelectionSet, tc.wantSet)
+			}
+			if result.NewSelectionStart != tc.wantStart || result.NewSelectionEnd != tc.wantEnd {
+				t.Errorf("selection = [%d, %d), want [%d, %d)",
+					result.NewSelectionStart, result.NewSelectionEnd, tc.wantStart, tc.wantEnd)
+			}
+		})
+	}
+}
+
+// TestCursorReadsSelectionEnd verifies state.cursor reports the caret before
+// any assignment and the assigned value afterwards.
+func TestCursorReadsSelectionEnd(t *testing.T) {
+	result := newExec().Execute(context.Background(), input("hello world", "world", 6, 11,
+		`function main(state) {
+    var before = state.cursor;
+    state.cursor = 2;
+    state.postInfo(before + "," + state.cursor);
+}`,
+	))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.InfoMessage != "11,2" {
+		t.Errorf("InfoMessage = %q, want %q", result.InfoMessage, "11,2")
+	}
+}
+
+// TestSelectionStaysReadOnly verifies state.selection cannot be modified, so
+// Boop scripts that accidentally write to it keep their upstream behaviour.
+func TestSelectionStaysReadOnly(t *testing.T) {
+	result := newExec().Execute(context.Background(), input("hello", "ell", 1, 4,
+		`function main(state) {
+    state.selection.start = 0;
+    state.postInfo(String(state.selection.start));
+}`,
+	))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.InfoMessage != "1" {
+		t.Errorf("state.selection.start = %q after assignment, want %q", result.InfoMessage, "1")
+	}
+	if result.SelectionSet {
+		t.Error("writing state.selection must not request a new selection")
+	}
+}
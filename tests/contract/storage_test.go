@@ -0,0 +1,128 @@
+// Package contract — acceptance tests for state.storage.
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// storageInput returns an input for src with storage for a script keyed
+// "counter.js" in dir and the storage permission granted.
+func storageInput(dir, src string) engine.ExecutionInput {
+	inp := noSelInput("", src)
+	inp.Storage = engine.NewStorage(dir, "counter.js", "Counter")
+	inp.Permissions = []string{engine.PermStorage}
+	return inp
+}
+
+// TestStoragePersistsAcrossRuns verifies values written in one run are read
+// back in the next.
+func TestStoragePersistsAcrossRuns(t *testing.T) {
+	dir := t.TempDir()
+	src := `function main(state) {
+    var n = state.storage.get("count", 0) + 1;
+    state.storage.set("count", n);
+    state.storage.set("meta", { last: n, tags: ["a"] });
+    state.text = String(n);
+}`
+	for want := 1; want <= 3; want++ {
+		result := newExec().Execute(context.Background(), storageInput(dir, src))
+		if !result.Success {
+			t.Fatalf("run %d: %s", want, result.ErrorMessage)
+		}
+		if result.NewText != string(rune('0'+want)) {
+			t.Errorf("run %d: NewText = %q", want, result.NewText)
+		}
+	}
+
+	infos, err := engine.ListStorage(dir)
+	if err != nil {
+		t.Fatalf("ListStorage: %v", err)
+	}
+	if len(infos) != 1 || infos[0].ScriptName != "Counter" || strings.Join(infos[0].Keys, ",") != "count,meta" {
+		t.Errorf("ListStorage = %+v", infos)
+	}
+}
+
+// TestStorageDiscardedOnError verifies an uncaught exception discards every
+// change made during the run.
+func TestStorageDiscardedOnError(t *testing.T) {
+	dir := t.TempDir()
+	result := newExec().Execute(context.Background(), storageInput(dir,
+		`function main(state) { state.storage.set("k", 1); throw new Error("boom"); }`))
+	if result.Success {
+		t.Fatal("expected failure")
+	}
+
+	result = newExec().Execute(context.Background(), storageInput(dir,
+		`function main(state) { state.text = String(state.storage.get("k")); }`))
+	if !result.Success || result.NewText != "undefined" {
+		t.Errorf("expected discarded write, got success=%v text=%q err=%s",
+			result.Success, result.NewText, result.ErrorMessage)
+	}
+}
+
+// TestStorageKeysDeleteClear verifies keys(), delete() and clear().
+func TestStorageKeysDeleteClear(t *testing.T) {
+	dir := t.TempDir()
+	result := newExec().Execute(context.Background(), storageInput(dir, `function main(state) {
+    var s = state.storage;
+    s.set("b", 2); s.set("a", 1); s.set("c", 3);
+    var out = s.keys().join(",") + ";" + s.delete("b") + s.delete("zzz") + ";" + s.keys().join(",");
+    s.clear();
+    state.text = out + ";" + s.keys().length;
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "a,b,c;truefalse;a,c;0" {
+		t.Errorf("NewText = %q", result.NewText)
+	}
+	if infos, _ := engine.ListStorage(dir); len(infos) != 0 {
+		t.Errorf("expected no storage file after clear, got %+v", infos)
+	}
+}
+
+// TestStorageQuota verifies writes beyond the quota throw and leave the
+// store unchanged.
+func TestStorageQuota(t *testing.T) {
+	dir := t.TempDir()
+	result := newExec().Execute(context.Background(), storageInput(dir, `function main(state) {
+    state.storage.set("small", "ok");
+    try {
+        state.storage.set("big", "x".repeat(2 * 1024 * 1024));
+    } catch (e) {
+        state.text = "caught:" + state.storage.keys().join(",");
+    }
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "caught:small" {
+		t.Errorf("NewText = %q", result.NewText)
+	}
+}
+
+// TestStorageUnavailable verifies state.storage is undefined without a store
+// or without the storage permission, and that non-serialisable values are
+// rejected.
+func TestStorageUnavailable(t *testing.T) {
+	src := `function main(state) { state.text = typeof state.storage; }`
+	ungranted := storageInput(t.TempDir(), src)
+	ungranted.Permissions = nil
+	for _, inp := range []engine.ExecutionInput{noSelInput("", src), ungranted} {
+		result := newExec().Execute(context.Background(), inp)
+		if !result.Success || result.NewText != "undefined" {
+			t.Errorf("got success=%v text=%q err=%s", result.Success, result.NewText, result.ErrorMessage)
+		}
+	}
+
+	result := newExec().Execute(context.Background(), storageInput(t.TempDir(),
+		`function main(state) { state.storage.set("f", function() {}); }`))
+	if result.Success {
+		t.Error("expected storing a function to fail")
+	}
+}
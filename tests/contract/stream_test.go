@@ -0,0 +1,189 @@
+// Package contract — acceptance tests for streaming mapLine/mapRecord scripts.
+package contract_test
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// stream runs src in streaming mode over in and returns the result and what
+// the script wrote.
+func stream(t *testing.T, src, in string) (engine.ExecutionResult, string) {
+	t.Helper()
+	var out strings.Builder
+	inp := engine.ExecutionInput{
+		ScriptName:   "test-script",
+		ScriptSource: src,
+		Timeout:      5 * time.Second,
+		Stream:       &engine.StreamIO{In: strings.NewReader(in), Out: &out},
+	}
+	return newExec().Execute(context.Background(), inp), out.String()
+}
+
+// TestStreamMapLine verifies lines are mapped one at a time, null drops a
+// line, an array emits several, and the trailing newline follows the input.
+func TestStreamMapLine(t *testing.T) {
+	src := `function mapLine(line) {
+    if (line === "drop") return null;
+    if (line === "twice") return [line, line];
+    return line.toUpperCase();
+}`
+	cases := []struct{ in, want string }{
+		{"a\ndrop\nb\n", "A\nB\n"},
+		{"a\r\ntwice", "A\ntwice\ntwice"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		result, out := stream(t, src, c.in)
+		if !result.Success {
+			t.Fatalf("%q: expected success, got error: %s", c.in, result.ErrorMessage)
+		}
+		if out != c.want {
+			t.Errorf("%q: got %q, want %q", c.in, out, c.want)
+		}
+		if result.MutationKind != engine.MutationNone {
+			t.Errorf("%q: streaming run reported mutation %v", c.in, result.MutationKind)
+		}
+	}
+}
+
+// TestStreamMapRecord verifies newline-delimited JSON records are parsed and
+// re-serialised, skipping blank lines.
+func TestStreamMapRecord(t *testing.T) {
+	result, out := stream(t, `function mapRecord(r) {
+    if (r.skip) return undefined;
+    r.n = r.n * 2;
+    return r;
+}`, "{\"n\":1}\n\n{\"skip\":true}\n{\"n\":2}\n")
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if want := "{\"n\":2}\n{\"n\":4}\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	result, _ = stream(t, `function mapRecord(r) { return r; }`, "{\"n\":1}\nnot json\n")
+	if result.Success || !strings.Contains(result.ErrorMessage, "line 2:") {
+		t.Errorf("success=%v err=%q, want a parse error on line 2", result.Success, result.ErrorMessage)
+	}
+}
+
+// TestStreamErrors verifies failures name the line they happened on and that
+// scripts without mapLine or mapRecord cannot stream.
+func TestStreamErrors(t *testing.T) {
+	result, out := stream(t, `function mapLine(line, state) {
+    if (line === "bad") state.postError("bad line");
+    return line;
+}`, "ok\nbad\nok\n")
+	if result.Success || result.ErrorMessage != "line 2: bad line" || out != "ok" {
+		t.Errorf("success=%v err=%q out=%q", result.Success, result.ErrorMessage, out)
+	}
+
+	result, out = stream(t, `function mapLine(line) { if (line === "x") throw new Error("boom"); return line; }`, "a\nx\n")
+	if result.Success || !strings.Contains(result.ErrorMessage, "line 2:") || !strings.Contains(result.ErrorMessage, "boom") {
+		t.Errorf("success=%v err=%q", result.Success, result.ErrorMessage)
+	}
+	if out != "a" {
+		t.Errorf("got output %q, want the lines before the error kept", out)
+	}
+
+	result, _ = stream(t, `function main(state) { state.text = "x"; }`, "x\n")
+	if result.Success || !strings.Contains(result.ErrorMessage, "cannot stream") {
+		t.Errorf("success=%v err=%q", result.Success, result.ErrorMessage)
+	}
+}
+
+// TestStreamTimeoutPerLine verifies the timeout bounds each line rather than
+// the whole stream.
+func TestStreamTimeoutPerLine(t *testing.T) {
+	var out strings.Builder
+	inp := engine.ExecutionInput{
+		ScriptName: "test-script",
+		ScriptSource: `function mapLine(line) {
+    var end = Date.now() + 30;
+    while (Date.now() < end) {}
+    return line;
+}`,
+		Timeout: 100 * time.Millisecond,
+		Stream:  &engine.StreamIO{In: strings.NewReader(strings.Repeat("x\n", 10)), Out: &out},
+	}
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if out.Len() != 20 {
+		t.Errorf("got %d bytes of output, want 20", out.Len())
+	}
+
+	inp.ScriptSource = `function mapLine(line) { while (true) {} }`
+	inp.Stream = &engine.StreamIO{In: strings.NewReader("x\n"), Out: &out}
+	if result := newExec().Execute(context.Background(), inp); result.Success {
+		t.Error("expected a runaway line to time out")
+	}
+}
+
+// slowReader hands out one chunk per Read, waiting delay before each after the
+// first, like a producer such as tail -f. It records the output written so
+// far at each Read.
+type slowReader struct {
+	chunks []string
+	delay  time.Duration
+	out    *strings.Builder
+	seen   []string
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	r.seen = append(r.seen, r.out.String())
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	if len(r.seen) > 1 {
+		time.Sleep(r.delay)
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+// TestStreamSlowProducer verifies waiting for input does not count towards
+// the per-line timeout, and each line's output is written before the next
+// line is waited for.
+func TestStreamSlowProducer(t *testing.T) {
+	var out strings.Builder
+	in := &slowReader{chunks: []string{"a\n", "b\n", "c\n"}, delay: 300 * time.Millisecond, out: &out}
+	inp := engine.ExecutionInput{
+		ScriptName:   "test-script",
+		ScriptSource: `function mapLine(line) { return line.toUpperCase(); }`,
+		Timeout:      100 * time.Millisecond,
+		Stream:       &engine.StreamIO{In: in, Out: &out},
+	}
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success || result.TimedOut {
+		t.Fatalf("success=%v timedOut=%v err=%q", result.Success, result.TimedOut, result.ErrorMessage)
+	}
+	if out.String() != "A\nB\nC\n" {
+		t.Errorf("got %q", out.String())
+	}
+	if want := []string{"", "A", "A\nB", "A\nB\nC"}; !slices.Equal(in.seen, want) {
+		t.Errorf("output at each read = %q, want %q", in.seen, want)
+	}
+}
+
+// TestStreamInEditor verifies a streaming script also runs on a document
+// without a main function.
+func TestStreamInEditor(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("a\nb\n",
+		`function mapLine(line) { return "> " + line; }`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != "> a\n> b\n" {
+		t.Errorf("got %q", result.NewText)
+	}
+}
@@ -0,0 +1,258 @@
+// Package contract — acceptance tests for the @boop/time module.
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runTime runs src with the clock fixed at 2024-02-29 12:30 UTC.
+func runTime(t *testing.T, src string) string {
+	t.Helper()
+	inp := noSelInput("", "var time = require('@boop/time');\n"+src)
+	inp.Now = time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC)
+	result := newExec().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	return result.NewText
+}
+
+// TestTimeParse verifies the forms parse recognises, each as an instant.
+func TestTimeParse(t *testing.T) {
+	got := runTime(t, `
+function main(state) {
+    state.text = [
+        '2024-01-02T15:04:05Z',
+        '2024-01-02T15:04:05.123456+02:00',
+        '2024-01-02 15:04:05',
+        '2024-01-02',
+        'Tue, 02 Jan 2024 15:04:05 GMT',
+        'Tue, 2 Jan 2024 15:04:05 -0800 (PST)',
+        'Tue, 02 Jan 2024 15:04:05 PST',
+        '[02/Jan/2024:15:04:05 +0100]',
+        'Jan  2 15:04:05',
+        'Tue Jan  2 15:04:05 2024',
+        'Tue Jan 02 2024 15:04:05 GMT+0100 (Central European Standard Time)',
+        '1704207845',
+        '1704207845123',
+        '1704207845123456',
+        '1704207845.5'
+    ].map(function (s) {
+        return time.parse(s, {zone: 'America/New_York'}).toISOString();
+    }).join("\n");
+}`)
+	want := strings.Join([]string{
+		"2024-01-02T15:04:05.000Z",
+		"2024-01-02T13:04:05.123Z",
+		"2024-01-02T20:04:05.000Z",
+		"2024-01-02T05:00:00.000Z",
+		"2024-01-02T15:04:05.000Z",
+		"2024-01-02T23:04:05.000Z",
+		"2024-01-02T23:04:05.000Z",
+		"2024-01-02T14:04:05.000Z",
+		"2024-01-02T20:04:05.000Z",
+		"2024-01-02T20:04:05.000Z",
+		"2024-01-02T14:04:05.000Z",
+		"2024-01-02T15:04:05.000Z",
+		"2024-01-02T15:04:05.123Z",
+		"2024-01-02T15:04:05.123Z",
+		"2024-01-02T15:04:05.500Z",
+	}, "\n")
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestTimeFormat verifies named layouts, strftime and Go layouts, and zone
+// conversion including daylight saving time.
+func TestTimeFormat(t *testing.T) {
+	got := runTime(t, `
+function main(state) {
+    var d = time.parse('2024-07-04T09:05:06.789Z');
+    state.text = [
+        time.format(d, 'RFC3339', {zone: 'UTC'}),
+        time.format(d, 'rfc3339', {zone: 'Europe/Berlin'}),
+        time.format(d, 'ISO8601', {zone: '+05:30'}),
+        time.format(d, 'RFC1123', {zone: 'America/Los_Angeles'}),
+        time.format(d, 'http', {zone: 'Asia/Tokyo'}),
+        time.format(d, 'clf', {zone: 'UTC'}),
+        time.format(d, 'unix'),
+        time.format(d, 'unixms'),
+        time.format(d, '%Y-%m-%d %H:%M:%S.%L %z %Z %j %a %u %s %%', {zone: 'Australia/Adelaide'}),
+        time.format(d, 'Monday, January 2 2006 3:04PM', {zone: 'UTC'}),
+        time.format('2024-12-01 10:00', 'RFC3339', {zone: 'Europe/Berlin'}),
+        time.format(0, 'DateTime', {zone: 'UTC'}),
+        JSON.stringify(time.offset('Europe/Berlin', d)),
+        JSON.stringify(time.offset('Europe/Berlin'))
+    ].join("\n");
+}`)
+	want := strings.Join([]string{
+		"2024-07-04T09:05:06Z",
+		"2024-07-04T11:05:06+02:00",
+		"2024-07-04T14:35:06.789+05:30",
+		"Thu, 04 Jul 2024 02:05:06 PDT",
+		"Thu, 04 Jul 2024 09:05:06 GMT",
+		"04/Jul/2024:09:05:06 +0000",
+		"1720083906",
+		"1720083906789",
+		"2024-07-04 18:35:06.789 +0930 ACST 186 Thu 4 1720083906 %",
+		"Thursday, July 4 2024 9:05AM",
+		"2024-12-01T10:00:00+01:00",
+		"1970-01-01 00:00:00",
+		`{"name":"CEST","offset":120,"dst":true}`,
+		`{"name":"CET","offset":60,"dst":false}`,
+	}, "\n")
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestTimeParseLayout verifies parsing with strftime and Go layouts and with
+// an explicit unit.
+func TestTimeParseLayout(t *testing.T) {
+	got := runTime(t, `
+function main(state) {
+    state.text = [
+        time.parse('04.07.2024 09:05', {layout: '%d.%m.%Y %H:%M', zone: 'UTC'}),
+        time.parse('July 4, 2024 at 9:05am', {layout: 'January 2, 2006 at 3:04pm', zone: 'Europe/Berlin'}),
+        time.parse('1720083906', {unit: 'ms'}),
+        time.now()
+    ].map(function (d) { return d.toISOString(); }).join("\n");
+}`)
+	want := strings.Join([]string{
+		"2024-07-04T09:05:00.000Z",
+		"2024-07-04T07:05:00.000Z",
+		"1970-01-20T21:48:03.906Z",
+		"2024-02-29T12:30:00.000Z",
+	}, "\n")
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestTimeDurations verifies parsing and formatting durations and date
+// arithmetic.
+func TestTimeDurations(t *testing.T) {
+	got := runTime(t, `
+function main(state) {
+    var d = 93784500;
+    state.text = [
+        ['1h30m', '1:30:00', '1:30', 'PT1H30M', '1 hour, 30 minutes', '1.5 hours', '1h 30m', '90 mins'].map(time.parseDuration).join(),
+        time.parseDuration('-P1DT0.5S'),
+        time.parseDuration('1 week and 2 days'),
+        time.parseDuration('250ms'),
+        time.formatDuration(d),
+        time.formatDuration(d, {style: 'long'}),
+        time.formatDuration(-d, {style: 'clock'}),
+        time.formatDuration(d, {style: 'iso'}),
+        time.formatDuration(d, {style: 'go'}),
+        time.formatDuration(0) + ' ' + time.formatDuration(0, {style: 'long'}) + ' ' + time.formatDuration(0, {style: 'iso'}),
+        time.formatDuration('61s', {style: 'long'}),
+        time.add('2024-03-30T12:00:00Z', '1 day').toISOString(),
+        time.diff('2024-03-01T00:00:00Z', time.now()),
+        time.formatDuration(time.diff('2024-12-25', '2024-12-24 18:30'))
+    ].join("\n");
+}`)
+	want := strings.Join([]string{
+		"5400000,5400000,5400000,5400000,5400000,5400000,5400000,5400000",
+		"-86400500",
+		"777600000",
+		"250",
+		"1d 2h 3m 4.5s",
+		"1 day, 2 hours, 3 minutes and 4.5 seconds",
+		"-26:03:04.5",
+		"P1DT2H3M4.5S",
+		"26h3m4.5s",
+		"0s 0 seconds PT0S",
+		"1 minute and 1 second",
+		"2024-03-31T12:00:00.000Z",
+		"41400000",
+		"5h 30m",
+	}, "\n")
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestTimeReplaceAll verifies that timestamps in free text are found and
+// rewritten, and that numbers that are unlikely to be times are left alone.
+func TestTimeReplaceAll(t *testing.T) {
+	got := runTime(t, `
+function main(state) {
+    var log = "2024-01-02T15:04:05+01:00 start\n" +
+        "127.0.0.1 - - [02/Jan/2024:15:04:05 +0000] \"GET / HTTP/1.1\" 200 1234567890123456\n" +
+        "Jan  2 15:04:05 host sshd: id 1704207845 at 1704207845123, port 22, code 9999999999\n" +
+        "Date: Tue, 02 Jan 2024 15:04:05 GMT; built 2024-01-02 15:04:05 UTC";
+    var kinds = [];
+    var out = time.replaceAll(log, function (m) {
+        kinds.push(m.kind);
+        return time.format(m.date, '%H:%M', {zone: 'UTC'});
+    }, {zone: 'UTC'});
+    state.text = out + "\n" + kinds.join() + "\n" +
+        time.replaceAll('at 1704207845', function () { return 'x'; }, {unix: false});
+}`)
+	want := "14:04 start\n" +
+		"127.0.0.1 - - [15:04] \"GET / HTTP/1.1\" 200 1234567890123456\n" +
+		"15:04 host sshd: id 15:04 at 15:04, port 22, code 9999999999\n" +
+		"Date: 15:04; built 15:04\n" +
+		"iso,clf,syslog,unix,unixms,rfc1123,iso\n" +
+		"at 1704207845"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestTimeReplaceAllISOForms verifies every ISO form replaceAll finds is
+// converted, including offsets written without a colon as many logs do.
+func TestTimeReplaceAllISOForms(t *testing.T) {
+	stamps := []string{
+		"2024-01-15 10:00:00-0500",
+		"2024-01-15T10:00:00.123456-0500",
+		"2024-01-15 10:00:00.5-05:00",
+		"2024-01-15 10:00-0500",
+		"2024-01-15 10:00-05:00",
+		"2024-01-15T10:00-0500",
+		"2024-01-15T10:00-05:00",
+		"2024-01-15 15:00 UTC",
+		"2024-01-15 15:00:00Z",
+		"2024-01-15T15:00Z",
+	}
+	got := runTime(t, `
+function main(state) {
+    state.text = time.replaceAll(`+"`"+strings.Join(stamps, "\n")+"`"+`, function (m) {
+        return time.format(m.date, '%H:%M', {zone: 'UTC'});
+    });
+}`)
+	if want := strings.TrimSuffix(strings.Repeat("15:00\n", len(stamps)), "\n"); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestTimeErrors verifies that invalid input and options are reported.
+func TestTimeErrors(t *testing.T) {
+	for name, call := range map[string]string{
+		"unreadable":     `time.parse('next tuesday')`,
+		"unknown zone":   `time.format(0, 'RFC3339', {zone: 'Mars/Olympus'})`,
+		"bad offset":     `time.parse('2024-01-02', {zone: '+25:00'})`,
+		"unknown unit":   `time.parse('1', {unit: 'days'})`,
+		"layout":         `time.parse('2024', {layout: '%d.%m.%Y'})`,
+		"strftime parse": `time.parse('1', {layout: '%s'})`,
+		"duration":       `time.parseDuration('soon')`,
+		"duration unit":  `time.parseDuration('3 fortnights')`,
+		"iso months":     `time.parseDuration('P1M')`,
+		"style":          `time.formatDuration(1, {style: 'roman'})`,
+		"not a function": `time.replaceAll('x', 'y')`,
+		"throwing fn":    `time.replaceAll('2024-01-02', function () { throw new Error('no'); })`,
+		"bad time":       `time.format({})`,
+	} {
+		inp := noSelInput("", `
+var time = require('@boop/time');
+function main(state) { `+call+`; }`)
+		if result := newExec().Execute(context.Background(), inp); result.Success {
+			t.Errorf("%s: expected %s to fail", name, call)
+		}
+	}
+}
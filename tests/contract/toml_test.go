@@ -0,0 +1,164 @@
+// Package contract — acceptance tests for the @boop/toml module.
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const sampleTOML = `title = "goop"
+ratio = 0.5
+big = 9007199254740991
+released = 2024-01-02T15:04:05Z
+day = 2024-01-02
+at = 07:32:00
+
+[owner]
+name = "sigterm"
+
+[[bin]]
+name = "goop"
+`
+
+// TestTOMLParse verifies types, tables and arrays of tables become JS values,
+// with keys in document order.
+func TestTOMLParse(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(sampleTOML, `
+var toml = require('@boop/toml');
+function main(state) {
+    var doc = toml.parse(state.text);
+    if (!(doc.released instanceof Date)) throw new Error("released is not a Date");
+    if (String(doc.day) !== "2024-01-02") throw new Error("day reads as " + doc.day);
+    state.text = JSON.stringify(doc);
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := `{"title":"goop","ratio":0.5,"big":9007199254740991,"released":"2024-01-02T15:04:05.000Z",` +
+		`"day":"2024-01-02","at":"07:32:00","owner":{"name":"sigterm"},"bin":[{"name":"goop"}]}`
+	if result.NewText != want {
+		t.Errorf("got  %s\nwant %s", result.NewText, want)
+	}
+}
+
+// TestTOMLRoundTrip verifies dates, local dates and integers survive a parse
+// and stringify round trip.
+func TestTOMLRoundTrip(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(sampleTOML, `
+var toml = require('@boop/toml');
+function main(state) { state.text = toml.stringify(toml.parse(state.text)); }`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	for _, want := range []string{
+		`title = "goop"`,
+		"ratio = 0.5",
+		"big = 9007199254740991",
+		"released = 2024-01-02T15:04:05Z",
+		"day = 2024-01-02\n",
+		"at = 07:32:00\n",
+		"[owner]\nname = \"sigterm\"",
+		"[[bin]]\nname = \"goop\"",
+	} {
+		if !strings.Contains(result.NewText, want) {
+			t.Errorf("output lacks %q:\n%s", want, result.NewText)
+		}
+	}
+}
+
+// TestTOMLOrderAndFloats verifies a document with unsorted keys and
+// whole-valued floats, laid out as stringify writes it, round-trips unchanged,
+// and that floats stay floats after a script edits the document.
+func TestTOMLOrderAndFloats(t *testing.T) {
+	doc := `name = "goop"
+version = 1.0
+edition = 2024
+weights = [1.0, 2.5, 3]
+
+[point]
+y = 1
+x = 2.0
+
+[tool.zeta]
+b = 1
+a = 2.0
+
+[[bin]]
+name = "goop"
+scale = 3.0
+`
+	result := newExec().Execute(context.Background(), noSelInput(doc, `
+var toml = require('@boop/toml');
+function main(state) {
+    var d = toml.parse(state.text);
+    if (toml.stringify(d) !== state.text) throw new Error("not a round trip:\n" + toml.stringify(d));
+    d.version = 2;
+    d.tool.zeta.c = 4;
+    state.text = toml.stringify(d);
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	for _, want := range []string{"version = 2.0\n", "b = 1\na = 2.0\nc = 4\n", "scale = 3.0\n"} {
+		if !strings.Contains(result.NewText, want) {
+			t.Errorf("output lacks %q:\n%s", want, result.NewText)
+		}
+	}
+}
+
+// TestTOMLQuotedKeys verifies keys that must be quoted keep their order and
+// round-trip, in tables and section headers alike.
+func TestTOMLQuotedKeys(t *testing.T) {
+	doc := `"" = 1
+- = 2
+"a,b" = 3
+z = "x\ty"
+
+["a.b"."c d"]
+- = 4
+"" = 5
+`
+	result := newExec().Execute(context.Background(), noSelInput(doc, `
+var toml = require('@boop/toml');
+function main(state) {
+    var d = toml.parse(state.text);
+    state.text = Object.keys(d).join("|") + "\n" + toml.stringify(d);
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if want := "|-|a,b|z|a.b\n" + doc; result.NewText != want {
+		t.Errorf("got %q, want %q", result.NewText, want)
+	}
+}
+
+// TestTOMLStringify verifies numbers keep integer or float form and null
+// values are left out.
+func TestTOMLStringify(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var toml = require('@boop/toml');
+function main(state) { state.text = toml.stringify({ port: 8080, ratio: 1.5, none: null, when: new Date(Date.UTC(2024, 0, 2)) }); }`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := "port = 8080\nratio = 1.5\nwhen = 2024-01-02T00:00:00Z\n"
+	if result.NewText != want {
+		t.Errorf("got %q, want %q", result.NewText, want)
+	}
+}
+
+// TestTOMLErrors verifies malformed documents and non-object roots throw.
+func TestTOMLErrors(t *testing.T) {
+	for _, src := range []string{
+		`require('@boop/toml').parse("a = ");`,
+		`require('@boop/toml').stringify([1, 2]);`,
+		`require('@boop/toml').stringify({ f: function () {} });`,
+	} {
+		result := newExec().Execute(context.Background(), noSelInput("",
+			"function main(state) { "+src+" }"))
+		if result.Success {
+			t.Errorf("%s: expected an error", src)
+		}
+	}
+}
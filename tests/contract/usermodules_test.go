@@ -0,0 +1,119 @@
+// Package contract — acceptance tests for require('@user/...') modules.
+package contract_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// writeLib creates a user library directory containing files.
+func writeLib(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// userInput returns an input for src with the given user library directory.
+func userInput(libDir, src string) engine.ExecutionInput {
+	inp := noSelInput("a,b", src)
+	inp.LibDir = libDir
+	return inp
+}
+
+// TestUserModuleRequire verifies @user/ modules load from the library
+// directory, including nested, relative and index.js requires, and that a
+// module shared by two others is loaded once.
+func TestUserModuleRequire(t *testing.T) {
+	dir := writeLib(t, map[string]string{
+		"csv.js":       `var q = require('./quote'); exports.row = function(f) { return f.map(q.quote).join(","); };`,
+		"quote.js":     `exports.loads = (exports.loads || 0) + 1; exports.quote = function(s) { return '"' + s + '"'; };`,
+		"ids/index.js": `var q = require('@user/quote'); exports.tag = function(s) { return "ID-" + s; }; exports.q = q;`,
+	})
+	result := newExec().Execute(context.Background(), userInput(dir, `function main(state) {
+    var csv = require('@user/csv'), ids = require('@user/ids');
+    state.text = csv.row(state.text.split(",")) + " " + ids.tag("7") + " " + ids.q.loads;
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != `"a","b" ID-7 1` {
+		t.Errorf("NewText = %q", result.NewText)
+	}
+}
+
+// TestUserModuleNotFound verifies a missing module names the locations
+// searched.
+func TestUserModuleNotFound(t *testing.T) {
+	dir := writeLib(t, nil)
+	result := newExec().Execute(context.Background(), userInput(dir,
+		`function main(state) { require('@user/missing'); }`))
+	if result.Success {
+		t.Fatal("expected failure")
+	}
+	for _, want := range []string{"Cannot find module '@user/missing'", filepath.Join(dir, "missing.js"), filepath.Join(dir, "missing", "index.js")} {
+		if !strings.Contains(result.ErrorMessage, want) {
+			t.Errorf("error %q does not mention %q", result.ErrorMessage, want)
+		}
+	}
+}
+
+// TestUserModuleCycle verifies circular requires are reported with the chain.
+func TestUserModuleCycle(t *testing.T) {
+	dir := writeLib(t, map[string]string{
+		"a.js": `require('@user/b');`,
+		"b.js": `require('./a');`,
+	})
+	result := newExec().Execute(context.Background(), userInput(dir,
+		`function main(state) { require('@user/a'); }`))
+	if result.Success {
+		t.Fatal("expected failure")
+	}
+	if !strings.Contains(result.ErrorMessage, "@user/a -> @user/b -> @user/a") {
+		t.Errorf("error %q does not show the cycle", result.ErrorMessage)
+	}
+}
+
+// TestUserModuleSandbox verifies non-namespaced and escaping paths stay
+// blocked, oversized modules are rejected and @user/ is unavailable without
+// a library directory.
+func TestUserModuleSandbox(t *testing.T) {
+	dir := writeLib(t, map[string]string{
+		"big.js":    "// " + strings.Repeat("x", 5*1024*1024),
+		"escape.js": `require('../secret');`,
+	})
+	if err := os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.js"), []byte(`exports.x = 1;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct{ name, lib, req string }{
+		{"bare name", dir, "big"},
+		{"relative from script", dir, "./escape"},
+		{"escape from module", dir, "@user/escape"},
+		{"escape in name", dir, "@user/../secret"},
+		{"oversized", dir, "@user/big"},
+		{"no lib dir", "", "@user/escape"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := newExec().Execute(context.Background(), userInput(tc.lib,
+				`function main(state) { require(`+jsString(tc.req)+`); }`))
+			if result.Success {
+				t.Errorf("require(%q) should fail", tc.req)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Package contract — acceptance tests for the @boop/xml module.
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const catalogXML = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- stock -->
+<catalog xmlns="urn:books" xmlns:p="urn:price">
+  <book id="1" lang="en">
+    <title>Go &amp; You</title>
+    <p:price currency="EUR">10</p:price>
+    <note><![CDATA[<b>bold</b>]]></note>
+  </book>
+  <book id="2">
+    <title>Mixed <i>content</i> here</title>
+    <p:price>20</p:price>
+  </book>
+</catalog>`
+
+// TestXMLParse verifies the object model: node types, attributes in order,
+// namespaces resolved from their prefixes, CDATA kept apart from text.
+func TestXMLParse(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(catalogXML, `
+var xml = require('@boop/xml');
+function main(state) {
+    var doc = xml.parse(state.text);
+    var root = doc.children[2];
+    var book = root.children[0];
+    state.text = JSON.stringify([
+        doc.type, doc.children[0].target, doc.children[1],
+        root.name, root.namespace, Object.keys(book.attributes),
+        book.children[1].name, book.children[1].namespace,
+        book.children[0].children[0], book.children[2].children[0]
+    ]);
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := `["document","xml",{"type":"comment","text":" stock "},"catalog","urn:books",["id","lang"],` +
+		`"p:price","urn:price",{"type":"text","text":"Go & You"},{"type":"cdata","text":"<b>bold</b>"}]`
+	if result.NewText != want {
+		t.Errorf("got  %s\nwant %s", result.NewText, want)
+	}
+}
+
+// TestXMLRoundTrip verifies stringify writes a parsed document back as it
+// was read, keeping mixed content on one line.
+func TestXMLRoundTrip(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(catalogXML, `
+var xml = require('@boop/xml');
+function main(state) { state.text = xml.stringify(xml.parse(state.text)); }`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != catalogXML {
+		t.Errorf("got:\n%s\nwant:\n%s", result.NewText, catalogXML)
+	}
+
+	result = newExec().Execute(context.Background(), noSelInput("", `
+var xml = require('@boop/xml');
+function main(state) {
+    var el = {type: "element", name: "a", attributes: {q: 'say "hi"\n'}, children: ["x < y", {type: "element", name: "b"}]};
+    state.text = xml.stringify(el, {indent: 0});
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if want := `<a q="say &quot;hi&quot;&#xA;">x &lt; y<b/></a>`; result.NewText != want {
+		t.Errorf("got %s, want %s", result.NewText, want)
+	}
+}
+
+// TestXMLQuery verifies the XPath-like queries.
+func TestXMLQuery(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(catalogXML, `
+var xml = require('@boop/xml');
+function main(state) {
+    var doc = xml.parse(state.text);
+    var book = xml.query(doc, '/catalog/book[1]')[0];
+    state.text = JSON.stringify([
+        xml.query(doc, '/catalog/book/@id'),
+        xml.query(doc, '//book[@id="2"]/title').map(xml.text),
+        xml.query(doc, '//p:price[@currency]/text()'),
+        xml.query(doc, '//price[last()]/text()'),
+        xml.query(doc, "//book[title='Go & You']/@lang"),
+        xml.query(doc, '//book[2]//text()'),
+        xml.query(book, 'title/text()'),
+        xml.query(book, '/book/@id'),
+        xml.query(doc, '//book[@id!="1"]').length
+    ]);
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := `[["1","2"],["Mixed content here"],["10"],["10","20"],["en"],["Mixed ","content"," here","20"],["Go & You"],["1"],1]`
+	if result.NewText != want {
+		t.Errorf("got  %s\nwant %s", result.NewText, want)
+	}
+}
+
+// TestXMLErrors verifies malformed documents and paths throw.
+func TestXMLErrors(t *testing.T) {
+	for _, c := range []struct{ src, want string }{
+		{`xml.parse('<a><b></a>')`, "element <b> closed by </a>"},
+		{`xml.parse('<a/><b/>')`, "more than one root element"},
+		{`xml.parse('just text')`, "text outside the root element"},
+		{`xml.query(xml.parse('<a/>'), 'a[')`, "unclosed ["},
+		{`xml.stringify({type: "element", name: "bad name"})`, `invalid element name "bad name"`},
+		{`xml.stringify({type: "comment", text: "a--b"})`, "cannot contain"},
+	} {
+		result := newExec().Execute(context.Background(), noSelInput("", `
+var xml = require('@boop/xml');
+function main(state) { `+c.src+`; }`))
+		if result.Success || !strings.Contains(result.ErrorMessage, c.want) {
+			t.Errorf("%s: success=%v err=%q, want %q", c.src, result.Success, result.ErrorMessage, c.want)
+		}
+	}
+}
+
+// TestXMLCompactObjects verifies toObject and fromObject.
+func TestXMLCompactObjects(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(catalogXML, `
+var xml = require('@boop/xml');
+function main(state) {
+    var obj = xml.toObject(xml.parse(state.text));
+    state.text = JSON.stringify(obj.catalog.book[0]) + "\n" +
+        xml.stringify(xml.fromObject({list: {"@n": 2, item: ["a", {"@id": "x", "#text": "b"}], empty: null}}));
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := `{"@id":"1","@lang":"en","title":"Go & You","p:price":{"@currency":"EUR","#text":"10"},"note":"<b>bold</b>"}
+<?xml version="1.0" encoding="UTF-8"?>
+<list n="2">
+  <item>a</item>
+  <item id="x">b</item>
+  <empty/>
+</list>`
+	if result.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.NewText, want)
+	}
+}
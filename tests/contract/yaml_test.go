@@ -0,0 +1,136 @@
+// Package contract — acceptance tests for order, comments and multiple
+// documents in the @boop/yaml module.
+package contract_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const manifestYAML = `# Deployment for the API
+kind: Deployment
+apiVersion: apps/v1
+metadata:
+  name: api # keep short
+  labels: {tier: backend, app: api}
+spec:
+  replicas: 2
+  created: 2024-01-02
+  defaults: &defaults
+    image: api:1.0
+  container:
+    <<: *defaults
+    port: 8080
+`
+
+// TestYAMLParseKeepsOrder verifies mappings keep their written order, merge
+// keys are applied and timestamps stay strings.
+func TestYAMLParseKeepsOrder(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(manifestYAML, `
+var yaml = require('@boop/yaml');
+function main(state) { state.text = JSON.stringify(yaml.parse(state.text)); }`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := `{"kind":"Deployment","apiVersion":"apps/v1","metadata":{"name":"api","labels":{"tier":"backend","app":"api"}},` +
+		`"spec":{"replicas":2,"created":"2024-01-02","defaults":{"image":"api:1.0"},"container":{"image":"api:1.0","port":8080}}}`
+	if result.NewText != want {
+		t.Errorf("got  %s\nwant %s", result.NewText, want)
+	}
+}
+
+// TestYAMLRoundTripKeepsComments verifies an unchanged document is written
+// back as it was read, and a changed one keeps its comments and styles.
+func TestYAMLRoundTripKeepsComments(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput(manifestYAML, `
+var yaml = require('@boop/yaml');
+function main(state) { state.text = yaml.stringify(yaml.parse(state.text), {indent: 2}); }`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if result.NewText != manifestYAML {
+		t.Errorf("unchanged document was rewritten:\n%s", result.NewText)
+	}
+
+	result = newExec().Execute(context.Background(), noSelInput(manifestYAML, `
+var yaml = require('@boop/yaml');
+function main(state) {
+    var doc = yaml.parse(state.text);
+    doc.spec.replicas = 3;
+    doc.metadata.labels.env = "prod";
+    delete doc.apiVersion;
+    state.text = yaml.stringify(doc, {indent: 2});
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	for _, want := range []string{
+		"# Deployment for the API\nkind: Deployment\nmetadata:",
+		"name: api # keep short",
+		"labels: {tier: backend, app: api, env: prod}",
+		"replicas: 3",
+		"created: 2024-01-02\n",
+		"container:\n    image: api:1.0\n    port: 8080",
+	} {
+		if !strings.Contains(result.NewText, want) {
+			t.Errorf("output lacks %q:\n%s", want, result.NewText)
+		}
+	}
+	if strings.Contains(result.NewText, "apiVersion") {
+		t.Errorf("deleted key was written:\n%s", result.NewText)
+	}
+}
+
+// TestYAMLStringifyOptions verifies the indent and flow options and that
+// plain objects are written in key order.
+func TestYAMLStringifyOptions(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("", `
+var yaml = require('@boop/yaml');
+function main(state) {
+    var obj = {zeta: {b: [1, 2]}, alpha: "true"};
+    state.text = yaml.stringify(obj) + "|" + yaml.stringify(obj, {indent: 2}) + "|" + yaml.stringify(obj, {flow: true});
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	want := "zeta:\n    b:\n        - 1\n        - 2\nalpha: \"true\"\n" +
+		"|zeta:\n  b:\n    - 1\n    - 2\nalpha: \"true\"\n" +
+		"|{zeta: {b: [1, 2]}, alpha: \"true\"}\n"
+	if result.NewText != want {
+		t.Errorf("got  %q\nwant %q", result.NewText, want)
+	}
+
+	result = newExec().Execute(context.Background(), noSelInput("", `
+var yaml = require('@boop/yaml');
+function main(state) { state.text = yaml.stringify({}, {indent: 20}); }`))
+	if result.Success || !strings.Contains(result.ErrorMessage, "indent must be between") {
+		t.Errorf("success=%v err=%q, want an indent error", result.Success, result.ErrorMessage)
+	}
+}
+
+// TestYAMLMultiDocument verifies parseAll and stringifyAll handle a stream of
+// several documents.
+func TestYAMLMultiDocument(t *testing.T) {
+	result := newExec().Execute(context.Background(), noSelInput("a: 1\n---\n# second\nb: [x]\n", `
+var yaml = require('@boop/yaml');
+function main(state) {
+    var docs = yaml.parseAll(state.text);
+    if (docs.length !== 2) throw new Error("got " + docs.length + " documents");
+    docs[0].a = 2;
+    state.text = yaml.stringifyAll(docs);
+}`))
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.ErrorMessage)
+	}
+	if want := "a: 2\n---\n# second\nb: [x]\n"; result.NewText != want {
+		t.Errorf("got %q, want %q", result.NewText, want)
+	}
+
+	result = newExec().Execute(context.Background(), noSelInput("a: 1\n---\nb: [\n", `
+var yaml = require('@boop/yaml');
+function main(state) { yaml.parseAll(state.text); }`))
+	if result.Success || !strings.Contains(result.ErrorMessage, "document 2") {
+		t.Errorf("success=%v err=%q, want an error naming document 2", result.Success, result.ErrorMessage)
+	}
+}
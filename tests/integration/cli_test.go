@@ -0,0 +1,324 @@
+// Package integration — end-to-end tests for the headless `goop run` mode.
+package integration_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codeberg.org/sigterm-de/goop/assets"
+	"codeberg.org/sigterm-de/goop/internal/cli"
+	"codeberg.org/sigterm-de/goop/internal/scripts"
+)
+
+// runCLI invokes cli.Run with empty user scripts, storage and library
+// directories and an empty trust store, and returns the exit code, stdout and
+// stderr.
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	dir := t.TempDir()
+	args = append([]string{
+		"--scripts-dir", filepath.Join(dir, "scripts"),
+		"--storage-dir", filepath.Join(dir, "storage"),
+		"--lib-dir", filepath.Join(dir, "lib"),
+		"--trust-file", filepath.Join(dir, "trust.json"),
+	}, args...)
+	code := cli.Run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// TestCLIRunScript verifies a script transforms stdin to stdout.
+func TestCLIRunScript(t *testing.T) {
+	code, out, errOut := runCLI(t, "hello", "Base64 Encode")
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, errOut)
+	}
+	if out != "aGVsbG8=" {
+		t.Errorf("stdout = %q, want %q", out, "aGVsbG8=")
+	}
+}
+
+// TestCLIParams verifies --param values are passed to the script and that
+// script names match case-insensitively.
+func TestCLIParams(t *testing.T) {
+	code, out, errOut := runCLI(t, "a\nb\n\nc", "--param", "delimiter=;", "--param", "skipEmpty=false", "join lines with delimiter")
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, errOut)
+	}
+	if out != "a;b;;c" {
+		t.Errorf("stdout = %q, want %q", out, "a;b;;c")
+	}
+}
+
+// TestCLIErrors verifies the exit codes for user errors.
+func TestCLIErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		args []string
+	}{
+		{"unknown script", "x", []string{"No Such Script"}},
+		{"missing script name", "x", nil},
+		{"unknown parameter", "x", []string{"--param", "nope=1", "Join Lines With Delimiter"}},
+		{"malformed parameter", "x", []string{"--param", "nope", "Join Lines With Delimiter"}},
+		{"script error", "{not json", []string{"Format JSON"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, out, errOut := runCLI(t, tc.in, tc.args...)
+			if code != 1 {
+				t.Errorf("exit code %d, want 1 (stderr: %s)", code, errOut)
+			}
+			if out != "" {
+				t.Errorf("stdout must stay empty on error, got %q", out)
+			}
+			if errOut == "" {
+				t.Error("expected a diagnostic on stderr")
+			}
+		})
+	}
+}
+
+// TestCLIList verifies --list prints the script library.
+func TestCLIList(t *testing.T) {
+	code, out, _ := runCLI(t, "", "--list")
+	if code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if !strings.Contains(out, "Base64 Encode\t") {
+		t.Errorf("expected Base64 Encode in listing, got %q", out[:min(200, len(out))])
+	}
+}
+
+// TestCLIClipboard verifies --clipboard feeds reads and --clipboard-out
+// receives writes, and that the clipboard stays unavailable without them.
+func TestCLIClipboard(t *testing.T) {
+	code, out, errOut := runCLI(t, "x=", "--clipboard", `say "hi"`, "Paste as JSON String")
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, errOut)
+	}
+	if out != `x="say \"hi\""` {
+		t.Errorf("stdout = %q", out)
+	}
+
+	if code, _, _ := runCLI(t, "x=", "Paste as JSON String"); code != 1 {
+		t.Errorf("exit code %d without --clipboard, want 1", code)
+	}
+}
+
+// TestCLIAllow verifies --allow grants declared permissions to user scripts
+// and rejects permissions the script does not declare.
+func TestCLIAllow(t *testing.T) {
+	dir := t.TempDir()
+	src := "/**!\n * @name Count Runs\n * @description D\n * @permissions storage\n */\n" +
+		`function main(state) { var n = state.storage.get("n", 0) + 1; state.storage.set("n", n); state.text = String(n); }`
+	if err := os.WriteFile(filepath.Join(dir, "count.js"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	storage := filepath.Join(dir, "storage")
+
+	if code, _, _ := runCLI(t, "", "--scripts-dir", dir, "Count Runs"); code != 1 {
+		t.Errorf("exit code %d without --allow, want 1", code)
+	}
+	for _, want := range []string{"1", "2"} {
+		code, out, errOut := runCLI(t, "", "--scripts-dir", dir, "--storage-dir", storage, "--allow", "storage", "Count Runs")
+		if code != 0 {
+			t.Fatalf("exit code %d, stderr: %s", code, errOut)
+		}
+		if out != want {
+			t.Errorf("stdout = %q, want %q", out, want)
+		}
+	}
+	if code, _, _ := runCLI(t, "", "--scripts-dir", dir, "--allow", "read-file", "Count Runs"); code != 1 {
+		t.Errorf("exit code %d for undeclared --allow, want 1", code)
+	}
+}
+
+// TestCLILocked verifies --locked refuses user scripts that have not been
+// approved, runs approved ones and never blocks built-ins.
+func TestCLILocked(t *testing.T) {
+	dir := t.TempDir()
+	src := "/**!\n * @name Shout\n * @description D\n */\nfunction main(state) { state.text = state.text.toUpperCase(); }"
+	if err := os.WriteFile(filepath.Join(dir, "shout.js"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	trustFile := filepath.Join(dir, "trust.json")
+
+	code, out, errOut := runCLI(t, "hi", "--scripts-dir", dir, "--trust-file", trustFile, "--locked", "Shout")
+	if code != 1 || out != "" || !strings.Contains(errOut, "not been approved") {
+		t.Fatalf("unapproved script: exit %d, stdout %q, stderr %q", code, out, errOut)
+	}
+	if code, out, _ := runCLI(t, "hi", "--scripts-dir", dir, "Shout"); code != 0 || out != "HI" {
+		t.Errorf("without --locked: exit %d, stdout %q", code, out)
+	}
+
+	result, err := scripts.NewLoader(assets.Scripts()).Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trust := scripts.LoadTrustStore(trustFile)
+	for _, s := range result.Scripts {
+		if s.Name == "Shout" {
+			if err := trust.Grant(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if code, out, _ := runCLI(t, "hi", "--scripts-dir", dir, "--trust-file", trustFile, "--locked", "Shout"); code != 0 || out != "HI" {
+		t.Errorf("approved script: exit %d, stdout %q", code, out)
+	}
+	if code, _, _ := runCLI(t, "hi", "--locked", "Upcase"); code != 0 {
+		t.Errorf("built-in script refused in locked mode: exit %d", code)
+	}
+}
+
+// TestCLIGoopRun verifies goop.run calls library scripts by name with
+// params, and that locked mode refuses calls to unapproved user scripts.
+func TestCLIGoopRun(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"pipeline.js": "/**!\n * @name Pipeline\n * @description D\n */\n" +
+			`function main(state) { state.text = goop.run("base64 encode", goop.run("Join Lines With Delimiter", state.text, { params: { delimiter: "+" } })); }`,
+		"caller.js": "/**!\n * @name Caller\n * @description D\n */\n" +
+			`function main(state) { state.text = goop.run("Pipeline", state.text); }`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	code, out, errOut := runCLI(t, "a\nb", "--scripts-dir", dir, "Pipeline")
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, errOut)
+	}
+	if out != "YSti" {
+		t.Errorf("stdout = %q, want %q", out, "YSti")
+	}
+
+	code, _, errOut = runCLI(t, "a\nb", "--scripts-dir", dir, "Caller")
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", code, errOut)
+	}
+
+	trustFile := filepath.Join(dir, "trust.json")
+	result, err := scripts.NewLoader(assets.Scripts()).Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trust := scripts.LoadTrustStore(trustFile)
+	for _, s := range result.Scripts {
+		if s.Name == "Caller" {
+			if err := trust.Grant(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	code, _, errOut = runCLI(t, "a\nb", "--scripts-dir", dir, "--trust-file", trustFile, "--locked", "Caller")
+	if code != 1 || !strings.Contains(errOut, "Pipeline has not been approved") {
+		t.Errorf("locked call to unapproved script: exit %d, stderr %q", code, errOut)
+	}
+}
+
+// TestCLIDeterministic verifies --seed makes a shuffle repeatable and --now
+// rejects malformed times.
+func TestCLIDeterministic(t *testing.T) {
+	in := "a\nb\nc\nd\ne\nf\ng\nh"
+	_, first, errOut := runCLI(t, in, "--seed", "7", "Shuffle Lines")
+	if first == "" {
+		t.Fatalf("no output, stderr: %s", errOut)
+	}
+	for range 3 {
+		if _, out, _ := runCLI(t, in, "--seed", "7", "Shuffle Lines"); out != first {
+			t.Errorf("--seed 7 gave %q, then %q", first, out)
+		}
+	}
+	if code, _, _ := runCLI(t, "x", "--now", "yesterday", "Upcase"); code != 1 {
+		t.Errorf("exit code %d for malformed --now, want 1", code)
+	}
+	if code, out, _ := runCLI(t, "x", "--now", "2024-01-02T15:04:05Z", "Upcase"); code != 0 || out != "X" {
+		t.Errorf("--now: exit %d, stdout %q", code, out)
+	}
+}
+
+// TestCLIBinaryRoundTrip verifies bytes decoded from Base64 reach stdout raw
+// and encode back to the same Base64 when fed to stdin.
+func TestCLIBinaryRoundTrip(t *testing.T) {
+	const b64 = "H4sIAAAAAAAA/wMAAAAAAAAAAAA="
+	code, raw, errOut := runCLI(t, b64, "Base64 Decode to Bytes")
+	if code != 0 {
+		t.Fatalf("decode: exit code %d, stderr: %s", code, errOut)
+	}
+	if len(raw) != 20 || raw[0] != 0x1f || raw[1] != 0x8b {
+		t.Fatalf("decode: stdout = % x", raw)
+	}
+	code, out, errOut := runCLI(t, raw, "Base64 Encode")
+	if code != 0 {
+		t.Fatalf("encode: exit code %d, stderr: %s", code, errOut)
+	}
+	if out != b64 {
+		t.Errorf("encode: stdout = %q, want %q", out, b64)
+	}
+}
+
+// TestCLIProgress verifies state.progress reports go to stderr and leave
+// stdout alone.
+func TestCLIProgress(t *testing.T) {
+	dir := t.TempDir()
+	src := "/**!\n * @name Slow\n * @description D\n */\n" +
+		`function main(state) { state.progress(0.5, "halfway"); state.progress(1); state.text = "done"; }`
+	if err := os.WriteFile(filepath.Join(dir, "slow.js"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, out, errOut := runCLI(t, "x", "--scripts-dir", dir, "Slow")
+	if code != 0 || out != "done" {
+		t.Fatalf("exit %d, stdout %q, stderr %q", code, out, errOut)
+	}
+	if errOut != "Slow:  50% halfway\nSlow: 100%\n" {
+		t.Errorf("stderr = %q", errOut)
+	}
+}
+
+func TestCLIStream(t *testing.T) {
+	code, out, errOut := runCLI(t, "a\nb\nc\n", "--stream", "Number Lines")
+	if code != 0 || out != "1: a\n2: b\n3: c\n" {
+		t.Fatalf("exit %d, stdout %q, stderr %q", code, out, errOut)
+	}
+
+	code, _, errOut = runCLI(t, "a\n", "--stream", "Upcase")
+	if code != 1 || !strings.Contains(errOut, "cannot stream") {
+		t.Errorf("exit %d, stderr %q; want exit 1 for a script without mapLine", code, errOut)
+	}
+}
+
+func TestCLIEach(t *testing.T) {
+	code, out, errOut := runCLI(t, "aGk=\nb2s=\n", "--each", "line", "Base64 Decode")
+	if code != 0 || out != "hi\nok\n" {
+		t.Fatalf("exit %d, stdout %q, stderr %q", code, out, errOut)
+	}
+
+	dir := t.TempDir()
+	src := "/**!\n * @name Picky\n * @description D\n */\n" +
+		`function main(state) { if (state.text === "x") throw new Error("no x"); state.text += "!"; }`
+	if err := os.WriteFile(filepath.Join(dir, "picky.js"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, out, errOut = runCLI(t, "a\nx\nb", "--scripts-dir", dir, "--each", "line", "Picky")
+	if code != 1 || out != "a!\nx\nb!" || !strings.Contains(errOut, "failed on 1 of 3 lines; line 2:") {
+		t.Errorf("exit %d, stdout %q, stderr %q", code, out, errOut)
+	}
+
+	if code, _, _ = runCLI(t, "", "--each", "word", "Upcase"); code != 1 {
+		t.Errorf("exit %d for an unknown --each mode, want 1", code)
+	}
+}
+
+func TestCLITiming(t *testing.T) {
+	code, out, errOut := runCLI(t, "a", "--timing", "Upcase")
+	if code != 0 || out != "A" || !strings.HasPrefix(errOut, "Upcase: ran in ") {
+		t.Errorf("exit %d, stdout %q, stderr %q", code, out, errOut)
+	}
+}
@@ -0,0 +1,63 @@
+package integration_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"testing"
+	"time"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// TestGzipScripts verifies that Gzip to Base64 output gunzips with the
+// standard library and that Gunzip Base64 reads it, and a binary .gz
+// document, back.
+func TestGzipScripts(t *testing.T) {
+	const text = "Grüße from the log\n"
+	res := runWithParams(t, "Gzip to Base64", text, map[string]any{"level": float64(9)})
+	if !res.Success {
+		t.Fatalf("Gzip to Base64 failed: %s", res.ErrorMessage)
+	}
+	back := runWithParams(t, "Gunzip Base64", res.NewText, nil)
+	if !back.Success || back.NewText != text {
+		t.Errorf("Gunzip Base64: success=%v text=%q err=%q", back.Success, back.NewText, back.ErrorMessage)
+	}
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	if _, err := w.Write([]byte{0xde, 0xad, 0xbe, 0xef}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	res = engine.NewExecutor().Execute(context.Background(), engine.ExecutionInput{
+		ScriptName:   "Gunzip Base64",
+		ScriptSource: loadScript(t, "Gunzip Base64"),
+		Data:         gz.Bytes(),
+		Timeout:      5 * time.Second,
+	})
+	if !res.Success || res.MutationKind != engine.MutationReplaceBytes || !bytes.Equal(res.NewBytes, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Errorf("Gunzip Base64 of a .gz document: success=%v kind=%v bytes=%x err=%q", res.Success, res.MutationKind, res.NewBytes, res.ErrorMessage)
+	}
+
+	res = runWithParams(t, "Gzip to Base64", "", map[string]any{"level": float64(9)})
+	raw, err := base64.StdEncoding.DecodeString(res.NewText)
+	if err != nil {
+		t.Fatalf("Gzip to Base64 of an empty document: %q is not base64: %v", res.NewText, err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	if out, err := io.ReadAll(r); err != nil || len(out) != 0 {
+		t.Errorf("gunzip of an empty document: %q, %v", out, err)
+	}
+
+	if res := runWithParams(t, "Gunzip Base64", "bm90IGd6aXA=", nil); res.Success {
+		t.Error("expected Gunzip Base64 to fail on data that is not gzip")
+	}
+}
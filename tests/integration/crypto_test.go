@@ -0,0 +1,74 @@
+package integration_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"codeberg.org/sigterm-de/goop/assets"
+	"codeberg.org/sigterm-de/goop/internal/engine"
+	"codeberg.org/sigterm-de/goop/internal/scripts"
+)
+
+// runWithParams runs the built-in script name over text with params.
+func runWithParams(t *testing.T, name, text string, params map[string]any) engine.ExecutionResult {
+	t.Helper()
+	return engine.NewExecutor().Execute(context.Background(), engine.ExecutionInput{
+		ScriptName:    name,
+		ScriptSource:  loadScript(t, name),
+		FullText:      text,
+		SelectionText: text,
+		SelectionEnd:  len(text),
+		Params:        params,
+		Timeout:       5 * time.Second,
+	})
+}
+
+// TestCryptoScripts verifies the HMAC, SHA-3 and bcrypt scripts.
+func TestCryptoScripts(t *testing.T) {
+	res := runWithParams(t, "HMAC-SHA256", "The quick brown fox jumps over the lazy dog",
+		map[string]any{"key": "key", "base64": false})
+	if !res.Success || res.NewText != "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8" {
+		t.Errorf("HMAC-SHA256: success=%v text=%q err=%q", res.Success, res.NewText, res.ErrorMessage)
+	}
+
+	res = execScript(t, "SHA3-256 Hash", loadScript(t, "SHA3-256 Hash"), "abc")
+	if !res.Success || res.NewText != "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532" {
+		t.Errorf("SHA3-256: success=%v text=%q err=%q", res.Success, res.NewText, res.ErrorMessage)
+	}
+
+	hashed := runWithParams(t, "Bcrypt Hash", "hunter2", map[string]any{"cost": float64(4)})
+	if !hashed.Success || !strings.HasPrefix(hashed.NewText, "$2a$04$") {
+		t.Fatalf("Bcrypt Hash: success=%v text=%q err=%q", hashed.Success, hashed.NewText, hashed.ErrorMessage)
+	}
+	ok := runWithParams(t, "Bcrypt Verify", "hunter2", map[string]any{"hash": hashed.NewText})
+	if !ok.Success || ok.InfoMessage == "" || ok.NewText != "" {
+		t.Errorf("Bcrypt Verify of the right password: success=%v info=%q err=%q", ok.Success, ok.InfoMessage, ok.ErrorMessage)
+	}
+	bad := runWithParams(t, "Bcrypt Verify", "hunter3", map[string]any{"hash": hashed.NewText})
+	if bad.Success {
+		t.Error("expected Bcrypt Verify to fail for the wrong password")
+	}
+}
+
+// TestPureScriptsTakeNoSecrets verifies no built-in script that takes a key,
+// secret or password is marked @pure: the memo cache would keep what was
+// computed with the secret for the life of the process.
+func TestPureScriptsTakeNoSecrets(t *testing.T) {
+	result, err := scripts.NewLoader(assets.Scripts()).Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, s := range result.Scripts {
+		if !s.Pure {
+			continue
+		}
+		for _, p := range s.Params {
+			name := strings.ToLower(p.Name)
+			if strings.Contains(name, "key") || strings.Contains(name, "secret") || strings.Contains(name, "password") {
+				t.Errorf("%s is @pure but takes the secret param %q", s.Name, p.Name)
+			}
+		}
+	}
+}
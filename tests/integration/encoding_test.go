@@ -0,0 +1,66 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// TestEncodingScriptRoundTrips verifies each encode script against a known
+// output, and that its decode script restores the text.
+func TestEncodingScriptRoundTrips(t *testing.T) {
+	const text = "Grüße, world!"
+	for _, tc := range []struct {
+		encode, decode string
+		params         map[string]any
+		want           string
+	}{
+		{"Base32 Encode", "Base32 Decode", map[string]any{"variant": "std", "padding": true}, "I5ZMHPGDT5SSYIDXN5ZGYZBB"},
+		{"Base32 Encode", "Base32 Decode", map[string]any{"variant": "crockford", "padding": true}, "8XSC7F63KXJJR83QDXS6RS11"},
+		{"Base58 Encode", "Base58 Decode", map[string]any{"flickr": false}, "2zwJoM9dp6WV2goURw3Te"},
+		{"Base64URL Encode", "Base64URL Decode", map[string]any{"padding": false}, "R3LDvMOfZSwgd29ybGQh"},
+		{"Ascii85 Encode", "Ascii85 Decode", map[string]any{"z85": false, "delimiters": true}, "<~7rlM[_k1Kk+EqaECh+[~>"},
+		{"Quoted-Printable Encode", "Quoted-Printable Decode", nil, "Gr=C3=BC=C3=9Fe, world!"},
+		{"UUEncode", "UUDecode", map[string]any{"name": "greeting.txt"}, "begin 644 greeting.txt\n/1W+#O,.?92P@=V]R;&0A\n`\nend\n"},
+		{"Percent Encode", "Percent Decode", map[string]any{"mode": "query"}, "Gr%C3%BC%C3%9Fe%2C+world%21"},
+		{"Hex Dump", "Hex Dump to Bytes", map[string]any{"columns": float64(8), "group": float64(4)},
+			"00000000: 4772c3bc c39f652c  Gr....e,\n00000008: 20776f72 6c6421     world!\n"},
+	} {
+		enc := runWithParams(t, tc.encode, text, tc.params)
+		if !enc.Success || enc.NewText != tc.want {
+			t.Errorf("%s: success=%v text=%q err=%q, want %q", tc.encode, enc.Success, enc.NewText, enc.ErrorMessage, tc.want)
+			continue
+		}
+		dec := runWithParams(t, tc.decode, enc.NewText, tc.params)
+		if !dec.Success || dec.NewText != text {
+			t.Errorf("%s: success=%v text=%q err=%q", tc.decode, dec.Success, dec.NewText, dec.ErrorMessage)
+		}
+	}
+}
+
+// TestEncodingScriptsBinary verifies that the encode scripts read a binary
+// document's bytes and the decode scripts write bytes that are not text.
+func TestEncodingScriptsBinary(t *testing.T) {
+	data := []byte{0x1f, 0x8b, 0x08, 0x00, 0xff}
+	res := engine.NewExecutor().Execute(context.Background(), engine.ExecutionInput{
+		ScriptName:   "Base58 Encode",
+		ScriptSource: loadScript(t, "Base58 Encode"),
+		Data:         data,
+		Params:       map[string]any{"flickr": false},
+		Timeout:      5 * time.Second,
+	})
+	if !res.Success || res.NewFullText != "4ZQbhXL" {
+		t.Fatalf("Base58 Encode of bytes: success=%v text=%q err=%q", res.Success, res.NewFullText, res.ErrorMessage)
+	}
+	res = runWithParams(t, "Base58 Decode", res.NewFullText, map[string]any{"flickr": false})
+	if !res.Success || res.MutationKind != engine.MutationReplaceBytes || !bytes.Equal(res.NewBytes, data) {
+		t.Errorf("Base58 Decode to bytes: success=%v kind=%v bytes=%x err=%q", res.Success, res.MutationKind, res.NewBytes, res.ErrorMessage)
+	}
+
+	if res := runWithParams(t, "Base32 Decode", "MZXW6===", map[string]any{"variant": "base64"}); res.Success {
+		t.Error("expected Base32 Decode to refuse an unknown variant")
+	}
+}
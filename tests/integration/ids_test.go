@@ -0,0 +1,45 @@
+package integration_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestIDScripts verifies the UUID insert, decode and convert scripts.
+func TestIDScripts(t *testing.T) {
+	v4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	res := runWithParams(t, "Insert UUID v4", "", nil)
+	if !res.Success || !v4.MatchString(res.InsertText) {
+		t.Errorf("Insert UUID v4: success=%v insert=%q err=%q", res.Success, res.InsertText, res.ErrorMessage)
+	}
+	res = runWithParams(t, "Insert UUID v7", "", nil)
+	if !res.Success || len(res.InsertText) != 36 || res.InsertText[14] != '7' {
+		t.Errorf("Insert UUID v7: success=%v insert=%q err=%q", res.Success, res.InsertText, res.ErrorMessage)
+	}
+	for _, name := range []string{"Insert UUID v4", "Insert UUID v7"} {
+		if script := builtinScript(t, name); len(script.Params) != 0 {
+			t.Errorf("%s declares params %v; an insert should not open the parameter form", name, script.Params)
+		}
+	}
+
+	res = runWithParams(t, "Decode UUID/ULID", "017F22E2-79B0-7CC3-98C4-DC0C0C07398F\n", map[string]any{"epoch": "twitter"})
+	if !res.Success || !strings.Contains(res.InfoMessage, "version: 7") ||
+		!strings.Contains(res.InfoMessage, "time: 2022-02-22T19:22:22.000Z") {
+		t.Errorf("Decode UUID/ULID: success=%v info=%q err=%q", res.Success, res.InfoMessage, res.ErrorMessage)
+	}
+	res = runWithParams(t, "Decode UUID/ULID", "175928847299117063", map[string]any{"epoch": "1420070400000"})
+	if !res.Success || !strings.Contains(res.InfoMessage, "2016-04-30T11:18:25.796Z") {
+		t.Errorf("Decode UUID/ULID of a Snowflake: success=%v info=%q err=%q", res.Success, res.InfoMessage, res.ErrorMessage)
+	}
+
+	res = runWithParams(t, "Convert UUID formats", "{017F22E2-79B0-7CC3-98C4-DC0C0C07398F}\n\nAX8i4nmwfMOYxNwMDAc5jw==",
+		map[string]any{"format": "hyphenated"})
+	if want := "017f22e2-79b0-7cc3-98c4-dc0c0c07398f\n\n017f22e2-79b0-7cc3-98c4-dc0c0c07398f"; !res.Success || res.NewText != want {
+		t.Errorf("Convert UUID formats: success=%v text=%q err=%q", res.Success, res.NewText, res.ErrorMessage)
+	}
+	res = runWithParams(t, "Convert UUID formats", "017f22e2-79b0-7cc3-98c4-dc0c0c07398f", map[string]any{"format": "base64"})
+	if !res.Success || res.NewText != "AX8i4nmwfMOYxNwMDAc5jw==" {
+		t.Errorf("Convert UUID formats to base64: success=%v text=%q err=%q", res.Success, res.NewText, res.ErrorMessage)
+	}
+}
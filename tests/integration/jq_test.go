@@ -0,0 +1,45 @@
+package integration_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+)
+
+// TestJQFilterScript verifies the jq Filter script replaces JSON with the
+// filter's results, as JSON or as raw strings.
+func TestJQFilterScript(t *testing.T) {
+	src := loadScript(t, "jq Filter")
+	doc := `{"users":[{"name":"ada","id":1},{"name":"bob","id":2}]}`
+	run := func(params map[string]any) engine.ExecutionResult {
+		return engine.NewExecutor().Execute(context.Background(), engine.ExecutionInput{
+			ScriptName:    "jq Filter",
+			ScriptSource:  src,
+			FullText:      doc,
+			SelectionText: doc,
+			SelectionEnd:  len(doc),
+			Params:        params,
+			Timeout:       5 * time.Second,
+		})
+	}
+
+	res := run(map[string]any{"filter": ".users[] | {name}", "raw": false})
+	if !res.Success {
+		t.Fatalf("jq Filter failed: %s", res.ErrorMessage)
+	}
+	if want := "{\n  \"name\": \"ada\"\n}\n{\n  \"name\": \"bob\"\n}"; res.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", res.NewText, want)
+	}
+
+	res = run(map[string]any{"filter": ".users[].name", "raw": true})
+	if !res.Success || res.NewText != "ada\nbob" {
+		t.Errorf("raw: success=%v text=%q err=%q", res.Success, res.NewText, res.ErrorMessage)
+	}
+
+	res = run(map[string]any{"filter": ".users[", "raw": false})
+	if res.Success {
+		t.Error("expected an invalid filter to fail")
+	}
+}
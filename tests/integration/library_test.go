@@ -217,6 +217,8 @@ func TestTC_L10_MetadataParsing(t *testing.T) {
  * @icon          <i class="fas fa-star"></i>
  * @tags          foo,bar, baz
  * @bias          -2.5
+ * @output        SQL
+ * @pure
  */
 function main(state) {}`
 
@@ -246,6 +248,12 @@ function main(state) {}`
 	if script.Bias != -2.5 {
 		t.Errorf("Bias: got %v, want -2.5", script.Bias)
 	}
+	if script.Output != "sql" {
+		t.Errorf("Output: got %q, want %q", script.Output, "sql")
+	}
+	if !script.Pure {
+		t.Error("Pure: got false, want true")
+	}
 }
 
 // TC-L-11: User scripts exceeding 1 MB are skipped.
@@ -280,6 +288,26 @@ func TestTC_L11_OversizedScriptSkipped(t *testing.T) {
 	}
 }
 
+// TC-L-12: Scripts defining mapLine or mapRecord are marked as streaming.
+func TestTC_L12_StreamingDetected(t *testing.T) {
+	dir := t.TempDir()
+	header := "/**!\n * @name          %s\n * @description   D\n */\n\n"
+	writeScript(t, dir, "lines.js", fmt.Sprintf(header, "Lines")+"function mapLine(line) { return line; }")
+	writeScript(t, dir, "records.js", fmt.Sprintf(header, "Records")+"function mapRecord (r) { return r; }")
+	writeScript(t, dir, "plain.js", validScript("Plain", "D")+"\n// function mapLine(line) is not defined here")
+
+	result, err := newLoader().Load(dir)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	want := map[string]bool{"Lines": true, "Records": true, "Plain": false}
+	for _, s := range result.Scripts {
+		if w, ok := want[s.Name]; ok && s.Streaming != w {
+			t.Errorf("%s: Streaming = %v, want %v", s.Name, s.Streaming, w)
+		}
+	}
+}
+
 // ── helpers ──────────────────────────────────────────────────────────────────
 
 func writeScript(t *testing.T, dir, name, content string) {
@@ -0,0 +1,143 @@
+// Package integration — tests for @param header parsing, value resolution and
+// remembered values.
+package integration_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+	"codeberg.org/sigterm-de/goop/internal/scripts"
+)
+
+// TestParseHeaderParams verifies the @param header syntax.
+func TestParseHeaderParams(t *testing.T) {
+	cases := []struct {
+		name    string
+		line    string
+		want    scripts.Param
+		wantErr bool
+	}{
+		{"full declaration", `delim:string "Delimiter" ", "`,
+			scripts.Param{Name: "delim", Type: scripts.ParamString, Label: "Delimiter", Default: ", "}, false},
+		{"bare default", `width:number "Pad to width" 80`,
+			scripts.Param{Name: "width", Type: scripts.ParamNumber, Label: "Pad to width", Default: "80"}, false},
+		{"escaped default", `sep "Separator" "\n"`,
+			scripts.Param{Name: "sep", Type: scripts.ParamString, Label: "Separator", Default: "\n"}, false},
+		{"name only", `key`,
+			scripts.Param{Name: "key", Type: scripts.ParamString, Label: "key", Default: ""}, false},
+		{"bool alias and zero default", `upper:bool "Uppercase"`,
+			scripts.Param{Name: "upper", Type: scripts.ParamBool, Label: "Uppercase", Default: "false"}, false},
+		{"unknown type", `n:int "N" 1`, scripts.Param{}, true},
+		{"invalid number default", `n:number "N" abc`, scripts.Param{}, true},
+		{"unterminated label", `n:string "N`, scripts.Param{}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := "/**!\n * @name   P\n * @description   D\n * @param " + tc.line + "\n */\nfunction main(state) {}"
+			s, err := scripts.ParseHeader(src)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got params %+v", s.Params)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseHeader: %v", err)
+			}
+			if len(s.Params) != 1 || s.Params[0] != tc.want {
+				t.Errorf("Params = %+v, want [%+v]", s.Params, tc.want)
+			}
+		})
+	}
+}
+
+// TestParseHeaderDuplicateParam verifies duplicate parameter names are rejected.
+func TestParseHeaderDuplicateParam(t *testing.T) {
+	src := "/**!\n * @name   P\n * @description   D\n * @param a\n * @param a:number\n */"
+	if _, err := scripts.ParseHeader(src); err == nil {
+		t.Fatal("expected error for duplicate @param")
+	}
+}
+
+// TestResolveParams verifies defaults, type conversion and unknown keys.
+func TestResolveParams(t *testing.T) {
+	params := []scripts.Param{
+		{Name: "delim", Type: scripts.ParamString, Label: "Delimiter", Default: ","},
+		{Name: "width", Type: scripts.ParamNumber, Label: "Width", Default: "10"},
+		{Name: "upper", Type: scripts.ParamBool, Label: "Upper", Default: "false"},
+	}
+
+	got, err := scripts.ResolveParams(params, map[string]string{"width": "42", "upper": "true"})
+	if err != nil {
+		t.Fatalf("ResolveParams: %v", err)
+	}
+	if got["delim"] != "," || got["width"] != 42.0 || got["upper"] != true {
+		t.Errorf("ResolveParams = %#v", got)
+	}
+
+	if _, err := scripts.ResolveParams(params, map[string]string{"width": "wide"}); err == nil {
+		t.Error("expected error for non-numeric width")
+	}
+	if _, err := scripts.ResolveParams(params, map[string]string{"widht": "1"}); err == nil {
+		t.Error("expected error for unknown parameter")
+	}
+}
+
+// TestParamMemoryRoundTrip verifies last-used values survive a reload and
+// that undeclared or new parameters fall back to defaults.
+func TestParamMemoryRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "params.json")
+	s := scripts.Script{
+		FilePath: "embedded:Join.js",
+		Params: []scripts.Param{
+			{Name: "delim", Type: scripts.ParamString, Default: ","},
+			{Name: "upper", Type: scripts.ParamBool, Default: "false"},
+		},
+	}
+
+	mem := scripts.LoadParamMemory(path)
+	if got := mem.Values(s); got["delim"] != "," || got["upper"] != "false" {
+		t.Fatalf("fresh memory should return defaults, got %v", got)
+	}
+	if err := mem.Remember(s, map[string]string{"delim": ";"}); err != nil {
+		t.Fatalf("Remember: %v", err)
+	}
+
+	got := scripts.LoadParamMemory(path).Values(s)
+	if got["delim"] != ";" {
+		t.Errorf("delim = %q after reload, want %q", got["delim"], ";")
+	}
+	if got["upper"] != "false" {
+		t.Errorf("upper = %q, want default %q", got["upper"], "false")
+	}
+}
+
+// TestJoinLinesWithDelimiter runs the built-in parameterised script.
+func TestJoinLinesWithDelimiter(t *testing.T) {
+	content := loadScript(t, "Join Lines With Delimiter")
+	s, err := scripts.ParseHeader(content)
+	if err != nil {
+		t.Fatalf("ParseHeader: %v", err)
+	}
+	values, err := scripts.ResolveParams(s.Params, map[string]string{"delimiter": " | "})
+	if err != nil {
+		t.Fatalf("ResolveParams: %v", err)
+	}
+
+	inp := engine.ExecutionInput{
+		ScriptSource:  content,
+		ScriptName:    s.Name,
+		FullText:      "a\n\nb\nc",
+		SelectionText: "a\n\nb\nc",
+		Params:        values,
+	}
+	result := engine.NewExecutor().Execute(t.Context(), inp)
+	if !result.Success {
+		t.Fatalf("script failed: %s", result.ErrorMessage)
+	}
+	if result.NewText != "a | b | c" {
+		t.Errorf("NewText = %q, want %q", result.NewText, "a | b | c")
+	}
+}
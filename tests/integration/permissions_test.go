@@ -0,0 +1,119 @@
+// Package integration — tests for @permissions header parsing and the trust
+// store.
+package integration_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"codeberg.org/sigterm-de/goop/assets"
+	"codeberg.org/sigterm-de/goop/internal/scripts"
+)
+
+// TestParseHeaderPermissions verifies the @permissions header syntax.
+func TestParseHeaderPermissions(t *testing.T) {
+	src := "/**!\n * @name   P\n * @description   D\n * @permissions Clipboard, storage read-file\n * @permissions clipboard\n */"
+	s, err := scripts.ParseHeader(src)
+	if err != nil {
+		t.Fatalf("ParseHeader: %v", err)
+	}
+	want := []scripts.Permission{scripts.PermClipboard, scripts.PermStorage, scripts.PermReadFile}
+	if !slices.Equal(s.Permissions, want) {
+		t.Errorf("Permissions = %v, want %v", s.Permissions, want)
+	}
+
+	src = "/**!\n * @name   P\n * @description   D\n * @permissions clipboard, teleport\n */"
+	if _, err := scripts.ParseHeader(src); err == nil {
+		t.Error("expected error for unknown permission")
+	}
+}
+
+// TestTrustStore verifies approvals persist, apply only to declared elevated
+// permissions of the approved script version and are implicit for built-ins.
+func TestTrustStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "trust.json")
+	user := scripts.Script{
+		Source:      scripts.UserProvided,
+		FilePath:    "/scripts/paste.js",
+		Content:     "v1",
+		Permissions: []scripts.Permission{scripts.PermClipboard, scripts.PermNetworkNone},
+	}
+	builtin := user
+	builtin.Source = scripts.BuiltIn
+
+	store := scripts.LoadTrustStore(path)
+	if got := store.Pending(user); !slices.Equal(got, []scripts.Permission{scripts.PermClipboard}) {
+		t.Fatalf("Pending = %v, want only the elevated permission", got)
+	}
+	if !store.Granted(user, scripts.PermNetworkNone) {
+		t.Error("network-none should not need approval")
+	}
+	if len(store.Pending(builtin)) != 0 {
+		t.Error("built-in script should be granted what it declares")
+	}
+	if store.Granted(builtin, scripts.PermStorage) {
+		t.Error("undeclared permission must never be granted")
+	}
+
+	if err := store.Grant(user); err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	reloaded := scripts.LoadTrustStore(path)
+	if got := reloaded.GrantedPermissions(user); len(got) != 2 {
+		t.Errorf("GrantedPermissions after reload = %v", got)
+	}
+	if entries, err := os.ReadDir(filepath.Dir(path)); err != nil || len(entries) != 1 {
+		t.Errorf("config dir holds %v (err %v), want only trust.json and no temp files", entries, err)
+	}
+
+	edited := user
+	edited.Content = "v2"
+	if len(reloaded.Pending(edited)) != 1 {
+		t.Error("a modified script must be approved again")
+	}
+}
+
+// TestTrustOnFirstUse verifies the loader records a content hash for user
+// scripts and that approval is tied to that exact content.
+func TestTrustOnFirstUse(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tool.js")
+	write := func(body string) scripts.Script {
+		t.Helper()
+		src := "/**!\n * @name Tool\n * @description D\n */\nfunction main(state) { " + body + " }"
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := scripts.NewLoader(assets.Scripts()).Load(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range result.Scripts {
+			if s.Source == scripts.UserProvided {
+				return s
+			}
+		}
+		t.Fatal("user script not loaded")
+		return scripts.Script{}
+	}
+
+	store := scripts.LoadTrustStore(filepath.Join(dir, "trust.json"))
+	s := write(`state.text = "a";`)
+	if s.Hash != scripts.ContentHash(s.Content) {
+		t.Errorf("Hash = %q, want hash of content", s.Hash)
+	}
+	if store.Approved(s) {
+		t.Fatal("new user script must not be approved")
+	}
+	if err := store.Grant(s); err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	if !scripts.LoadTrustStore(filepath.Join(dir, "trust.json")).Approved(s) {
+		t.Error("approval should survive a reload")
+	}
+	if store.Approved(write(`state.text = "b";`)) {
+		t.Error("a modified script must be approved again")
+	}
+}
@@ -0,0 +1,59 @@
+package integration_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"codeberg.org/sigterm-de/goop/internal/engine"
+	"howett.net/plist"
+)
+
+// TestJSONPlistRoundTrip verifies JSON to Plist and Plist to JSON undo each
+// other.
+func TestJSONPlistRoundTrip(t *testing.T) {
+	in := `{"name":"goop","count":3,"ratio":0.5,"tags":["a","b"],"nested":{"ok":true}}`
+	toPlist := execScript(t, "JSON to Plist", loadScript(t, "JSON to Plist"), in)
+	if !toPlist.Success {
+		t.Fatalf("JSON to Plist failed: %s", toPlist.ErrorMessage)
+	}
+	back := execScript(t, "Plist to JSON", loadScript(t, "Plist to JSON"), toPlist.NewText)
+	if !back.Success {
+		t.Fatalf("Plist to JSON failed: %s", back.ErrorMessage)
+	}
+
+	var want, got any
+	if err := json.Unmarshal([]byte(in), &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(back.NewText), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, back.NewText)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the data:\n got %v\nwant %v", got, want)
+	}
+}
+
+// TestPlistToJSONBinary verifies a binary property list document converts.
+func TestPlistToJSONBinary(t *testing.T) {
+	data, err := plist.Marshal(map[string]any{"CFBundleName": "goop", "LSMinimumSystemVersion": "11.0"}, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inp := engine.ExecutionInput{
+		ScriptSource: loadScript(t, "Plist to JSON"),
+		ScriptName:   "Plist to JSON",
+		Data:         data,
+		Timeout:      5 * time.Second,
+	}
+	result := engine.NewExecutor().Execute(context.Background(), inp)
+	if !result.Success {
+		t.Fatalf("Plist to JSON failed: %s", result.ErrorMessage)
+	}
+	want := "{\n  \"CFBundleName\": \"goop\",\n  \"LSMinimumSystemVersion\": \"11.0\"\n}"
+	if result.NewFullText != want {
+		t.Errorf("got %q, want %q", result.NewFullText, want)
+	}
+}
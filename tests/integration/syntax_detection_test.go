@@ -33,6 +33,12 @@ func TestSyntaxDetectionFormats(t *testing.T) {
 			wantLangID:   "html",
 			wantLangName: "HTML",
 		},
+		{
+			name:         "JSONtoTOML script output",
+			input:        "name = \"goop\"\n\n[dependencies]\nserde = \"1\"\n",
+			wantLangID:   "toml",
+			wantLangName: "TOML",
+		},
 		{
 			name:         "JSONtoYAML script output",
 			input:        "---\nkey: value\nnested:\n  a: 1\n  b: 2\n",
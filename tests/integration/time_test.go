@@ -0,0 +1,35 @@
+package integration_test
+
+import (
+	"testing"
+)
+
+// TestTimeScripts verifies the timestamp scripts built on @boop/time.
+func TestTimeScripts(t *testing.T) {
+	res := execScript(t, "Date to Timestamp", loadScript(t, "Date to Timestamp"), "Tue, 02 Jan 2024 15:04:05 -0800")
+	if !res.Success || res.NewText != "1704236645" {
+		t.Errorf("Date to Timestamp: success=%v text=%q err=%q", res.Success, res.NewText, res.ErrorMessage)
+	}
+	res = execScript(t, "Date to UTC", loadScript(t, "Date to UTC"), "1704207845")
+	if !res.Success || res.NewText != "Tue, 02 Jan 2024 15:04:05 GMT" {
+		t.Errorf("Date to UTC: success=%v text=%q err=%q", res.Success, res.NewText, res.ErrorMessage)
+	}
+	if res := execScript(t, "Date to UTC", loadScript(t, "Date to UTC"), "not a date"); res.Success {
+		t.Error("expected Date to UTC to fail on text that is not a date")
+	}
+
+	res = runWithParams(t, "Convert timestamps in selection",
+		"deployed 2024-01-02T15:04:05Z, rolled back at 1704211445 (see [02/Jan/2024:16:30:00 +0000])",
+		map[string]any{"zone": "Europe/Berlin", "format": "%d.%m.%Y %H:%M", "unix": true})
+	if want := "deployed 02.01.2024 16:04, rolled back at 02.01.2024 17:04 (see [02.01.2024 17:30])"; !res.Success || res.NewText != want {
+		t.Errorf("Convert timestamps: success=%v text=%q err=%q", res.Success, res.NewText, res.ErrorMessage)
+	}
+	if res.InfoMessage != "Converted 3 timestamps" {
+		t.Errorf("Convert timestamps: info=%q", res.InfoMessage)
+	}
+	res = runWithParams(t, "Convert timestamps in selection", "2024-01-02T15:04:05Z",
+		map[string]any{"zone": "Nowhere/Else", "format": "RFC3339", "unix": true})
+	if res.Success {
+		t.Error("expected Convert timestamps to fail for an unknown zone")
+	}
+}
@@ -0,0 +1,57 @@
+package integration_test
+
+import (
+	"strings"
+	"testing"
+)
+
+const cargoTOML = `[package]
+name = "goop"
+version = "0.1.0"
+edition = "2021"
+
+[dependencies]
+serde = { version = "1", features = ["derive"] }
+`
+
+// TestTOMLConversions verifies TOML converts to JSON and YAML and back.
+func TestTOMLConversions(t *testing.T) {
+	toJSON := execScript(t, "TOML to JSON", loadScript(t, "TOML to JSON"), cargoTOML)
+	if !toJSON.Success {
+		t.Fatalf("TOML to JSON failed: %s", toJSON.ErrorMessage)
+	}
+	if !strings.Contains(toJSON.NewText, `"features": [`) || !strings.Contains(toJSON.NewText, `"edition": "2021"`) {
+		t.Errorf("unexpected JSON:\n%s", toJSON.NewText)
+	}
+
+	fromJSON := execScript(t, "JSON to TOML", loadScript(t, "JSON to TOML"), toJSON.NewText)
+	if !fromJSON.Success {
+		t.Fatalf("JSON to TOML failed: %s", fromJSON.ErrorMessage)
+	}
+	for _, want := range []string{"[package]\n", `name = "goop"`, "[dependencies.serde]\n", `features = ["derive"]`} {
+		if !strings.Contains(fromJSON.NewText, want) {
+			t.Errorf("JSON to TOML output lacks %q:\n%s", want, fromJSON.NewText)
+		}
+	}
+
+	toYAML := execScript(t, "TOML to YAML", loadScript(t, "TOML to YAML"), cargoTOML)
+	if !toYAML.Success {
+		t.Fatalf("TOML to YAML failed: %s", toYAML.ErrorMessage)
+	}
+	if !strings.Contains(toYAML.NewText, "edition: \"2021\"") {
+		t.Errorf("unexpected YAML:\n%s", toYAML.NewText)
+	}
+
+	fromYAML := execScript(t, "YAML to TOML", loadScript(t, "YAML to TOML"), toYAML.NewText)
+	if !fromYAML.Success {
+		t.Fatalf("YAML to TOML failed: %s", fromYAML.ErrorMessage)
+	}
+	if !strings.Contains(fromYAML.NewText, `edition = "2021"`) {
+		t.Errorf("unexpected TOML:\n%s", fromYAML.NewText)
+	}
+
+	bad := execScript(t, "JSON to TOML", loadScript(t, "JSON to TOML"), "[1, 2]")
+	if bad.Success {
+		t.Error("expected JSON to TOML to refuse an array")
+	}
+}
@@ -16,6 +16,13 @@ import (
 // loadScript returns the Content of the first script with the given name from
 // the embedded library, or skips the test if not found.
 func loadScript(t *testing.T, name string) string {
+	t.Helper()
+	return builtinScript(t, name).Content
+}
+
+// builtinScript returns the built-in script called name, skipping the test
+// when there is none.
+func builtinScript(t *testing.T, name string) scripts.Script {
 	t.Helper()
 	result, err := scripts.NewLoader(assets.Scripts()).Load("")
 	if err != nil {
@@ -23,11 +30,11 @@ func loadScript(t *testing.T, name string) string {
 	}
 	for _, s := range result.Scripts {
 		if s.Name == name {
-			return s.Content
+			return s
 		}
 	}
 	t.Skipf("built-in script %q not found — skipping", name)
-	return ""
+	return scripts.Script{}
 }
 
 func execScript(t *testing.T, scriptName, scriptContent, fullText string) engine.ExecutionResult {
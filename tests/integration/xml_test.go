@@ -0,0 +1,40 @@
+package integration_test
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestXMLJSONConversions verifies XML converts to JSON and back.
+func TestXMLJSONConversions(t *testing.T) {
+	in := "<order id=\"7\"><item sku=\"a1\">Pen</item><item sku=\"b2\">Ink</item><note>rush</note></order>"
+
+	toJSON := execScript(t, "XML to JSON", loadScript(t, "XML to JSON"), in)
+	if !toJSON.Success {
+		t.Fatalf("XML to JSON failed: %s", toJSON.ErrorMessage)
+	}
+	for _, want := range []string{`"@id": "7"`, `"item": [`, `"@sku": "b2"`, `"#text": "Ink"`, `"note": "rush"`} {
+		if !strings.Contains(toJSON.NewText, want) {
+			t.Errorf("XML to JSON output lacks %q:\n%s", want, toJSON.NewText)
+		}
+	}
+
+	fromJSON := execScript(t, "JSON to XML", loadScript(t, "JSON to XML"), toJSON.NewText)
+	if !fromJSON.Success {
+		t.Fatalf("JSON to XML failed: %s", fromJSON.ErrorMessage)
+	}
+	want := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<order id=\"7\">\n  <item sku=\"a1\">Pen</item>\n" +
+		"  <item sku=\"b2\">Ink</item>\n  <note>rush</note>\n</order>"
+	if fromJSON.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", fromJSON.NewText, want)
+	}
+
+	wrapped := execScript(t, "JSON to XML", loadScript(t, "JSON to XML"), `[1, 2]`)
+	if !wrapped.Success || !strings.Contains(wrapped.NewText, "<root>\n  <item>1</item>\n  <item>2</item>\n</root>") {
+		t.Errorf("JSON to XML of an array: success=%v\n%s", wrapped.Success, wrapped.NewText)
+	}
+
+	if bad := execScript(t, "XML to JSON", loadScript(t, "XML to JSON"), "<a><b></a>"); bad.Success {
+		t.Error("expected XML to JSON to reject malformed XML")
+	}
+}
@@ -0,0 +1,41 @@
+package integration_test
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestYAMLJSONConversionsKeepOrder verifies "YAML to JSON" and "JSON to YAML"
+// keep key order, and that several YAML documents become a JSON array.
+func TestYAMLJSONConversionsKeepOrder(t *testing.T) {
+	manifest := "kind: Service\napiVersion: v1\nmetadata:\n  name: api\n  namespace: web\n"
+
+	toJSON := execScript(t, "YAML to JSON", loadScript(t, "YAML to JSON"), manifest)
+	if !toJSON.Success {
+		t.Fatalf("YAML to JSON failed: %s", toJSON.ErrorMessage)
+	}
+	want := "{\n  \"kind\": \"Service\",\n  \"apiVersion\": \"v1\",\n  \"metadata\": {\n    \"name\": \"api\",\n    \"namespace\": \"web\"\n  }\n}"
+	if toJSON.NewText != want {
+		t.Errorf("got:\n%s\nwant:\n%s", toJSON.NewText, want)
+	}
+
+	fromJSON := execScript(t, "JSON to YAML", loadScript(t, "JSON to YAML"), toJSON.NewText)
+	if !fromJSON.Success {
+		t.Fatalf("JSON to YAML failed: %s", fromJSON.ErrorMessage)
+	}
+	if fromJSON.NewText != manifest {
+		t.Errorf("got:\n%s\nwant:\n%s", fromJSON.NewText, manifest)
+	}
+
+	multi := execScript(t, "YAML to JSON", loadScript(t, "YAML to JSON"), "a: 1\n---\nb: 2\n")
+	if !multi.Success {
+		t.Fatalf("YAML to JSON failed on two documents: %s", multi.ErrorMessage)
+	}
+	if got := strings.Join(strings.Fields(multi.NewText), ""); got != `[{"a":1},{"b":2}]` {
+		t.Errorf("got %s, want an array of both documents", multi.NewText)
+	}
+
+	if bad := execScript(t, "YAML to JSON", loadScript(t, "YAML to JSON"), "a: [\n"); bad.Success {
+		t.Error("expected YAML to JSON to reject invalid YAML")
+	}
+}